@@ -13,7 +13,7 @@ import (
 
 func TestAcceleratorsIntersections(t *testing.T) {
 	rand.Seed(time.Now().Unix())
-	prims, _ := example.GetTeapotScene()
+	prims, _ := example.GetTeapotScene(nil)
 	prims = FullyRefinePrimitives(prims)
 
 	tests := []struct {
@@ -171,3 +171,64 @@ func randInRange(min, max float64) float64 {
 func uniformRandomSphere() geometry.Vector {
 	return geometry.NewVector(rand.Float64()-0.5, rand.Float64()-0.5, rand.Float64()-0.5)
 }
+
+func TestAcceleratorsTraversalStats(t *testing.T) {
+	prims, _ := example.GetTeapotScene(nil)
+	prims = FullyRefinePrimitives(prims)
+
+	tests := []struct {
+		name  string
+		accel StatsIntersecter
+	}{
+		{
+			name:  "grid",
+			accel: NewGrid(prims),
+		},
+		{
+			name:  "bvh",
+			accel: NewBVH(prims, 1),
+		},
+	}
+
+	ray := geometry.NewRay(
+		geometry.NewVector(0, 0, -5),
+		geometry.NewVector(0, 0, 1),
+	)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				st      TraversalStats
+				in      primitive.Intersection
+				inStats primitive.Intersection
+			)
+
+			hit := test.accel.(primitive.Primitive).Intersect(ray, &in)
+			hitStats := test.accel.IntersectStats(ray, &inStats, &st)
+
+			if hit != hitStats || in.DfGeometry.Distance != inStats.DfGeometry.Distance {
+				t.Fatalf("intersection with statistics differs from the one without")
+			}
+
+			if st.Rays != 1 {
+				t.Errorf("expected one counted ray but got %d", st.Rays)
+			}
+			if st.NodesVisited == 0 || st.BoxTests == 0 || st.PrimitiveTests == 0 {
+				t.Errorf("expected non-zero counters but got %+v", st)
+			}
+
+			test.accel.IntersectPStats(ray, &st)
+			if st.Rays != 2 {
+				t.Errorf("expected two counted rays but got %d", st.Rays)
+			}
+		})
+	}
+
+	var inLeaves uint64
+	for prims, leaves := range NewBVH(prims, 4).LeafOccupancy() {
+		inLeaves += uint64(prims) * leaves
+	}
+	if inLeaves != uint64(len(prims)) {
+		t.Errorf("expected %d primitives in BVH leaves but found %d", len(prims), inLeaves)
+	}
+}
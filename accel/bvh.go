@@ -1,7 +1,6 @@
 package accel
 
 import (
-	"fmt"
 	"math"
 	"sort"
 
@@ -83,7 +82,6 @@ func NewBVH(p []primitive.Primitive, mp uint8) *BVH {
 	}
 
 	bvh.primitives = FullyRefinePrimitives(p)
-	fmt.Printf("Number of primitive in BVH: %d\n", len(bvh.primitives))
 
 	// Nothing else to do, this would be an empty BVH
 	if len(bvh.primitives) == 0 {
@@ -107,8 +105,6 @@ func NewBVH(p []primitive.Primitive, mp uint8) *BVH {
 	var offset uint32
 	bvh.flattenBVHTree(root, &offset)
 
-	fmt.Printf("Final BVH has %d nodes\n", totalNodes)
-
 	return bvh
 }
 
@@ -269,6 +265,19 @@ func (bvh *BVH) bvhRecursiveBuild(
 
 // Intersect implements the Primitive interface
 func (bvh *BVH) Intersect(ray geometry.Ray, in *primitive.Intersection) bool {
+	return bvh.IntersectStats(ray, in, nil)
+}
+
+// IntersectStats implements the [StatsIntersecter] interface. When `st` is nil no
+// work is recorded.
+func (bvh *BVH) IntersectStats(
+	ray geometry.Ray,
+	in *primitive.Intersection,
+	st *TraversalStats,
+) bool {
+	if st != nil {
+		st.Rays++
+	}
 	if bvh.nodes == nil {
 		return false
 	}
@@ -287,6 +296,10 @@ func (bvh *BVH) Intersect(ray geometry.Ray, in *primitive.Intersection) bool {
 	var todo [256]uint32
 	for {
 		node := &bvh.nodes[nodeNum]
+		if st != nil {
+			st.NodesVisited++
+			st.BoxTests++
+		}
 		if !node.bounds.IntersectPOptimized(&ray, &invDir, dirIsNeg) {
 			// Ray does not intersect this node's BBox. Continue with the next
 			// one.
@@ -318,6 +331,9 @@ func (bvh *BVH) Intersect(ray geometry.Ray, in *primitive.Intersection) bool {
 
 		// The node is an leaf node. So intersect with all of its primitives
 		// searching for a hit.
+		if st != nil {
+			st.PrimitiveTests += uint64(node.nPrimitives)
+		}
 		for i := uint32(0); i < uint32(node.nPrimitives); i++ {
 			if bvh.primitives[node.offset+i].Intersect(ray, in) {
 				hit = true
@@ -336,6 +352,15 @@ func (bvh *BVH) Intersect(ray geometry.Ray, in *primitive.Intersection) bool {
 
 // IntersectP implements the Primitive interface
 func (bvh *BVH) IntersectP(ray geometry.Ray) bool {
+	return bvh.IntersectPStats(ray, nil)
+}
+
+// IntersectPStats implements the [StatsIntersecter] interface. When `st` is nil no
+// work is recorded.
+func (bvh *BVH) IntersectPStats(ray geometry.Ray, st *TraversalStats) bool {
+	if st != nil {
+		st.Rays++
+	}
 	if bvh.nodes == nil {
 		return false
 	}
@@ -353,6 +378,10 @@ func (bvh *BVH) IntersectP(ray geometry.Ray) bool {
 	var todo [256]uint32
 	for {
 		node := &bvh.nodes[nodeNum]
+		if st != nil {
+			st.NodesVisited++
+			st.BoxTests++
+		}
 		if !node.bounds.IntersectPOptimized(&ray, &invDir, dirIsNeg) {
 			// Ray does not intersect this node's BBox. Continue with the next
 			// one.
@@ -385,6 +414,9 @@ func (bvh *BVH) IntersectP(ray geometry.Ray) bool {
 		// The node is an leaf node. So intersect with all of its primitives
 		// searching for a hit.
 		for i := uint32(0); i < uint32(node.nPrimitives); i++ {
+			if st != nil {
+				st.PrimitiveTests++
+			}
 			if bvh.primitives[node.offset+i].IntersectP(ray) {
 				return true
 			}
@@ -399,6 +431,135 @@ func (bvh *BVH) IntersectP(ray geometry.Ray) bool {
 	return false
 }
 
+// IntersectPacket implements the [PacketIntersecter] interface.
+func (bvh *BVH) IntersectPacket(p *RayPacket) {
+	p.prepare()
+	if bvh.nodes == nil || p.Len == 0 {
+		return
+	}
+
+	var todoOffset, nodeNum uint32
+	var todo [256]uint32
+	for {
+		node := &bvh.nodes[nodeNum]
+		first := p.firstHit(&node.bounds, nil)
+
+		if first < p.Len && node.nPrimitives == 0 {
+			// Put the far node on the todo stack. The first ray which hit the
+			// node decides which child is closer for the whole packet.
+			if p.dirIsNeg[first][node.axis] {
+				todo[todoOffset] = nodeNum + 1
+				todoOffset++
+				nodeNum = node.offset
+			} else {
+				todo[todoOffset] = node.offset
+				todoOffset++
+				nodeNum = nodeNum + 1
+			}
+			continue
+		}
+
+		if first < p.Len {
+			// A leaf node. Intersect its primitives with every ray which hits
+			// the node's bounding box.
+			for i := first; i < p.Len; i++ {
+				ray := &p.Rays[i]
+				if !node.bounds.IntersectPOptimized(ray, &p.invDir[i], p.dirIsNeg[i]) {
+					continue
+				}
+
+				for j := uint32(0); j < uint32(node.nPrimitives); j++ {
+					if bvh.primitives[node.offset+j].Intersect(*ray, &p.Isect[i]) {
+						p.Hit[i] = true
+						ray.Maxt = p.Isect[i].DfGeometry.Distance
+					}
+				}
+			}
+		}
+
+		if todoOffset == 0 {
+			break
+		}
+		todoOffset--
+		nodeNum = todo[todoOffset]
+	}
+}
+
+// IntersectPPacket implements the [PacketIntersecter] interface.
+func (bvh *BVH) IntersectPPacket(p *RayPacket) {
+	p.prepare()
+	if bvh.nodes == nil || p.Len == 0 {
+		return
+	}
+
+	active := p.Len
+
+	var todoOffset, nodeNum uint32
+	var todo [256]uint32
+	for {
+		node := &bvh.nodes[nodeNum]
+		first := p.firstHit(&node.bounds, &p.Hit)
+
+		if first < p.Len && node.nPrimitives == 0 {
+			if p.dirIsNeg[first][node.axis] {
+				todo[todoOffset] = nodeNum + 1
+				todoOffset++
+				nodeNum = node.offset
+			} else {
+				todo[todoOffset] = node.offset
+				todoOffset++
+				nodeNum = nodeNum + 1
+			}
+			continue
+		}
+
+		if first < p.Len {
+			for i := first; i < p.Len; i++ {
+				ray := &p.Rays[i]
+				if p.Hit[i] ||
+					!node.bounds.IntersectPOptimized(ray, &p.invDir[i], p.dirIsNeg[i]) {
+					continue
+				}
+
+				for j := uint32(0); j < uint32(node.nPrimitives); j++ {
+					if bvh.primitives[node.offset+j].IntersectP(*ray) {
+						p.Hit[i] = true
+						active--
+						break
+					}
+				}
+			}
+
+			if active == 0 {
+				return
+			}
+		}
+
+		if todoOffset == 0 {
+			break
+		}
+		todoOffset--
+		nodeNum = todo[todoOffset]
+	}
+}
+
+// Size returns the number of refined primitives and nodes in the hierarchy.
+func (bvh *BVH) Size() (primitives, nodes int) {
+	return len(bvh.primitives), len(bvh.nodes)
+}
+
+// LeafOccupancy implements the [StatsIntersecter] interface.
+func (bvh *BVH) LeafOccupancy() []uint64 {
+	var hist []uint64
+	for i := range bvh.nodes {
+		if bvh.nodes[i].nPrimitives == 0 {
+			continue
+		}
+		hist = addToHistogram(hist, int(bvh.nodes[i].nPrimitives))
+	}
+	return hist
+}
+
 func newBVHPrimitiveInfo(pn int, b *bbox.BBox) bvhPrimitiveInfo {
 	return bvhPrimitiveInfo{
 		primitiveNumber: pn,
@@ -105,6 +105,21 @@ func (g *Grid) voxelToPos(p int, axis int) float64 {
 
 // Intersect implements the Primitive interface
 func (g *Grid) Intersect(ray geometry.Ray, in *primitive.Intersection) bool {
+	return g.IntersectStats(ray, in, nil)
+}
+
+// IntersectStats implements the [StatsIntersecter] interface. When `st` is nil no
+// work is recorded.
+func (g *Grid) IntersectStats(
+	ray geometry.Ray,
+	in *primitive.Intersection,
+	st *TraversalStats,
+) bool {
+	if st != nil {
+		st.Rays++
+		st.BoxTests++
+	}
+
 	var rayT float64
 
 	if g.bounds.Inside(ray.At(ray.Mint)) {
@@ -143,8 +158,11 @@ func (g *Grid) Intersect(ray geometry.Ray, in *primitive.Intersection) bool {
 
 	for {
 		voxel := g.voxels[g.offset(pos[0], pos[1], pos[2])]
+		if st != nil {
+			st.NodesVisited++
+		}
 		if voxel != nil {
-			if ok := voxel.Intersect(ray, in); ok {
+			if ok := voxel.IntersectStats(ray, in, st); ok {
 				hasHit = true
 				ray.Maxt = in.DfGeometry.Distance
 			}
@@ -178,6 +196,17 @@ func (g *Grid) Intersect(ray geometry.Ray, in *primitive.Intersection) bool {
 
 // IntersectP implements the Primitive interface
 func (g *Grid) IntersectP(ray geometry.Ray) bool {
+	return g.IntersectPStats(ray, nil)
+}
+
+// IntersectPStats implements the [StatsIntersecter] interface. When `st` is nil no
+// work is recorded.
+func (g *Grid) IntersectPStats(ray geometry.Ray, st *TraversalStats) bool {
+	if st != nil {
+		st.Rays++
+		st.BoxTests++
+	}
+
 	var rayT float64
 
 	if g.bounds.Inside(ray.At(ray.Mint)) {
@@ -214,8 +243,11 @@ func (g *Grid) IntersectP(ray geometry.Ray) bool {
 
 	for {
 		voxel := g.voxels[g.offset(pos[0], pos[1], pos[2])]
+		if st != nil {
+			st.NodesVisited++
+		}
 		if voxel != nil {
-			if intersected := voxel.IntersectP(ray); intersected {
+			if intersected := voxel.IntersectPStats(ray, st); intersected {
 				return true
 			}
 		}
@@ -245,3 +277,17 @@ func (g *Grid) IntersectP(ray geometry.Ray) bool {
 
 	return false
 }
+
+// LeafOccupancy implements the [StatsIntersecter] interface. Every voxel of the
+// grid is considered a leaf.
+func (g *Grid) LeafOccupancy() []uint64 {
+	var hist []uint64
+	for _, voxel := range g.voxels {
+		var n int
+		if voxel != nil {
+			n = len(voxel.primitives)
+		}
+		hist = addToHistogram(hist, n)
+	}
+	return hist
+}
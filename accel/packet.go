@@ -0,0 +1,192 @@
+package accel
+
+import (
+	"math"
+
+	"github.com/ironsmile/raytracer/bbox"
+	"github.com/ironsmile/raytracer/geometry"
+	"github.com/ironsmile/raytracer/primitive"
+)
+
+// PacketSize is the maximum number of rays in a [RayPacket].
+const PacketSize = 16
+
+// RayPacket is a group of rays which are traced through an accelerator together.
+// Packets work best when their rays are coherent. For example primary rays for
+// neighbouring pixels or shadow rays toward the same light. For such packets whole
+// sub-trees of the accelerator could be culled with a single test.
+type RayPacket struct {
+	// Rays holds the rays of the packet. Only the first `Len` are used. After
+	// intersection the Maxt of every ray which hit something is set to the
+	// distance to the closest hit.
+	Rays [PacketSize]geometry.Ray
+
+	// Len is the number of used rays in `Rays`.
+	Len int
+
+	// Hit is set after intersection for every ray. For [PacketIntersecter.IntersectPacket]
+	// it tells whether the ray hit anything. For [PacketIntersecter.IntersectPPacket]
+	// it tells whether the ray is occluded.
+	Hit [PacketSize]bool
+
+	// Isect holds the intersection information for every ray which hit something
+	// after [PacketIntersecter.IntersectPacket].
+	Isect [PacketSize]primitive.Intersection
+
+	invDir   [PacketSize]geometry.Vector
+	dirIsNeg [PacketSize][3]bool
+
+	// coherent is true when all rays have the same direction signs on every axis.
+	// Only then the interval bounds below are usable for culling.
+	coherent bool
+	negDir   [3]bool
+	oMin     [3]float64
+	oMax     [3]float64
+	iMin     [3]float64
+	iMax     [3]float64
+	minMint  float64
+}
+
+// PacketIntersecter is implemented by accelerators which support tracing
+// [RayPacket]s.
+type PacketIntersecter interface {
+	// IntersectPacket finds the closest intersection for every ray in the packet.
+	IntersectPacket(*RayPacket)
+
+	// IntersectPPacket finds whether every ray in the packet intersects anything.
+	IntersectPPacket(*RayPacket)
+}
+
+// IntersectPacket intersects all rays in the packet against `prim`. Accelerators
+// which implement [PacketIntersecter] are traversed as packets. All other primitives
+// are intersected one ray at a time.
+func IntersectPacket(prim primitive.Primitive, p *RayPacket) {
+	if pi, ok := prim.(PacketIntersecter); ok {
+		pi.IntersectPacket(p)
+		return
+	}
+
+	for i := 0; i < p.Len; i++ {
+		p.Hit[i] = prim.Intersect(p.Rays[i], &p.Isect[i])
+		if p.Hit[i] {
+			p.Rays[i].Maxt = p.Isect[i].DfGeometry.Distance
+		}
+	}
+}
+
+// IntersectPPacket is the same as [IntersectPacket] but only checks whether rays
+// intersect anything at all.
+func IntersectPPacket(prim primitive.Primitive, p *RayPacket) {
+	if pi, ok := prim.(PacketIntersecter); ok {
+		pi.IntersectPPacket(p)
+		return
+	}
+
+	for i := 0; i < p.Len; i++ {
+		p.Hit[i] = prim.IntersectP(p.Rays[i])
+	}
+}
+
+// prepare computes all the per-ray and per-packet data needed for traversal and
+// resets the hit results.
+func (p *RayPacket) prepare() {
+	p.coherent = p.Len > 0
+	p.minMint = math.Inf(1)
+
+	for axis := 0; axis < 3; axis++ {
+		p.oMin[axis], p.oMax[axis] = math.Inf(1), math.Inf(-1)
+		p.iMin[axis], p.iMax[axis] = math.Inf(1), math.Inf(-1)
+	}
+
+	for i := 0; i < p.Len; i++ {
+		ray := &p.Rays[i]
+		p.Hit[i] = false
+		p.invDir[i] = geometry.NewVector(
+			1/ray.Direction.X,
+			1/ray.Direction.Y,
+			1/ray.Direction.Z,
+		)
+		p.dirIsNeg[i] = [3]bool{
+			p.invDir[i].X < 0,
+			p.invDir[i].Y < 0,
+			p.invDir[i].Z < 0,
+		}
+		p.minMint = math.Min(p.minMint, ray.Mint)
+
+		origin := [3]float64{ray.Origin.X, ray.Origin.Y, ray.Origin.Z}
+		inv := [3]float64{p.invDir[i].X, p.invDir[i].Y, p.invDir[i].Z}
+		for axis := 0; axis < 3; axis++ {
+			if i == 0 {
+				p.negDir[axis] = p.dirIsNeg[i][axis]
+			} else if p.negDir[axis] != p.dirIsNeg[i][axis] {
+				p.coherent = false
+			}
+			if math.IsInf(inv[axis], 0) || math.IsNaN(inv[axis]) {
+				p.coherent = false
+			}
+
+			p.oMin[axis] = math.Min(p.oMin[axis], origin[axis])
+			p.oMax[axis] = math.Max(p.oMax[axis], origin[axis])
+			p.iMin[axis] = math.Min(p.iMin[axis], inv[axis])
+			p.iMax[axis] = math.Max(p.iMax[axis], inv[axis])
+		}
+	}
+}
+
+// missesBox uses interval arithmetic for checking whether all rays in a coherent
+// packet miss the bounding box `b`. It is conservative: false does not mean that
+// any of the rays hits the box.
+func (p *RayPacket) missesBox(b *bbox.BBox) bool {
+	if !p.coherent {
+		return false
+	}
+
+	bMin := [3]float64{b.Min.X, b.Min.Y, b.Min.Z}
+	bMax := [3]float64{b.Max.X, b.Max.Y, b.Max.Z}
+
+	tNear, tFar := p.minMint, math.Inf(1)
+	for axis := 0; axis < 3; axis++ {
+		near, far := bMin[axis], bMax[axis]
+		if p.negDir[axis] {
+			near, far = far, near
+		}
+
+		nLo, _ := mulInterval(near-p.oMax[axis], near-p.oMin[axis], p.iMin[axis], p.iMax[axis])
+		_, fHi := mulInterval(far-p.oMax[axis], far-p.oMin[axis], p.iMin[axis], p.iMax[axis])
+
+		tNear = math.Max(tNear, nLo)
+		tFar = math.Min(tFar, fHi)
+		if tNear > tFar {
+			return true
+		}
+	}
+
+	return false
+}
+
+// firstHit returns the index of the first ray which is not `done` and intersects
+// the bounding box `b`. It returns p.Len when there is no such ray.
+func (p *RayPacket) firstHit(b *bbox.BBox, done *[PacketSize]bool) int {
+	if p.missesBox(b) {
+		return p.Len
+	}
+
+	for i := 0; i < p.Len; i++ {
+		if done != nil && done[i] {
+			continue
+		}
+		if b.IntersectPOptimized(&p.Rays[i], &p.invDir[i], p.dirIsNeg[i]) {
+			return i
+		}
+	}
+
+	return p.Len
+}
+
+// mulInterval returns the interval which contains all products of a number in
+// [a0, a1] and a number in [b0, b1].
+func mulInterval(a0, a1, b0, b1 float64) (float64, float64) {
+	p1, p2, p3, p4 := a0*b0, a0*b1, a1*b0, a1*b1
+	return math.Min(math.Min(p1, p2), math.Min(p3, p4)),
+		math.Max(math.Max(p1, p2), math.Max(p3, p4))
+}
@@ -0,0 +1,91 @@
+package accel
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/ironsmile/raytracer/bbox"
+	"github.com/ironsmile/raytracer/geometry"
+	"github.com/ironsmile/raytracer/primitive"
+	"github.com/ironsmile/raytracer/scene/example"
+)
+
+// TestBVHPacketIntersections checks that tracing rays in packets finds exactly the
+// same intersections as tracing them one by one. Coherent packets are rays from the
+// same origin toward neighbouring points, like primary rays. Incoherent packets have
+// random origins and directions.
+func TestBVHPacketIntersections(t *testing.T) {
+	prims, _ := example.GetTeapotScene(nil)
+	prims = FullyRefinePrimitives(prims)
+	bvh := NewBVH(prims, 4)
+	rnd := rand.New(rand.NewSource(42))
+
+	var bb *bbox.BBox
+	for _, pr := range prims {
+		bb = bbox.Union(bb, pr.GetWorldBBox())
+	}
+	center := bb.Min.Plus(bb.Max).MultiplyScalar(0.5)
+	extent := bb.Max.Minus(bb.Min).Length()
+
+	for i := 0; i < 2000; i++ {
+		var packet RayPacket
+		packet.Len = 1 + rnd.Intn(PacketSize)
+
+		coherent := i%2 == 0
+		eye := center.Plus(uniformRandomSphere().MultiplyScalar(extent))
+		target := center.Plus(uniformRandomSphere().MultiplyScalar(extent * 0.4))
+
+		for r := 0; r < packet.Len; r++ {
+			if coherent {
+				jitter := geometry.NewVector(
+					rnd.Float64()-0.5,
+					rnd.Float64()-0.5,
+					rnd.Float64()-0.5,
+				).MultiplyScalar(extent * 0.05)
+				packet.Rays[r] = geometry.NewRay(eye,
+					target.Plus(jitter).Minus(eye).Normalize())
+				continue
+			}
+
+			origin := center.Plus(uniformRandomSphere().MultiplyScalar(extent))
+			packet.Rays[r] = geometry.NewRay(origin, uniformRandomSphere().Normalize())
+			if rnd.Intn(4) == 0 {
+				packet.Rays[r].Maxt = rnd.Float64() * extent
+			}
+		}
+
+		rays := packet.Rays
+		shadow := packet
+
+		bvh.IntersectPacket(&packet)
+		bvh.IntersectPPacket(&shadow)
+
+		for r := 0; r < packet.Len; r++ {
+			var in primitive.Intersection
+			hit := bvh.Intersect(rays[r], &in)
+
+			if hit != packet.Hit[r] {
+				t.Fatalf("packet %d ray %d: expected hit %t but got %t",
+					i, r, hit, packet.Hit[r])
+			}
+			if bvh.IntersectP(rays[r]) != shadow.Hit[r] {
+				t.Fatalf("packet %d ray %d: expected occluded %t but got %t",
+					i, r, !shadow.Hit[r], shadow.Hit[r])
+			}
+			if !hit {
+				continue
+			}
+
+			got := packet.Isect[r].DfGeometry.Distance
+			if math.Abs(got-in.DfGeometry.Distance) > geometry.EPSILON {
+				t.Errorf("packet %d ray %d: expected distance %f but got %f",
+					i, r, in.DfGeometry.Distance, got)
+			}
+			if packet.Rays[r].Maxt != got {
+				t.Errorf("packet %d ray %d: Maxt %f was not updated to the hit distance %f",
+					i, r, packet.Rays[r].Maxt, got)
+			}
+		}
+	}
+}
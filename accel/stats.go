@@ -0,0 +1,67 @@
+package accel
+
+import (
+	"github.com/ironsmile/raytracer/geometry"
+	"github.com/ironsmile/raytracer/primitive"
+)
+
+// TraversalStats counts the work an accelerator has done while searching for ray
+// intersections. It is not safe for concurrent use. Every rendering goroutine is
+// expected to keep its own and merge them with [TraversalStats.Add].
+type TraversalStats struct {
+	// Rays is the number of rays traced through the accelerator.
+	Rays uint64
+
+	// NodesVisited is the number of BVH nodes or grid voxels visited.
+	NodesVisited uint64
+
+	// BoxTests is the number of ray-bounding box intersection tests.
+	BoxTests uint64
+
+	// PrimitiveTests is the number of ray-primitive intersection tests.
+	PrimitiveTests uint64
+}
+
+// Add accumulates the counts from `other` into `s`.
+func (s *TraversalStats) Add(other *TraversalStats) {
+	s.Rays += other.Rays
+	s.NodesVisited += other.NodesVisited
+	s.BoxTests += other.BoxTests
+	s.PrimitiveTests += other.PrimitiveTests
+}
+
+// Reset zeroes out all counters.
+func (s *TraversalStats) Reset() {
+	*s = TraversalStats{}
+}
+
+// Cost returns a single number which approximates how expensive the counted
+// traversals were. It is used for painting traversal heatmaps.
+func (s *TraversalStats) Cost() uint64 {
+	return s.NodesVisited + s.PrimitiveTests
+}
+
+// StatsIntersecter is implemented by accelerators which are able to count the work
+// they do while intersecting rays.
+type StatsIntersecter interface {
+	// IntersectStats is the same as the Intersect method of the [primitive.Primitive]
+	// interface but records its work in `st`.
+	IntersectStats(geometry.Ray, *primitive.Intersection, *TraversalStats) bool
+
+	// IntersectPStats is the same as the IntersectP method of the
+	// [primitive.Primitive] interface but records its work in `st`.
+	IntersectPStats(geometry.Ray, *TraversalStats) bool
+
+	// LeafOccupancy returns a histogram of the number of primitives stored in
+	// leaf nodes. The value at index `i` is the number of leaves which hold
+	// exactly `i` primitives.
+	LeafOccupancy() []uint64
+}
+
+func addToHistogram(hist []uint64, bucket int) []uint64 {
+	for len(hist) <= bucket {
+		hist = append(hist, 0)
+	}
+	hist[bucket]++
+	return hist
+}
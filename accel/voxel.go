@@ -27,6 +27,58 @@ func (v *Voxel) IntersectP(ray geometry.Ray) bool {
 	return primitive.IntersectPMultiple(v.primitives, ray)
 }
 
+// IntersectStats is the same as [Voxel.Intersect] but records the work done in `st`.
+// When `st` is nil no work is recorded.
+func (v *Voxel) IntersectStats(
+	ray geometry.Ray,
+	in *primitive.Intersection,
+	st *TraversalStats,
+) bool {
+	if st == nil {
+		return v.Intersect(ray, in)
+	}
+
+	var hasHit bool
+	for _, pr := range v.primitives {
+		var ok bool
+		if sub, isGrid := pr.(*Grid); isGrid {
+			ok = sub.IntersectStats(ray, in, st)
+		} else {
+			st.PrimitiveTests++
+			ok = pr.Intersect(ray, in)
+		}
+
+		if ok {
+			hasHit = true
+			ray.Maxt = in.DfGeometry.Distance
+		}
+	}
+	return hasHit
+}
+
+// IntersectPStats is the same as [Voxel.IntersectP] but records the work done in
+// `st`. When `st` is nil no work is recorded.
+func (v *Voxel) IntersectPStats(ray geometry.Ray, st *TraversalStats) bool {
+	if st == nil {
+		return v.IntersectP(ray)
+	}
+
+	for _, pr := range v.primitives {
+		var ok bool
+		if sub, isGrid := pr.(*Grid); isGrid {
+			ok = sub.IntersectPStats(ray, st)
+		} else {
+			st.PrimitiveTests++
+			ok = pr.IntersectP(ray)
+		}
+
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
 // Add inserts a primitive in this voxel
 func (v *Voxel) Add(p primitive.Primitive) {
 	if p.CanIntersect() {
@@ -0,0 +1,392 @@
+// Package aov collects arbitrary output variables: extra images which the renderer
+// produces along with the final colour. They describe the surfaces hit by the
+// primary rays, like their depth, normals and albedo, and are used by compositors
+// and denoisers.
+package aov
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/ironsmile/raytracer/geometry"
+	"github.com/ironsmile/raytracer/mat"
+)
+
+// Kind is a type of output variable.
+type Kind int
+
+const (
+	// KindDepth is the distance from the camera to the hit point along the viewing
+	// direction of the camera.
+	KindDepth Kind = iota
+
+	// KindNormal is the shading normal of the hit point in world space.
+	KindNormal
+
+	// KindAlbedo is the colour of the material at the hit point.
+	KindAlbedo
+
+	// KindPosition is the hit point in world space.
+	KindPosition
+
+	// KindUV is the surface parameterization of the hit point. For meshes these
+	// are their texture coordinates.
+	KindUV
+
+	// KindPrimitiveID is the ID of the hit primitive plus one. See
+	// [primitive.Primitive.GetID].
+	KindPrimitiveID
+
+	// KindMaterialID is a hash of the material at the hit point. Surfaces with the
+	// same material properties have the same ID.
+	KindMaterialID
+)
+
+// Kinds are all the supported output variables.
+var Kinds = []Kind{
+	KindDepth,
+	KindNormal,
+	KindAlbedo,
+	KindPosition,
+	KindUV,
+	KindPrimitiveID,
+	KindMaterialID,
+}
+
+// String implements fmt.Stringer.
+func (k Kind) String() string {
+	switch k {
+	case KindDepth:
+		return "depth"
+	case KindNormal:
+		return "normal"
+	case KindAlbedo:
+		return "albedo"
+	case KindPosition:
+		return "position"
+	case KindUV:
+		return "uv"
+	case KindPrimitiveID:
+		return "primitive-id"
+	case KindMaterialID:
+		return "material-id"
+	default:
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+}
+
+// ParseKind returns the Kind with the given name. The names are the same as the ones
+// returned by [Kind.String].
+func ParseKind(name string) (Kind, error) {
+	for _, kind := range Kinds {
+		if kind.String() == name {
+			return kind, nil
+		}
+	}
+	return KindDepth, fmt.Errorf("unknown output variable %q", name)
+}
+
+// ParseKinds parses a comma separated list of output variable names. The name
+// "all" stands for all of them.
+func ParseKinds(list string) ([]Kind, error) {
+	if list == "all" {
+		return Kinds, nil
+	}
+
+	var kinds []Kind
+	for _, name := range strings.Split(list, ",") {
+		kind, err := ParseKind(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		kinds = append(kinds, kind)
+	}
+	return kinds, nil
+}
+
+// channels returns the names of the channels of this kind.
+func (k Kind) channels() []string {
+	switch k {
+	case KindDepth:
+		return []string{"Z"}
+	case KindNormal, KindPosition:
+		return []string{"X", "Y", "Z"}
+	case KindAlbedo:
+		return []string{"R", "G", "B"}
+	case KindUV:
+		return []string{"U", "V"}
+	default:
+		return []string{"id"}
+	}
+}
+
+// isID returns true for the kinds which are identifiers. They could not be averaged
+// so the value of the first sample for a pixel is kept.
+func (k Kind) isID() bool {
+	return k == KindPrimitiveID || k == KindMaterialID
+}
+
+// Sample holds all output variables for the surface hit by a single primary ray.
+type Sample struct {
+	Depth       float64
+	Normal      geometry.Vector
+	Albedo      geometry.Color
+	Position    geometry.Vector
+	U, V        float64
+	PrimitiveID uint64
+	MaterialID  uint32
+}
+
+// values returns the values of the channels of `kind` for this sample. Only the
+// first `n` of them are used.
+func (s *Sample) values(kind Kind) (v [3]float64, n int) {
+	switch kind {
+	case KindDepth:
+		return [3]float64{s.Depth}, 1
+	case KindNormal:
+		return [3]float64{s.Normal.X, s.Normal.Y, s.Normal.Z}, 3
+	case KindAlbedo:
+		return [3]float64{s.Albedo.Red(), s.Albedo.Green(), s.Albedo.Blue()}, 3
+	case KindPosition:
+		return [3]float64{s.Position.X, s.Position.Y, s.Position.Z}, 3
+	case KindUV:
+		return [3]float64{s.U, s.V}, 2
+	case KindPrimitiveID:
+		return [3]float64{float64(s.PrimitiveID + 1)}, 1
+	default:
+		return [3]float64{float64(s.MaterialID)}, 1
+	}
+}
+
+// MaterialID returns the ID of a material for [Sample.MaterialID]. It is a hash of
+// all material properties and is never zero.
+func MaterialID(m *mat.Material) uint32 {
+	h := fnv.New32a()
+
+	var buf [8]byte
+	write := func(v float64) {
+		bits := math.Float64bits(v)
+		for i := range buf {
+			buf[i] = byte(bits >> (8 * i))
+		}
+		h.Write(buf[:])
+	}
+
+	if m.Color != nil {
+		write(m.Color.Red())
+		write(m.Color.Green())
+		write(m.Color.Blue())
+	}
+	write(m.Refl)
+	write(m.Diff)
+	write(m.Refr)
+	write(m.RefrIndex)
+
+	return max(h.Sum32(), 1)
+}
+
+// Buffers accumulates the output variables for every pixel of an image. Values of
+// all samples which hit a surface in a pixel are averaged, except for the IDs for
+// which the value of the first such sample is kept. Pixels in which nothing was hit
+// are zero.
+//
+// Concurrent calls to [Buffers.Add] are safe as long as they are for different
+// pixels.
+type Buffers struct {
+	width, height int
+
+	kinds []Kind
+
+	// data holds the values of every kind. There are as many values per pixel as
+	// there are channels of the kind.
+	data [][]float64
+
+	// hits is the number of samples which hit something in every pixel.
+	hits []uint32
+}
+
+// NewBuffers returns buffers for an image with the given size which collect the
+// output variables in `kinds`.
+func NewBuffers(width, height int, kinds []Kind) *Buffers {
+	b := &Buffers{
+		width:  width,
+		height: height,
+		kinds:  kinds,
+		data:   make([][]float64, len(kinds)),
+		hits:   make([]uint32, width*height),
+	}
+
+	for i, kind := range kinds {
+		b.data[i] = make([]float64, width*height*len(kind.channels()))
+	}
+
+	return b
+}
+
+// Kinds returns the output variables collected in these buffers.
+func (b *Buffers) Kinds() []Kind {
+	return b.kinds
+}
+
+// Add records the sample `s` for the pixel at (x, y). Samples outside of the image
+// are ignored.
+func (b *Buffers) Add(x, y int, s *Sample) {
+	if x < 0 || y < 0 || x >= b.width || y >= b.height {
+		return
+	}
+
+	ind := y*b.width + x
+	b.hits[ind]++
+	hits := float64(b.hits[ind])
+
+	for i, kind := range b.kinds {
+		values, n := s.values(kind)
+		pixel := b.data[i][ind*n : (ind+1)*n]
+
+		for ch, v := range values[:n] {
+			if kind.isID() {
+				if hits == 1 {
+					pixel[ch] = v
+				}
+				continue
+			}
+			pixel[ch] += (v - pixel[ch]) / hits
+		}
+	}
+}
+
+// Values returns the values of the output variable `kind` for all pixels or nil
+// when it is not collected. They are in row order and every pixel has as many
+// consecutive values as there are channels of the variable: one for the depth and
+// the IDs, two for UV and three for the rest.
+func (b *Buffers) Values(kind Kind) []float64 {
+	data, _ := b.pixels(kind)
+	return data
+}
+
+// Hits returns the number of samples for the pixel at (x, y) which hit a surface.
+func (b *Buffers) Hits(x, y int) int {
+	return int(b.hits[y*b.width+x])
+}
+
+// Width returns the width of the image in pixels.
+func (b *Buffers) Width() int {
+	return b.width
+}
+
+// Height returns the height of the image in pixels.
+func (b *Buffers) Height() int {
+	return b.height
+}
+
+// Reset discards all samples.
+func (b *Buffers) Reset() {
+	clear(b.hits)
+	for _, d := range b.data {
+		clear(d)
+	}
+}
+
+// buffersMagic starts the binary form of Buffers. Its last byte is the version of
+// the format.
+var buffersMagic = [4]byte{'a', 'o', 'v', 1}
+
+// MarshalBinary implements encoding.BinaryMarshaler. It returns the collected
+// values of all pixels so that they can be restored with UnmarshalBinary after a
+// restart of the program.
+func (b *Buffers) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write(buffersMagic[:])
+
+	kinds := make([]uint32, len(b.kinds))
+	for i, k := range b.kinds {
+		kinds[i] = uint32(k)
+	}
+
+	header := []any{uint32(b.width), uint32(b.height), uint32(len(kinds)), kinds, b.hits}
+	for _, data := range header {
+		if err := binary.Write(&buf, binary.LittleEndian, data); err != nil {
+			return nil, err
+		}
+	}
+	for _, data := range b.data {
+		if err := binary.Write(&buf, binary.LittleEndian, data); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. It replaces all values
+// with the ones in `data` which must be for an image with the same size and the
+// same output variables.
+func (b *Buffers) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+
+	var (
+		magic                   [4]byte
+		width, height, numKinds uint32
+	)
+	for _, v := range []any{&magic, &width, &height, &numKinds} {
+		if err := binary.Read(r, binary.LittleEndian, v); err != nil {
+			return fmt.Errorf("reading output variables: %w", err)
+		}
+	}
+
+	if magic != buffersMagic {
+		return fmt.Errorf("unknown format of the output variables")
+	}
+	if int(width) != b.width || int(height) != b.height {
+		return fmt.Errorf("output variables are for a %dx%d image but this one is %dx%d",
+			width, height, b.width, b.height)
+	}
+
+	if int(numKinds) != len(b.kinds) {
+		return fmt.Errorf("there are %d output variables but %d are collected",
+			numKinds, len(b.kinds))
+	}
+	kinds := make([]uint32, numKinds)
+	if err := binary.Read(r, binary.LittleEndian, kinds); err != nil {
+		return fmt.Errorf("reading output variables: %w", err)
+	}
+	if !slices.EqualFunc(kinds, b.kinds, func(a uint32, k Kind) bool {
+		return a == uint32(k)
+	}) {
+		return fmt.Errorf("output variables %v are not the collected %v", kinds, b.kinds)
+	}
+
+	restored := NewBuffers(b.width, b.height, b.kinds)
+	if err := binary.Read(r, binary.LittleEndian, restored.hits); err != nil {
+		return fmt.Errorf("reading output variables: %w", err)
+	}
+	for _, v := range restored.data {
+		if err := binary.Read(r, binary.LittleEndian, v); err != nil {
+			return fmt.Errorf("reading output variables: %w", err)
+		}
+	}
+	if r.Len() != 0 {
+		return fmt.Errorf("%d unexpected bytes after the output variables", r.Len())
+	}
+
+	copy(b.hits, restored.hits)
+	for i := range b.data {
+		copy(b.data[i], restored.data[i])
+	}
+	return nil
+}
+
+// pixels returns the values of `kind` for every pixel and whether it is collected.
+func (b *Buffers) pixels(kind Kind) ([]float64, bool) {
+	for i, k := range b.kinds {
+		if k == kind {
+			return b.data[i], true
+		}
+	}
+	return nil, false
+}
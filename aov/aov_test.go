@@ -0,0 +1,185 @@
+package aov
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/ironsmile/raytracer/geometry"
+)
+
+// TestBuffersAdd checks that values are averaged over the samples of a pixel
+// except for the IDs which are kept from the first sample.
+func TestBuffersAdd(t *testing.T) {
+	b := NewBuffers(4, 3, []Kind{KindDepth, KindNormal, KindPrimitiveID})
+
+	b.Add(1, 2, &Sample{Depth: 2, Normal: geometry.NewVector(0, 1, 0), PrimitiveID: 5})
+	b.Add(1, 2, &Sample{Depth: 4, Normal: geometry.NewVector(0, 0, 1), PrimitiveID: 8})
+	b.Add(7, 7, &Sample{Depth: 100})
+
+	depth, _ := b.pixels(KindDepth)
+	normal, _ := b.pixels(KindNormal)
+	ids, _ := b.pixels(KindPrimitiveID)
+
+	ind := 2*4 + 1
+	if depth[ind] != 3 {
+		t.Errorf("expected mean depth 3 but got %f", depth[ind])
+	}
+	if got := normal[ind*3 : ind*3+3]; got[0] != 0 || got[1] != 0.5 || got[2] != 0.5 {
+		t.Errorf("expected mean normal (0, 0.5, 0.5) but got %v", got)
+	}
+	if ids[ind] != 6 {
+		t.Errorf("expected the first primitive ID plus one (6) but got %f", ids[ind])
+	}
+
+	for i, v := range depth {
+		if i != ind && v != 0 {
+			t.Errorf("pixel %d without samples has depth %f", i, v)
+		}
+	}
+
+	if _, err := b.Image(KindAlbedo); err == nil {
+		t.Errorf("expected an error for an output variable which is not collected")
+	}
+}
+
+// TestWriteEXR checks the structure of the written OpenEXR files: the header, the
+// channel list, the offset table and the pixel values.
+func TestWriteEXR(t *testing.T) {
+	const width, height = 3, 2
+
+	b := NewBuffers(width, height, []Kind{KindUV, KindMaterialID, KindDepth})
+	b.Add(2, 1, &Sample{Depth: 1.5, U: 0.25, V: 0.75, MaterialID: 77})
+
+	var buf bytes.Buffer
+	if err := b.WriteEXR(&buf); err != nil {
+		t.Fatalf("writing EXR: %s", err)
+	}
+	data := buf.Bytes()
+	le := binary.LittleEndian
+
+	if !bytes.Equal(data[:8], []byte{0x76, 0x2f, 0x31, 0x01, 2, 0, 0, 0}) {
+		t.Fatalf("wrong magic number and version: %v", data[:8])
+	}
+
+	cstring := func(pos int) (string, int) {
+		end := bytes.IndexByte(data[pos:], 0)
+		return string(data[pos : pos+end]), pos + end + 1
+	}
+
+	attrs := map[string][]byte{}
+	pos := 8
+	for data[pos] != 0 {
+		var name string
+		name, pos = cstring(pos)
+		_, pos = cstring(pos)
+		size := int(le.Uint32(data[pos:]))
+		attrs[name] = data[pos+4 : pos+4+size]
+		pos += 4 + size
+	}
+	pos++
+
+	for _, name := range []string{"channels", "compression", "dataWindow", "displayWindow",
+		"lineOrder", "pixelAspectRatio", "screenWindowCenter", "screenWindowWidth"} {
+		if _, ok := attrs[name]; !ok {
+			t.Errorf("required attribute %s is missing", name)
+		}
+	}
+
+	var (
+		names []string
+		types []uint32
+	)
+	chlist := attrs["channels"]
+	for p := 0; chlist[p] != 0; p += 16 {
+		end := bytes.IndexByte(chlist[p:], 0)
+		names = append(names, string(chlist[p:p+end]))
+		p += end + 1
+		types = append(types, le.Uint32(chlist[p:]))
+	}
+
+	wantNames := []string{"depth.Z", "material-id.id", "uv.U", "uv.V"}
+	wantTypes := []uint32{exrFloat, exrUint, exrFloat, exrFloat}
+	if len(names) != len(wantNames) {
+		t.Fatalf("expected channels %v but got %v", wantNames, names)
+	}
+	for i := range names {
+		if names[i] != wantNames[i] || types[i] != wantTypes[i] {
+			t.Errorf("channel %d: expected %s of type %d but got %s of type %d",
+				i, wantNames[i], wantTypes[i], names[i], types[i])
+		}
+	}
+
+	lineSize := 4 * width * len(names)
+	for y := range height {
+		offset := int(le.Uint64(data[pos+8*y:]))
+		if got := int(le.Uint32(data[offset:])); got != y {
+			t.Fatalf("block at offset %d is for line %d instead of %d", offset, got, y)
+		}
+		if got := int(le.Uint32(data[offset+4:])); got != lineSize {
+			t.Fatalf("line %d has size %d instead of %d", y, got, lineSize)
+		}
+	}
+
+	lastLine := int(le.Uint64(data[pos+8*(height-1):])) + 8
+	if len(data) != lastLine+lineSize {
+		t.Errorf("file has %d bytes instead of %d", len(data), lastLine+lineSize)
+	}
+
+	value := func(channel, x int) uint32 {
+		return le.Uint32(data[lastLine+4*(channel*width+x):])
+	}
+	if got := math.Float32frombits(value(0, 2)); got != 1.5 {
+		t.Errorf("expected depth 1.5 but got %f", got)
+	}
+	if got := value(1, 2); got != 77 {
+		t.Errorf("expected material ID 77 but got %d", got)
+	}
+	if got := math.Float32frombits(value(3, 2)); got != 0.75 {
+		t.Errorf("expected V 0.75 but got %f", got)
+	}
+	if got := value(1, 0); got != 0 {
+		t.Errorf("expected material ID 0 for a pixel without samples but got %d", got)
+	}
+}
+
+// TestBuffersMarshal checks that buffers are restored from their binary form and
+// that buffers with other output variables are not.
+func TestBuffersMarshal(t *testing.T) {
+	kinds := []Kind{KindDepth, KindNormal, KindPrimitiveID}
+	b := NewBuffers(4, 3, kinds)
+	b.Add(1, 2, &Sample{Depth: 2, Normal: geometry.NewVector(0, 1, 0), PrimitiveID: 5})
+	b.Add(1, 2, &Sample{Depth: 4, Normal: geometry.NewVector(0, 0, 1), PrimitiveID: 8})
+	b.Add(3, 0, &Sample{Depth: 7})
+
+	data, err := b.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewBuffers(4, 3, kinds)
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatalf("restoring: %s", err)
+	}
+	for _, kind := range kinds {
+		want, got := b.Values(kind), restored.Values(kind)
+		if !slices.Equal(got, want) {
+			t.Errorf("restored %s is %v instead of %v", kind, got, want)
+		}
+	}
+	if restored.Hits(1, 2) != 2 {
+		t.Errorf("restored pixel has %d hits instead of 2", restored.Hits(1, 2))
+	}
+
+	for _, other := range []*Buffers{
+		NewBuffers(3, 4, kinds),
+		NewBuffers(4, 3, []Kind{KindDepth, KindNormal}),
+		NewBuffers(4, 3, []Kind{KindDepth, KindNormal, KindMaterialID}),
+	} {
+		if err := other.UnmarshalBinary(data); err == nil {
+			t.Errorf("buffers with other size or kinds %v were restored", other.Kinds())
+		}
+	}
+}
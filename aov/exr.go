@@ -0,0 +1,162 @@
+package aov
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"sort"
+)
+
+// OpenEXR pixel types.
+const (
+	exrUint  = 0
+	exrFloat = 2
+)
+
+// exrChannel is a single channel of an OpenEXR image.
+type exrChannel struct {
+	name      string
+	pixelType int32
+
+	// values holds the values of the channel for every pixel.
+	values func(ind int) float64
+}
+
+// SaveEXR writes all output variables in a single OpenEXR file. See
+// [Buffers.WriteEXR].
+func (b *Buffers) SaveEXR(filename string) error {
+	out, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if err := b.WriteEXR(out); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// WriteEXR writes all output variables as layers of an uncompressed scan line
+// OpenEXR image. Every variable is a layer named after it. Channels are named
+// "<layer>.<channel>", for example "normal.X" and "depth.Z". IDs are 32 bit
+// unsigned integers and all other channels are 32 bit floats.
+func (b *Buffers) WriteEXR(w io.Writer) error {
+	var channels []exrChannel
+	for i, kind := range b.kinds {
+		data := b.data[i]
+		names := kind.channels()
+
+		pixelType := int32(exrFloat)
+		if kind.isID() {
+			pixelType = exrUint
+		}
+
+		for ch, name := range names {
+			channels = append(channels, exrChannel{
+				name:      kind.String() + "." + name,
+				pixelType: pixelType,
+				values: func(ind int) float64 {
+					return data[ind*len(names)+ch]
+				},
+			})
+		}
+	}
+
+	return writeEXR(w, b.width, b.height, channels)
+}
+
+// writeEXR writes an uncompressed scan line OpenEXR image with the given channels.
+// The format is described in "The OpenEXR File Layout" at
+// https://openexr.com/en/latest/OpenEXRFileLayout.html.
+func writeEXR(w io.Writer, width, height int, channels []exrChannel) error {
+	// Channels are always stored in alphabetical order.
+	sort.Slice(channels, func(i, j int) bool {
+		return channels[i].name < channels[j].name
+	})
+
+	var header bytes.Buffer
+	le := binary.LittleEndian
+
+	// Magic number and version 2 with no flags for a single part scan line file.
+	header.Write([]byte{0x76, 0x2f, 0x31, 0x01, 2, 0, 0, 0})
+
+	attribute := func(name, typ string, value []byte) {
+		header.WriteString(name)
+		header.WriteByte(0)
+		header.WriteString(typ)
+		header.WriteByte(0)
+		header.Write(le.AppendUint32(nil, uint32(len(value))))
+		header.Write(value)
+	}
+
+	var chlist []byte
+	for _, ch := range channels {
+		chlist = append(chlist, ch.name...)
+		chlist = append(chlist, 0)
+		chlist = le.AppendUint32(chlist, uint32(ch.pixelType))
+		chlist = append(chlist, 0, 0, 0, 0) // pLinear and reserved
+		chlist = le.AppendUint32(chlist, 1) // x sampling
+		chlist = le.AppendUint32(chlist, 1) // y sampling
+	}
+	chlist = append(chlist, 0)
+
+	var box []byte
+	for _, v := range []int{0, 0, width - 1, height - 1} {
+		box = le.AppendUint32(box, uint32(int32(v)))
+	}
+
+	one := le.AppendUint32(nil, math.Float32bits(1))
+
+	attribute("channels", "chlist", chlist)
+	attribute("compression", "compression", []byte{0})
+	attribute("dataWindow", "box2i", box)
+	attribute("displayWindow", "box2i", box)
+	attribute("lineOrder", "lineOrder", []byte{0})
+	attribute("pixelAspectRatio", "float", one)
+	attribute("screenWindowCenter", "v2f", make([]byte, 8))
+	attribute("screenWindowWidth", "float", one)
+	header.WriteByte(0)
+
+	// Every scan line is a separate block and is preceded by its y coordinate and
+	// its size in bytes. The header is followed by a table with the offsets of all
+	// blocks.
+	lineSize := 4 * width * len(channels)
+	blocksStart := header.Len() + 8*height
+
+	for y := range height {
+		offset := blocksStart + y*(8+lineSize)
+		header.Write(le.AppendUint64(nil, uint64(offset)))
+	}
+
+	bw := bufio.NewWriter(w)
+	if _, err := bw.Write(header.Bytes()); err != nil {
+		return err
+	}
+
+	line := make([]byte, 0, 8+lineSize)
+	for y := range height {
+		line = le.AppendUint32(line[:0], uint32(y))
+		line = le.AppendUint32(line, uint32(lineSize))
+
+		for _, ch := range channels {
+			for x := range width {
+				v := ch.values(y*width + x)
+				if ch.pixelType == exrUint {
+					line = le.AppendUint32(line, uint32(v))
+				} else {
+					line = le.AppendUint32(line, math.Float32bits(float32(v)))
+				}
+			}
+		}
+
+		if _, err := bw.Write(line); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,118 @@
+package aov
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+
+	"github.com/ironsmile/raytracer/utils"
+)
+
+// SavePNG writes the output variable `kind` as a PNG image. See [Buffers.Image]
+// for how its values are turned into colours.
+func (b *Buffers) SavePNG(kind Kind, filename string) error {
+	img, err := b.Image(kind)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if err := png.Encode(out, img); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// Image returns an image which visualises the output variable `kind`. Pixels in
+// which nothing was hit are black. Otherwise:
+//
+//   - depth and position are scaled to the range of their values in the image so
+//     that near surfaces are dark and far ones are bright;
+//   - normals have their components mapped from [-1, 1] to [0, 1];
+//   - albedo is the colour itself;
+//   - U and V are the red and green channels, wrapped to [0, 1);
+//   - every ID has its own random colour.
+//
+// It returns an error when the variable is not collected in these buffers.
+func (b *Buffers) Image(kind Kind) (image.Image, error) {
+	data, ok := b.pixels(kind)
+	if !ok {
+		return nil, fmt.Errorf("output variable %s is not collected", kind)
+	}
+
+	n := len(kind.channels())
+	img := image.NewNRGBA64(image.Rect(0, 0, b.width, b.height))
+
+	// Depth and position are scaled using the smallest and biggest value of any
+	// of their channels.
+	low, high := math.Inf(1), math.Inf(-1)
+	for ind, hits := range b.hits {
+		if hits == 0 {
+			continue
+		}
+		for _, v := range data[ind*n : (ind+1)*n] {
+			low, high = min(low, v), max(high, v)
+		}
+	}
+	scale := 1 / max(high-low, 1e-9)
+
+	for ind, hits := range b.hits {
+		if hits == 0 {
+			img.SetNRGBA64(ind%b.width, ind/b.width, color.NRGBA64{A: 0xffff})
+			continue
+		}
+
+		var rgb [3]float64
+		pixel := data[ind*n : (ind+1)*n]
+
+		switch kind {
+		case KindDepth:
+			v := (pixel[0] - low) * scale
+			rgb = [3]float64{v, v, v}
+		case KindPosition:
+			for ch := range rgb {
+				rgb[ch] = (pixel[ch] - low) * scale
+			}
+		case KindNormal:
+			for ch := range rgb {
+				rgb[ch] = pixel[ch]*0.5 + 0.5
+			}
+		case KindAlbedo:
+			copy(rgb[:], pixel)
+		case KindUV:
+			rgb[0] = pixel[0] - math.Floor(pixel[0])
+			rgb[1] = pixel[1] - math.Floor(pixel[1])
+		default:
+			rgb = idColor(uint64(pixel[0]))
+		}
+
+		img.SetNRGBA64(ind%b.width, ind/b.width, color.NRGBA64{
+			R: uint16(utils.Clamp(rgb[0], 0, 1) * 0xffff),
+			G: uint16(utils.Clamp(rgb[1], 0, 1) * 0xffff),
+			B: uint16(utils.Clamp(rgb[2], 0, 1) * 0xffff),
+			A: 0xffff,
+		})
+	}
+
+	return img, nil
+}
+
+// idColor returns a bright random colour for `id`.
+func idColor(id uint64) [3]float64 {
+	h := id*0x9e3779b97f4a7c15 + 0x632be59bd9b4e019
+	h ^= h >> 29
+
+	var rgb [3]float64
+	for ch := range rgb {
+		rgb[ch] = 0.25 + 0.75*float64((h>>(16*ch))&0xffff)/0xffff
+	}
+	return rgb
+}
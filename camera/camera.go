@@ -19,4 +19,23 @@ type Camera interface {
 
 	Yaw(float64) error
 	Pitch(float64) error
+	Roll(float64) error
+
+	// SetPosition moves the camera without turning it and LookAt turns it
+	// toward a point.
+	SetPosition(geometry.Vector) error
+	LookAt(geometry.Vector) error
+
+	// SetFOV sets the field of view in degrees and Zoom narrows the view by a
+	// factor, widening it for factors less than one. Cameras which do not
+	// support them return an error.
+	SetFOV(float64) error
+	Zoom(float64) error
+
+	// State returns the current projection, position and orientation of the
+	// camera and SetState moves and turns it to a state. SetState ignores the
+	// projection of the state and its field of view or size when they are zero
+	// or not supported by the camera.
+	State() State
+	SetState(State) error
 }
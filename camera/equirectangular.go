@@ -0,0 +1,72 @@
+package camera
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ironsmile/raytracer/geometry"
+)
+
+// EquirectangularCamera sees the whole sphere around it. The horizontal axis of
+// the image is the longitude, from -180 to 180 degrees, and the vertical axis is
+// the latitude, from 90 to -90 degrees. The centre of the image is the viewing
+// direction. Such images are used for 360 degree and VR previews and have an
+// aspect ratio of 2:1.
+type EquirectangularCamera struct {
+	view
+
+	rasterW, rasterH float64
+}
+
+// GenerateRay creates a ray from the camera source in the direction seen at the
+// raster position (x, y).
+func (e *EquirectangularCamera) GenerateRay(x, y float64) geometry.Ray {
+	phi := (x/e.rasterW - 0.5) * 2 * math.Pi
+	lambda := (0.5 - y/e.rasterH) * math.Pi
+
+	cosLambda := math.Cos(lambda)
+	dir := geometry.NewVector(
+		cosLambda*math.Sin(phi),
+		math.Sin(lambda),
+		cosLambda*math.Cos(phi),
+	)
+
+	return e.camToWorld.Ray(geometry.NewRay(geometry.NewVector(0, 0, 0), dir))
+}
+
+// SetFOV returns an error because equirectangular cameras always see all
+// directions.
+func (e *EquirectangularCamera) SetFOV(float64) error {
+	return fmt.Errorf("equirectangular cameras have a fixed field of view")
+}
+
+// Zoom returns an error because equirectangular cameras always see all
+// directions.
+func (e *EquirectangularCamera) Zoom(float64) error {
+	return fmt.Errorf("equirectangular cameras cannot zoom")
+}
+
+// State implements [Camera].
+func (e *EquirectangularCamera) State() State {
+	state := e.state()
+	state.Projection = ProjectionEquirectangular
+	return state
+}
+
+// SetState implements [Camera].
+func (e *EquirectangularCamera) SetState(s State) error {
+	return e.setState(s)
+}
+
+// NewEquirectangular returns a panoramic camera for an image with the given size.
+func NewEquirectangular(
+	position, lookAt, up geometry.Vector,
+	width, height float64,
+) *EquirectangularCamera {
+	cam := &EquirectangularCamera{
+		rasterW: width,
+		rasterH: height,
+	}
+	cam.init(position, lookAt, up)
+	return cam
+}
@@ -0,0 +1,147 @@
+package camera
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ironsmile/raytracer/geometry"
+)
+
+// FisheyeMapping is the way in which a [FisheyeCamera] maps the angle between a
+// ray and the viewing direction to the distance from the centre of the image.
+type FisheyeMapping int
+
+const (
+	// FisheyeEquidistant makes the distance proportional to the angle.
+	FisheyeEquidistant FisheyeMapping = iota
+
+	// FisheyeEquisolid preserves the areas. It squeezes the edges of the image
+	// circle more than FisheyeEquidistant.
+	FisheyeEquisolid
+)
+
+// String implements fmt.Stringer.
+func (m FisheyeMapping) String() string {
+	switch m {
+	case FisheyeEquidistant:
+		return "equidistant"
+	case FisheyeEquisolid:
+		return "equisolid"
+	default:
+		return fmt.Sprintf("FisheyeMapping(%d)", int(m))
+	}
+}
+
+// FisheyeCamera projects the scene in a circle inscribed in the image. The field
+// of view may be up to 360 degrees. Points of the image outside of the circle are
+// not seen, see [Blind].
+type FisheyeCamera struct {
+	view
+
+	mapping FisheyeMapping
+
+	// halfFOV is half of the field of view in radians.
+	halfFOV float64
+	screen  [4]float64
+
+	rasterW, rasterH float64
+}
+
+// GenerateRay creates a ray from the camera source in the direction seen at the
+// raster position (x, y).
+func (f *FisheyeCamera) GenerateRay(x, y float64) geometry.Ray {
+	posX, posY := screenPoint(f.screen, x, y, f.rasterW, f.rasterH)
+
+	r := math.Hypot(posX, posY)
+	if r > 1 {
+		return blindRay
+	}
+
+	var theta float64
+	switch f.mapping {
+	case FisheyeEquisolid:
+		theta = 2 * math.Asin(r*math.Sin(f.halfFOV/2))
+	default:
+		theta = r * f.halfFOV
+	}
+
+	dir := geometry.NewVector(0, 0, 1)
+	if r > 0 {
+		sinTheta := math.Sin(theta)
+		dir = geometry.NewVector(sinTheta*posX/r, sinTheta*posY/r, math.Cos(theta))
+	}
+
+	return f.camToWorld.Ray(geometry.NewRay(geometry.NewVector(0, 0, 0), dir))
+}
+
+// SetFOV sets the field of view of the image circle in degrees. It may be up to
+// 360.
+func (f *FisheyeCamera) SetFOV(fov float64) error {
+	if fov <= 0 || fov > 360 {
+		return fmt.Errorf("fisheye field of view must be between 0 and 360 degrees")
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	f.halfFOV = geometry.Radians(fov) / 2
+	return nil
+}
+
+// Zoom makes the field of view `factor` times narrower. It does not get wider
+// than 360 degrees.
+func (f *FisheyeCamera) Zoom(factor float64) error {
+	if err := checkZoom(factor); err != nil {
+		return err
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	f.halfFOV = min(f.halfFOV/factor, math.Pi)
+	return nil
+}
+
+// State implements [Camera].
+func (f *FisheyeCamera) State() State {
+	state := f.state()
+	state.Projection = ProjectionFisheyeEquidistant
+	if f.mapping == FisheyeEquisolid {
+		state.Projection = ProjectionFisheyeEquisolid
+	}
+
+	f.RLock()
+	state.FOV = f.halfFOV * 2 * 180 / math.Pi
+	f.RUnlock()
+
+	return state
+}
+
+// SetState implements [Camera].
+func (f *FisheyeCamera) SetState(s State) error {
+	if s.FOV != 0 {
+		if err := f.SetFOV(s.FOV); err != nil {
+			return err
+		}
+	}
+	return f.setState(s)
+}
+
+// NewFisheye returns a fisheye camera for an image with the given size. Its image
+// circle spans the shorter side of the image and covers `fov` degrees.
+func NewFisheye(
+	position, lookAt, up geometry.Vector,
+	mapping FisheyeMapping,
+	fov float64,
+	width, height float64,
+) *FisheyeCamera {
+	cam := &FisheyeCamera{
+		mapping: mapping,
+		halfFOV: geometry.Radians(fov) / 2,
+		screen:  screenWindow(width, height),
+		rasterW: width,
+		rasterH: height,
+	}
+	cam.init(position, lookAt, up)
+	return cam
+}
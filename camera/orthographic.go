@@ -0,0 +1,94 @@
+package camera
+
+import (
+	"fmt"
+
+	"github.com/ironsmile/raytracer/geometry"
+)
+
+// OrthographicCamera projects the scene on the image with parallel rays. Objects
+// keep their size regardless of their distance from the camera, which is useful
+// for architectural drawings.
+type OrthographicCamera struct {
+	view
+
+	// halfSize is half of the size of the shorter side of the image in world
+	// units.
+	halfSize float64
+	screen   [4]float64
+
+	rasterW, rasterH float64
+}
+
+// GenerateRay creates a ray which goes straight forward from the point of the
+// image plane for the raster position (x, y).
+func (o *OrthographicCamera) GenerateRay(x, y float64) geometry.Ray {
+	posX, posY := screenPoint(o.screen, x, y, o.rasterW, o.rasterH)
+	ray := geometry.NewRay(
+		geometry.NewVector(posX*o.halfSize, posY*o.halfSize, 0),
+		geometry.NewVector(0, 0, 1),
+	)
+
+	return o.camToWorld.Ray(ray)
+}
+
+// SetFOV returns an error because orthographic cameras have no field of view. Use
+// [OrthographicCamera.Zoom] instead.
+func (o *OrthographicCamera) SetFOV(float64) error {
+	return fmt.Errorf("orthographic cameras have no field of view")
+}
+
+// Zoom makes the area seen by the camera `factor` times smaller.
+func (o *OrthographicCamera) Zoom(factor float64) error {
+	if err := checkZoom(factor); err != nil {
+		return err
+	}
+
+	o.Lock()
+	defer o.Unlock()
+
+	o.halfSize /= factor
+	return nil
+}
+
+// State implements [Camera].
+func (o *OrthographicCamera) State() State {
+	state := o.state()
+	state.Projection = ProjectionOrthographic
+
+	o.RLock()
+	state.Size = o.halfSize * 2
+	o.RUnlock()
+
+	return state
+}
+
+// SetState implements [Camera].
+func (o *OrthographicCamera) SetState(s State) error {
+	if s.Size < 0 {
+		return fmt.Errorf("orthographic size must not be negative")
+	}
+	if s.Size != 0 {
+		o.Lock()
+		o.halfSize = s.Size / 2
+		o.Unlock()
+	}
+	return o.setState(s)
+}
+
+// NewOrthographic returns a camera for an image with the given size. `size` is the
+// length in world units of the shorter side of the area seen by the camera.
+func NewOrthographic(
+	position, lookAt, up geometry.Vector,
+	size float64,
+	width, height float64,
+) *OrthographicCamera {
+	cam := &OrthographicCamera{
+		halfSize: size / 2,
+		screen:   screenWindow(width, height),
+		rasterW:  width,
+		rasterH:  height,
+	}
+	cam.init(position, lookAt, up)
+	return cam
+}
@@ -1,32 +1,26 @@
 package camera
 
 import (
-	"sync"
+	"fmt"
+	"math"
 
 	"github.com/ironsmile/raytracer/geometry"
-	"github.com/ironsmile/raytracer/transform"
 )
 
 // PinholeCamera is the most basic type of camera. One in which the scene is projected on a
 // rectangle and the viewer is a single point behind the screen.
 type PinholeCamera struct {
-	camToWorld *transform.Transform
-	distance   float64
-	screen     [4]float64
+	view
 
-	rasterW, rasterH float64
-
-	origin geometry.Vector
-	lookAt geometry.Vector
-	up     geometry.Vector
+	distance float64
+	screen   [4]float64
 
-	sync.RWMutex
+	rasterW, rasterH float64
 }
 
 // GenerateRay creates a ray from the camera source through one single point of the screen
 func (p *PinholeCamera) GenerateRay(x, y float64) geometry.Ray {
-	posX := p.screen[0] + (x/p.rasterW)*p.screen[1]*2
-	posY := p.screen[3] + (y/p.rasterH)*p.screen[2]*2
+	posX, posY := screenPoint(p.screen, x, y, p.rasterW, p.rasterH)
 	ray := geometry.NewRay(
 		geometry.NewVector(0, 0, 0),
 		geometry.NewVector(posX, posY, p.distance).Normalize(),
@@ -35,97 +29,61 @@ func (p *PinholeCamera) GenerateRay(x, y float64) geometry.Ray {
 	return p.camToWorld.Ray(ray)
 }
 
-// Forward moves the camera in its lookAt direction
-func (p *PinholeCamera) Forward(speed float64) error {
-	p.Lock()
-	defer p.Unlock()
-
-	dir := p.lookAt.Minus(p.origin).Normalize().MultiplyScalar(speed)
-	p.move(dir)
-	return nil
-}
-
-// Backward moves the camera opposite its lookAt direction
-func (p *PinholeCamera) Backward(speed float64) error {
-	p.Lock()
-	defer p.Unlock()
-
-	dir := p.lookAt.Minus(p.origin).Normalize().MultiplyScalar(speed).Neg()
-	p.move(dir)
-	return nil
-}
-
-// Left moves the camera to the left relative to its lookAt and up directions
-func (p *PinholeCamera) Left(speed float64) error {
-	p.Lock()
-	defer p.Unlock()
-
-	dir := p.lookAt.Minus(p.origin).Normalize()
-	dir = p.up.Cross(dir).MultiplyScalar(speed).Neg()
-	p.move(dir)
-	return nil
-}
+// SetFOV sets the field of view in degrees across the shorter side of the image.
+// It must be less than 180.
+func (p *PinholeCamera) SetFOV(fov float64) error {
+	if fov <= 0 || fov >= 180 {
+		return fmt.Errorf("pinhole field of view must be between 0 and 180 degrees")
+	}
 
-// Up moves the camera straight up regardless of its orientation.
-func (p *PinholeCamera) Up(speed float64) error {
 	p.Lock()
 	defer p.Unlock()
 
-	p.move(geometry.NewVector(0, 1, 0).MultiplyScalar(speed))
+	p.distance = FOVDistance(fov)
 	return nil
 }
 
-// Down moves the camera straight down regardless of its orientation.
-func (p *PinholeCamera) Down(speed float64) error {
-	p.Lock()
-	defer p.Unlock()
-
-	p.move(geometry.NewVector(0, -1, 0).MultiplyScalar(speed))
-	return nil
-}
+// Zoom narrows the field of view by moving the screen `factor` times further from
+// the viewer.
+func (p *PinholeCamera) Zoom(factor float64) error {
+	if err := checkZoom(factor); err != nil {
+		return err
+	}
 
-// Right moves the camera to the right relative to its lookAt and up directions
-func (p *PinholeCamera) Right(speed float64) error {
 	p.Lock()
 	defer p.Unlock()
 
-	dir := p.lookAt.Minus(p.origin).Normalize()
-	dir = p.up.Cross(dir).MultiplyScalar(speed)
-	p.move(dir)
+	p.distance *= factor
 	return nil
 }
 
-func (p *PinholeCamera) move(dir geometry.Vector) {
-	p.origin = p.origin.Plus(dir)
-	p.lookAt = p.lookAt.Plus(dir)
-	p.computeMatrix()
-}
+// State implements [Camera].
+func (p *PinholeCamera) State() State {
+	state := p.state()
+	state.Projection = ProjectionPinhole
 
-func (p *PinholeCamera) computeMatrix() {
-	p.camToWorld = transform.LookAt(p.origin, p.lookAt, p.up).Inverse()
-}
+	p.RLock()
+	state.FOV = 2 * math.Atan(1/p.distance) * 180 / math.Pi
+	p.RUnlock()
 
-// Yaw rotats the camera around its up axis
-func (p *PinholeCamera) Yaw(angle float64) error {
-	p.Lock()
-	defer p.Unlock()
-
-	p.rotate(transform.RotateY(angle))
-	return nil
+	return state
 }
 
-// Pitch rotates the camera around on the lookAt axis
-func (p *PinholeCamera) Pitch(angle float64) error {
-	p.Lock()
-	defer p.Unlock()
-
-	p.rotate(transform.RotateX(angle))
-	return nil
+// SetState implements [Camera].
+func (p *PinholeCamera) SetState(s State) error {
+	if s.FOV != 0 {
+		if err := p.SetFOV(s.FOV); err != nil {
+			return err
+		}
+	}
+	return p.setState(s)
 }
 
-func (p *PinholeCamera) rotate(rotation *transform.Transform) {
-	p.lookAt = p.camToWorld.Point(rotation.Point(p.camToWorld.Inverse().Point(p.lookAt)))
-	p.computeMatrix()
+// FOVDistance returns the distance from the viewer to the screen of a pinhole
+// camera with a field of view of `fov` degrees across the shorter side of the
+// image. It is the `dist` argument of [NewPinhole].
+func FOVDistance(fov float64) float64 {
+	return 1 / math.Tan(geometry.Radians(fov)/2)
 }
 
 // NewPinhole returns a new camera which is set up for writing in particular output
@@ -137,30 +95,30 @@ func NewPinhole(
 	width float64,
 	height float64,
 ) *PinholeCamera {
-	cam := &PinholeCamera{
-		origin:   camPosition,
-		lookAt:   camLookAtPoint,
-		up:       camUp,
-		distance: dist}
-
-	cam.computeMatrix()
+	cam := &PinholeCamera{distance: dist}
+	cam.init(camPosition, camLookAtPoint, camUp)
 
 	cam.rasterW = width
 	cam.rasterH = height
+	cam.screen = screenWindow(width, height)
 
-	frame := cam.rasterW / cam.rasterH
+	return cam
+}
+
+// screenWindow returns the screen window of an image with the given size. Its
+// shorter side goes from -1 to 1. The elements are the minimum and maximum x
+// followed by the minimum and maximum y.
+func screenWindow(width, height float64) [4]float64 {
+	frame := width / height
 
 	if frame > 1.0 {
-		cam.screen[0] = -frame
-		cam.screen[1] = frame
-		cam.screen[2] = -1.0
-		cam.screen[3] = 1.0
-	} else {
-		cam.screen[0] = -1.0
-		cam.screen[1] = 1.0
-		cam.screen[2] = -1.0 / frame
-		cam.screen[3] = 1.0 / frame
+		return [4]float64{-frame, frame, -1.0, 1.0}
 	}
+	return [4]float64{-1.0, 1.0, -1.0 / frame, 1.0 / frame}
+}
 
-	return cam
+// screenPoint returns the point of the screen window `screen` for the raster
+// position (x, y) in an image with the given size.
+func screenPoint(screen [4]float64, x, y, width, height float64) (float64, float64) {
+	return screen[0] + (x/width)*screen[1]*2, screen[3] + (y/height)*screen[2]*2
 }
@@ -0,0 +1,187 @@
+package camera
+
+import (
+	"fmt"
+
+	"github.com/ironsmile/raytracer/geometry"
+)
+
+// Projection is a type of [Camera]. It is the way in which the camera projects the
+// scene on the image.
+type Projection int
+
+const (
+	// ProjectionPinhole is the perspective projection of [PinholeCamera].
+	ProjectionPinhole Projection = iota
+
+	// ProjectionOrthographic is the parallel projection of [OrthographicCamera].
+	ProjectionOrthographic
+
+	// ProjectionFisheyeEquidistant is a [FisheyeCamera] with [FisheyeEquidistant]
+	// mapping.
+	ProjectionFisheyeEquidistant
+
+	// ProjectionFisheyeEquisolid is a [FisheyeCamera] with [FisheyeEquisolid]
+	// mapping.
+	ProjectionFisheyeEquisolid
+
+	// ProjectionEquirectangular is the 360 degree panorama of
+	// [EquirectangularCamera].
+	ProjectionEquirectangular
+)
+
+// Projections are all the supported types of cameras.
+var Projections = []Projection{
+	ProjectionPinhole,
+	ProjectionOrthographic,
+	ProjectionFisheyeEquidistant,
+	ProjectionFisheyeEquisolid,
+	ProjectionEquirectangular,
+}
+
+// String implements fmt.Stringer.
+func (p Projection) String() string {
+	switch p {
+	case ProjectionPinhole:
+		return "pinhole"
+	case ProjectionOrthographic:
+		return "orthographic"
+	case ProjectionFisheyeEquidistant:
+		return "fisheye-equidistant"
+	case ProjectionFisheyeEquisolid:
+		return "fisheye-equisolid"
+	case ProjectionEquirectangular:
+		return "equirectangular"
+	default:
+		return fmt.Sprintf("Projection(%d)", int(p))
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler. The text is the name returned by
+// [Projection.String].
+func (p Projection) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. It accepts the names of
+// [ParseProjection].
+func (p *Projection) UnmarshalText(text []byte) error {
+	proj, err := ParseProjection(string(text))
+	if err != nil {
+		return err
+	}
+	*p = proj
+	return nil
+}
+
+// ParseProjection returns the Projection with the given name. The names are the
+// same as the ones returned by [Projection.String].
+func ParseProjection(name string) (Projection, error) {
+	for _, p := range Projections {
+		if p.String() == name {
+			return p, nil
+		}
+	}
+	return ProjectionPinhole, fmt.Errorf("unknown camera projection %q", name)
+}
+
+// Default fields of view in degrees for [New].
+const (
+	DefaultPinholeFOV = 90
+	DefaultFisheyeFOV = 180
+)
+
+// Options describe a camera created with [New].
+type Options struct {
+	Projection Projection
+
+	Position geometry.Vector
+	LookAt   geometry.Vector
+	Up       geometry.Vector
+
+	// FOV is the field of view in degrees across the shorter side of the image
+	// for pinhole and fisheye cameras. When zero [DefaultPinholeFOV] or
+	// [DefaultFisheyeFOV] is used.
+	FOV float64
+
+	// Size is the length in world units of the shorter side of the area seen by
+	// orthographic cameras. When zero it is the area seen by a pinhole camera
+	// with the default field of view at the LookAt point.
+	Size float64
+
+	// Width and Height are the size of the image in pixels.
+	Width, Height float64
+}
+
+// New returns a camera with the given options.
+func New(o Options) (Camera, error) {
+	if o.Width <= 0 || o.Height <= 0 {
+		return nil, fmt.Errorf("image size %gx%g is not positive", o.Width, o.Height)
+	}
+	if o.FOV < 0 || o.Size < 0 {
+		return nil, fmt.Errorf("field of view and size must not be negative")
+	}
+
+	switch o.Projection {
+	case ProjectionPinhole:
+		fov := o.FOV
+		if fov == 0 {
+			fov = DefaultPinholeFOV
+		}
+		if fov >= 180 {
+			return nil, fmt.Errorf("pinhole field of view must be less than 180 degrees")
+		}
+		return NewPinhole(o.Position, o.LookAt, o.Up, FOVDistance(fov), o.Width, o.Height), nil
+
+	case ProjectionOrthographic:
+		return NewOrthographic(o.Position, o.LookAt, o.Up, o.orthographicSize(), o.Width, o.Height), nil
+
+	case ProjectionFisheyeEquidistant, ProjectionFisheyeEquisolid:
+		fov := o.FOV
+		if fov == 0 {
+			fov = DefaultFisheyeFOV
+		}
+		if fov > 360 {
+			return nil, fmt.Errorf("fisheye field of view must be at most 360 degrees")
+		}
+
+		mapping := FisheyeEquidistant
+		if o.Projection == ProjectionFisheyeEquisolid {
+			mapping = FisheyeEquisolid
+		}
+		return NewFisheye(o.Position, o.LookAt, o.Up, mapping, fov, o.Width, o.Height), nil
+
+	case ProjectionEquirectangular:
+		return NewEquirectangular(o.Position, o.LookAt, o.Up, o.Width, o.Height), nil
+
+	default:
+		return nil, fmt.Errorf("unknown camera projection %s", o.Projection)
+	}
+}
+
+// orthographicSize returns the Size of an orthographic camera with the options,
+// replacing zero with its default.
+func (o Options) orthographicSize() float64 {
+	if o.Size != 0 {
+		return o.Size
+	}
+	return 2 * o.LookAt.Minus(o.Position).Length() / FOVDistance(DefaultPinholeFOV)
+}
+
+// checkZoom returns an error when `factor` cannot be used for zooming.
+func checkZoom(factor float64) error {
+	if factor <= 0 {
+		return fmt.Errorf("zoom factor %g is not positive", factor)
+	}
+	return nil
+}
+
+// blindRay is returned by cameras for points of the image which they do not see.
+var blindRay = geometry.Ray{}
+
+// Blind reports whether `ray` was generated for a point of the image which the
+// camera does not see, such as the corners of a [FisheyeCamera] image. Such rays
+// have no direction and must not be traced.
+func Blind(ray geometry.Ray) bool {
+	return ray.Direction == geometry.Vector{}
+}
@@ -0,0 +1,181 @@
+package camera
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ironsmile/raytracer/geometry"
+)
+
+var (
+	testPosition = geometry.NewVector(1, 2, 3)
+	testLookAt   = geometry.NewVector(1, 2, 10)
+	testUp       = geometry.NewVector(0, 1, 0)
+	testForward  = geometry.NewVector(0, 0, 1)
+)
+
+// TestParseProjection checks that all projections are parsed from their names.
+func TestParseProjection(t *testing.T) {
+	for _, p := range Projections {
+		got, err := ParseProjection(p.String())
+		if err != nil || got != p {
+			t.Errorf("parsing %s returned %s, %v", p, got, err)
+		}
+	}
+
+	if _, err := ParseProjection("nope"); err == nil {
+		t.Errorf("expected an error for an unknown projection")
+	}
+}
+
+// TestProjectionCentre checks that every camera looks toward its LookAt point in
+// the centre of the image.
+func TestProjectionCentre(t *testing.T) {
+	const width, height = 200, 100
+
+	for _, p := range Projections {
+		cam := newTestCamera(t, p, 0, width, height)
+		ray := cam.GenerateRay(width/2, height/2)
+
+		if angle := angleTo(ray.Direction, testForward); angle > 1e-9 {
+			t.Errorf("%s: central ray is %g degrees off", p, angle)
+		}
+		if !ray.Origin.Equals(testPosition) {
+			t.Errorf("%s: central ray starts at %s", p, ray.Origin)
+		}
+	}
+}
+
+// TestOrthographic checks that orthographic rays are parallel and that the image
+// has the requested size.
+func TestOrthographic(t *testing.T) {
+	cam, err := New(Options{
+		Projection: ProjectionOrthographic,
+		Position:   testPosition,
+		LookAt:     testLookAt,
+		Up:         testUp,
+		Size:       4,
+		Width:      300,
+		Height:     100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	top := cam.GenerateRay(150, 0)
+	bottom := cam.GenerateRay(150, 100)
+	corner := cam.GenerateRay(300, 100)
+
+	for _, ray := range []geometry.Ray{top, bottom, corner} {
+		if angle := angleTo(ray.Direction, testForward); angle > 1e-9 {
+			t.Errorf("ray from %s is %g degrees off", ray.Origin, angle)
+		}
+	}
+
+	if d := top.Origin.Minus(bottom.Origin).Length(); math.Abs(d-4) > 1e-9 {
+		t.Errorf("image is %g high instead of 4", d)
+	}
+	if d := corner.Origin.Minus(bottom.Origin).Length(); math.Abs(d-6) > 1e-9 {
+		t.Errorf("half of the image is %g wide instead of 6", d)
+	}
+}
+
+// TestFisheye checks that the edge of the image circle is at half the field of
+// view and that its outside is blind.
+func TestFisheye(t *testing.T) {
+	const size = 100
+
+	for _, p := range []Projection{ProjectionFisheyeEquidistant, ProjectionFisheyeEquisolid} {
+		for _, fov := range []float64{120, 180, 270} {
+			cam := newTestCamera(t, p, fov, size, size)
+
+			edge := cam.GenerateRay(size, size/2)
+			if angle := angleTo(edge.Direction, testForward); math.Abs(angle-fov/2) > 1e-6 {
+				t.Errorf("%s %g: edge of the circle is at %g degrees", p, fov, angle)
+			}
+
+			if ray := cam.GenerateRay(0, 0); !Blind(ray) {
+				t.Errorf("%s %g: corner of the image is not blind", p, fov)
+			}
+		}
+	}
+
+	// At half of the radius the equisolid mapping sees less than the equidistant.
+	equidistant := newTestCamera(t, ProjectionFisheyeEquidistant, 180, size, size)
+	equisolid := newTestCamera(t, ProjectionFisheyeEquisolid, 180, size, size)
+	a1 := angleTo(equidistant.GenerateRay(size*0.75, size/2).Direction, testForward)
+	a2 := angleTo(equisolid.GenerateRay(size*0.75, size/2).Direction, testForward)
+	if math.Abs(a1-45) > 1e-6 || a2 >= a1 {
+		t.Errorf("unexpected angles %g and %g at half of the radius", a1, a2)
+	}
+}
+
+// TestEquirectangular checks the directions at the edges of a panorama.
+func TestEquirectangular(t *testing.T) {
+	const width, height = 200, 100
+	cam := newTestCamera(t, ProjectionEquirectangular, 0, width, height)
+
+	tests := []struct {
+		x, y float64
+		want geometry.Vector
+	}{
+		{0, height / 2, geometry.NewVector(0, 0, -1)},
+		{width, height / 2, geometry.NewVector(0, 0, -1)},
+		{width / 2, 0, geometry.NewVector(0, 1, 0)},
+		{width / 2, height, geometry.NewVector(0, -1, 0)},
+	}
+
+	for _, test := range tests {
+		ray := cam.GenerateRay(test.x, test.y)
+		if angle := angleTo(ray.Direction, test.want); angle > 1e-6 {
+			t.Errorf("ray at (%g, %g) is %g degrees away from %s",
+				test.x, test.y, angle, test.want)
+		}
+	}
+
+	left := cam.GenerateRay(width/4, height/2).Direction
+	right := cam.GenerateRay(width*3/4, height/2).Direction
+	if angle := angleTo(left, right.Neg()); angle > 1e-6 {
+		t.Errorf("left and right rays are not opposite: %s and %s", left, right)
+	}
+}
+
+// TestMovement checks that all cameras move with the movement methods.
+func TestMovement(t *testing.T) {
+	for _, p := range Projections {
+		cam := newTestCamera(t, p, 0, 100, 100)
+		if err := cam.Forward(2); err != nil {
+			t.Fatal(err)
+		}
+
+		want := testPosition.Plus(geometry.NewVector(0, 0, 2))
+		if ray := cam.GenerateRay(50, 50); !ray.Origin.Equals(want) {
+			t.Errorf("%s: camera is at %s after moving forward instead of %s",
+				p, ray.Origin, want)
+		}
+	}
+}
+
+func newTestCamera(t *testing.T, p Projection, fov, width, height float64) Camera {
+	t.Helper()
+
+	cam, err := New(Options{
+		Projection: p,
+		Position:   testPosition,
+		LookAt:     testLookAt,
+		Up:         testUp,
+		FOV:        fov,
+		Width:      width,
+		Height:     height,
+	})
+	if err != nil {
+		t.Fatalf("creating %s camera: %s", p, err)
+	}
+	return cam
+}
+
+// angleTo returns the angle in degrees between `a` and `b`.
+func angleTo(a, b geometry.Vector) float64 {
+	cos := a.Normalize().Dot(b.Normalize())
+	return math.Acos(math.Max(-1, math.Min(1, cos))) * 180 / math.Pi
+}
@@ -0,0 +1,151 @@
+package camera
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ironsmile/raytracer/geometry"
+)
+
+// State is the projection, position and orientation of a camera without the size
+// of its image. It is returned by [Camera.State] and may be stored as text with
+// [State.MarshalText] in order to create the same camera later, for example in a
+// file render of a viewpoint found in the interactive window.
+//
+// The text is a single line of space separated key=value pairs:
+//
+//	projection=pinhole position=0,0,-5 lookAt=0,0,1 up=0,1,0 fov=90
+//
+// The fov key is present only for cameras with a field of view and size only for
+// orthographic ones.
+type State struct {
+	Projection Projection
+
+	Position geometry.Vector
+	LookAt   geometry.Vector
+	Up       geometry.Vector
+
+	// FOV is the field of view in degrees. See [Options].
+	FOV float64
+
+	// Size is the size of the area seen by orthographic cameras. See [Options].
+	Size float64
+}
+
+// Options returns the options for creating a camera with the state for an image
+// with the given size.
+func (s State) Options(width, height float64) Options {
+	return Options{
+		Projection: s.Projection,
+		Position:   s.Position,
+		LookAt:     s.LookAt,
+		Up:         s.Up,
+		FOV:        s.FOV,
+		Size:       s.Size,
+		Width:      width,
+		Height:     height,
+	}
+}
+
+// String implements fmt.Stringer. It is the same as the text returned by
+// [State.MarshalText].
+func (s State) String() string {
+	fields := []string{
+		"projection=" + s.Projection.String(),
+		"position=" + formatVector(s.Position),
+		"lookAt=" + formatVector(s.LookAt),
+		"up=" + formatVector(s.Up),
+	}
+	if s.FOV != 0 {
+		fields = append(fields, "fov="+formatFloat(s.FOV))
+	}
+	if s.Size != 0 {
+		fields = append(fields, "size="+formatFloat(s.Size))
+	}
+
+	return strings.Join(fields, " ")
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. The position and lookAt
+// keys are required. The up direction is along the Y axis when it is missing and
+// the rest of the keys are zero.
+func (s *State) UnmarshalText(text []byte) error {
+	state := State{Up: geometry.NewVector(0, 1, 0)}
+	seen := make(map[string]bool)
+
+	for _, field := range strings.Fields(string(text)) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return fmt.Errorf("camera state field %q is not key=value", field)
+		}
+		if seen[key] {
+			return fmt.Errorf("camera state has %s more than once", key)
+		}
+		seen[key] = true
+
+		var err error
+		switch key {
+		case "projection":
+			state.Projection, err = ParseProjection(value)
+		case "position":
+			state.Position, err = parseVector(value)
+		case "lookAt":
+			state.LookAt, err = parseVector(value)
+		case "up":
+			state.Up, err = parseVector(value)
+		case "fov":
+			state.FOV, err = strconv.ParseFloat(value, 64)
+		case "size":
+			state.Size, err = strconv.ParseFloat(value, 64)
+		default:
+			err = fmt.Errorf("unknown key")
+		}
+		if err != nil {
+			return fmt.Errorf("camera state %s: %w", key, err)
+		}
+	}
+
+	for _, key := range []string{"position", "lookAt"} {
+		if !seen[key] {
+			return fmt.Errorf("camera state has no %s", key)
+		}
+	}
+
+	*s = state
+	return nil
+}
+
+// formatVector formats `v` as comma separated coordinates.
+func formatVector(v geometry.Vector) string {
+	return formatFloat(v.X) + "," + formatFloat(v.Y) + "," + formatFloat(v.Z)
+}
+
+// formatFloat formats `f` with as many digits as needed for parsing it back.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// parseVector parses comma separated coordinates.
+func parseVector(s string) (geometry.Vector, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return geometry.Vector{}, fmt.Errorf("%q must be x,y,z", s)
+	}
+
+	var coords [3]float64
+	for i, part := range parts {
+		c, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return geometry.Vector{}, err
+		}
+		coords[i] = c
+	}
+
+	return geometry.NewVector(coords[0], coords[1], coords[2]), nil
+}
@@ -0,0 +1,135 @@
+package camera
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ironsmile/raytracer/geometry"
+)
+
+// TestStateText checks that camera states survive being stored as text and that
+// cameras created from them see the same.
+func TestStateText(t *testing.T) {
+	for _, p := range Projections {
+		cam := newTestCamera(t, p, 0, 100, 50)
+		cam.Yaw(31)
+		cam.Pitch(-12)
+		cam.Roll(7)
+		cam.Left(0.3)
+
+		text, err := cam.State().MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var state State
+		if err := state.UnmarshalText(text); err != nil {
+			t.Fatalf("%s: parsing %q: %s", p, text, err)
+		}
+		if state != cam.State() {
+			t.Errorf("%s: parsed state %s instead of %s", p, state, cam.State())
+		}
+
+		restored, err := New(state.Options(100, 50))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, point := range [][2]float64{{50, 25}, {3, 7}, {90, 40}} {
+			want := cam.GenerateRay(point[0], point[1])
+			got := restored.GenerateRay(point[0], point[1])
+			if !got.Origin.Equals(want.Origin) || angleTo(got.Direction, want.Direction) > 1e-6 {
+				t.Errorf("%s: restored camera sees %v instead of %v at %v",
+					p, got, want, point)
+			}
+		}
+	}
+}
+
+// TestStateDefaults checks the defaults and errors of parsing states.
+func TestStateDefaults(t *testing.T) {
+	var state State
+	if err := state.UnmarshalText([]byte("position=1,2,3   lookAt=0,0,1e3")); err != nil {
+		t.Fatal(err)
+	}
+
+	want := State{
+		Projection: ProjectionPinhole,
+		Position:   geometry.NewVector(1, 2, 3),
+		LookAt:     geometry.NewVector(0, 0, 1000),
+		Up:         geometry.NewVector(0, 1, 0),
+	}
+	if state != want {
+		t.Errorf("parsed %s instead of %s", state, want)
+	}
+
+	for _, text := range []string{
+		"",
+		"position=1,2,3",
+		"position=1,2,3 lookAt=0,0,1 fov",
+		"position=1,2,3 lookAt=0,0,1 zoom=2",
+		"position=1,2 lookAt=0,0,1",
+		"position=1,2,3 lookAt=0,0,x",
+		"position=1,2,3 lookAt=0,0,1 projection=nope",
+		"position=1,2,3 lookAt=0,0,1 position=1,2,3",
+	} {
+		if err := state.UnmarshalText([]byte(text)); err == nil {
+			t.Errorf("expected an error for %q", text)
+		}
+	}
+
+	if state != want {
+		t.Errorf("failed parsing changed the state to %s", state)
+	}
+}
+
+// TestStereoState checks that stereo cameras return the state of the camera
+// between the eyes.
+func TestStereoState(t *testing.T) {
+	cam := newTestStereo(t, ProjectionPinhole, StereoOptions{IPD: 1})
+	if err := cam.SetFOV(50); err != nil {
+		t.Fatal(err)
+	}
+
+	state := cam.State()
+	if !state.Position.Equals(testPosition) || state.Projection != ProjectionPinhole ||
+		math.Abs(state.FOV-50) > 1e-9 {
+		t.Errorf("unexpected stereo camera state %s", state)
+	}
+}
+
+// TestSetState checks that cameras take the state of other cameras with the same
+// projection.
+func TestSetState(t *testing.T) {
+	for _, p := range Projections {
+		src := newTestCamera(t, p, 0, 100, 100)
+		src.Yaw(-20)
+		src.Roll(15)
+		src.Up(2)
+		src.Zoom(1.5)
+
+		dst, err := New(Options{
+			Projection: p,
+			Position:   geometry.NewVector(0, 0, 0),
+			LookAt:     geometry.NewVector(1, 0, 0),
+			Up:         testUp,
+			Width:      100,
+			Height:     100,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := dst.SetState(src.State()); err != nil {
+			t.Fatalf("%s: %s", p, err)
+		}
+		if got, want := dst.State(), src.State(); got != want {
+			t.Errorf("%s: camera has state %s instead of %s", p, got, want)
+		}
+
+		bad := src.State()
+		bad.LookAt = bad.Position
+		if err := dst.SetState(bad); err == nil {
+			t.Errorf("%s: expected an error for looking at the camera position", p)
+		}
+	}
+}
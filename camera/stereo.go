@@ -0,0 +1,237 @@
+package camera
+
+import (
+	"fmt"
+
+	"github.com/ironsmile/raytracer/geometry"
+)
+
+// StereoLayout is the way in which the images of the two eyes of a [StereoCamera]
+// are placed in one image.
+type StereoLayout int
+
+const (
+	// StereoSideBySide puts the left eye in the left half of the image and the
+	// right eye in the right half.
+	StereoSideBySide StereoLayout = iota
+
+	// StereoTopBottom puts the left eye in the top half of the image and the
+	// right eye in the bottom half.
+	StereoTopBottom
+)
+
+// StereoLayouts are all the supported stereo layouts.
+var StereoLayouts = []StereoLayout{
+	StereoSideBySide,
+	StereoTopBottom,
+}
+
+// String implements fmt.Stringer.
+func (l StereoLayout) String() string {
+	switch l {
+	case StereoSideBySide:
+		return "side-by-side"
+	case StereoTopBottom:
+		return "top-bottom"
+	default:
+		return fmt.Sprintf("StereoLayout(%d)", int(l))
+	}
+}
+
+// ParseStereoLayout returns the StereoLayout with the given name. The names are
+// the same as the ones returned by [StereoLayout.String].
+func ParseStereoLayout(name string) (StereoLayout, error) {
+	for _, l := range StereoLayouts {
+		if l.String() == name {
+			return l, nil
+		}
+	}
+	return StereoSideBySide, fmt.Errorf("unknown stereo layout %q", name)
+}
+
+// DefaultIPD is the interpupillary distance used by [NewStereo] when none is
+// given. It is the average distance between human eyes in metres.
+const DefaultIPD = 0.065
+
+// StereoOptions describe the eyes of a [StereoCamera].
+type StereoOptions struct {
+	Layout StereoLayout
+
+	// IPD is the interpupillary distance, the distance between the eyes, in
+	// world units. When zero [DefaultIPD] is used.
+	IPD float64
+
+	// Convergence is the distance at which the eyes converge. Objects at it
+	// appear at the depth of the screen. Zero means that they converge at
+	// infinity and the eyes look in parallel.
+	Convergence float64
+
+	// ODS turns on omni-directional stereo. Instead of being fixed, the eyes are
+	// on a circle with a diameter of IPD and are always perpendicular to the
+	// direction of the ray. It is meant for 360 degree panoramas with
+	// [ProjectionEquirectangular] which look right in every direction.
+	ODS bool
+}
+
+// StereoCamera renders the scene for the left and right eye in one image. Every
+// eye sees it through a camera with the same projection whose position is moved
+// half of the interpupillary distance to the side.
+type StereoCamera struct {
+	view
+
+	// mono generates the rays of both eyes in camera space.
+	mono Camera
+
+	layout      StereoLayout
+	ipd         float64
+	convergence float64
+	ods         bool
+
+	rasterW, rasterH float64
+}
+
+// NewStereo returns a stereo camera whose eyes use the camera described by `o`.
+// The Width and Height in `o` are of the whole image with both eyes.
+func NewStereo(o Options, s StereoOptions) (*StereoCamera, error) {
+	if s.IPD < 0 || s.Convergence < 0 {
+		return nil, fmt.Errorf("interpupillary distance and convergence must not be negative")
+	}
+
+	cam := &StereoCamera{
+		layout:      s.Layout,
+		ipd:         s.IPD,
+		convergence: s.Convergence,
+		ods:         s.ODS,
+		rasterW:     o.Width,
+		rasterH:     o.Height,
+	}
+	if cam.ipd == 0 {
+		cam.ipd = DefaultIPD
+	}
+
+	mono := o
+	switch s.Layout {
+	case StereoSideBySide:
+		mono.Width /= 2
+	case StereoTopBottom:
+		mono.Height /= 2
+	default:
+		return nil, fmt.Errorf("unknown stereo layout %s", s.Layout)
+	}
+
+	// The size of the orthographic camera depends on the distance to the LookAt
+	// point so it has to be computed before moving the eye to the origin.
+	mono.Size = o.orthographicSize()
+	mono.Position = geometry.NewVector(0, 0, 0)
+	mono.LookAt = geometry.NewVector(0, 0, 1)
+	mono.Up = geometry.NewVector(0, 1, 0)
+
+	var err error
+	cam.mono, err = New(mono)
+	if err != nil {
+		return nil, err
+	}
+
+	cam.init(o.Position, o.LookAt, o.Up)
+	return cam, nil
+}
+
+// GenerateRay creates a ray for one point of the image. Depending on the point
+// it is a ray of the left or the right eye.
+func (s *StereoCamera) GenerateRay(x, y float64) geometry.Ray {
+	// side is -1 for the left eye and 1 for the right one.
+	side := -1.0
+
+	switch s.layout {
+	case StereoSideBySide:
+		if half := s.rasterW / 2; x >= half {
+			x -= half
+			side = 1
+		}
+	case StereoTopBottom:
+		if half := s.rasterH / 2; y >= half {
+			y -= half
+			side = 1
+		}
+	}
+
+	ray := s.mono.GenerateRay(x, y)
+	if Blind(ray) {
+		return ray
+	}
+
+	return s.camToWorld.Ray(s.eyeRay(ray, side))
+}
+
+// SetFOV sets the field of view of both eyes.
+func (s *StereoCamera) SetFOV(fov float64) error {
+	return s.mono.SetFOV(fov)
+}
+
+// Zoom zooms both eyes.
+func (s *StereoCamera) Zoom(factor float64) error {
+	return s.mono.Zoom(factor)
+}
+
+// State returns the state of the camera between the eyes. It does not include
+// the stereo options.
+func (s *StereoCamera) State() State {
+	mono := s.mono.State()
+
+	state := s.state()
+	state.Projection = mono.Projection
+	state.FOV = mono.FOV
+	state.Size = mono.Size
+	return state
+}
+
+// SetState places the camera between the eyes at the state. Its field of view
+// and size are used for both eyes.
+func (s *StereoCamera) SetState(state State) error {
+	mono := s.mono.State()
+	mono.FOV = state.FOV
+	mono.Size = state.Size
+	if err := s.mono.SetState(mono); err != nil {
+		return err
+	}
+	return s.setState(state)
+}
+
+// eyeRay moves the camera space `ray` of the central camera to the eye on `side`.
+func (s *StereoCamera) eyeRay(ray geometry.Ray, side float64) geometry.Ray {
+	dir := ray.Direction
+
+	// The eye is moved to the right of the ray. For ODS this is perpendicular to
+	// its horizontal direction and otherwise it is the right of the camera.
+	right := geometry.NewVector(1, 0, 0)
+	if s.ods {
+		horizontal := geometry.NewVector(dir.X, 0, dir.Z)
+		if horizontal.Length() == 0 {
+			// Straight up and down both eyes are at the centre.
+			return ray
+		}
+		horizontal = horizontal.Normalize()
+		right = geometry.NewVector(horizontal.Z, 0, -horizontal.X)
+	}
+
+	eye := ray.Origin.Plus(right.MultiplyScalar(side * s.ipd / 2))
+	if s.convergence == 0 {
+		return geometry.NewRay(eye, dir)
+	}
+
+	// Both eyes look at the point which the central ray sees at the convergence
+	// distance. Without ODS it is on a plane so that the eyes have no vertical
+	// parallax.
+	var target geometry.Vector
+	switch {
+	case s.ods:
+		target = ray.Origin.Plus(dir.MultiplyScalar(s.convergence))
+	case dir.Z > 0:
+		target = ray.Origin.Plus(dir.MultiplyScalar(s.convergence / dir.Z))
+	default:
+		// Rays which do not cross the convergence plane stay parallel.
+		return geometry.NewRay(eye, dir)
+	}
+
+	return geometry.NewRay(eye, target.Minus(eye).Normalize())
+}
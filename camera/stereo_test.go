@@ -0,0 +1,127 @@
+package camera
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ironsmile/raytracer/geometry"
+)
+
+// TestParseStereoLayout checks that all layouts are parsed from their names.
+func TestParseStereoLayout(t *testing.T) {
+	for _, l := range StereoLayouts {
+		got, err := ParseStereoLayout(l.String())
+		if err != nil || got != l {
+			t.Errorf("parsing %s returned %s, %v", l, got, err)
+		}
+	}
+
+	if _, err := ParseStereoLayout("nope"); err == nil {
+		t.Errorf("expected an error for an unknown layout")
+	}
+}
+
+// TestStereoEyes checks that the eyes are in the right halves of the image and
+// are moved to the sides of the camera.
+func TestStereoEyes(t *testing.T) {
+	const ipd = 0.5
+	right := geometry.NewVector(1, 0, 0)
+
+	tests := []struct {
+		layout      StereoLayout
+		left, right [2]float64
+	}{
+		{StereoSideBySide, [2]float64{50, 50}, [2]float64{150, 50}},
+		{StereoTopBottom, [2]float64{100, 25}, [2]float64{100, 75}},
+	}
+
+	for _, test := range tests {
+		cam := newTestStereo(t, ProjectionPinhole, StereoOptions{
+			Layout: test.layout,
+			IPD:    ipd,
+		})
+
+		leftRay := cam.GenerateRay(test.left[0], test.left[1])
+		rightRay := cam.GenerateRay(test.right[0], test.right[1])
+
+		wantLeft := testPosition.Plus(right.MultiplyScalar(-ipd / 2))
+		wantRight := testPosition.Plus(right.MultiplyScalar(ipd / 2))
+		if !leftRay.Origin.Equals(wantLeft) || !rightRay.Origin.Equals(wantRight) {
+			t.Errorf("%s: eyes are at %s and %s instead of %s and %s", test.layout,
+				leftRay.Origin, rightRay.Origin, wantLeft, wantRight)
+		}
+
+		for _, ray := range []geometry.Ray{leftRay, rightRay} {
+			if angle := angleTo(ray.Direction, testForward); angle > 1e-9 {
+				t.Errorf("%s: parallel eyes look %g degrees off", test.layout, angle)
+			}
+		}
+	}
+}
+
+// TestStereoConvergence checks that the central rays of both eyes meet at the
+// convergence distance.
+func TestStereoConvergence(t *testing.T) {
+	const convergence = 4
+
+	for _, ods := range []bool{false, true} {
+		cam := newTestStereo(t, ProjectionEquirectangular, StereoOptions{
+			Layout:      StereoTopBottom,
+			Convergence: convergence,
+			ODS:         ods,
+		})
+
+		want := testPosition.Plus(testForward.MultiplyScalar(convergence))
+		for _, y := range []float64{25, 75} {
+			ray := cam.GenerateRay(100, y)
+			dist := want.Minus(ray.Origin).Length()
+			if got := ray.Origin.Plus(ray.Direction.MultiplyScalar(dist)); !got.Equals(want) {
+				t.Errorf("ODS %t: eye ray at y=%g goes through %s instead of %s",
+					ods, y, got, want)
+			}
+		}
+	}
+}
+
+// TestStereoODS checks that ODS eyes are perpendicular to the rays in every
+// direction.
+func TestStereoODS(t *testing.T) {
+	const ipd = 0.2
+
+	cam := newTestStereo(t, ProjectionEquirectangular, StereoOptions{
+		Layout: StereoTopBottom,
+		IPD:    ipd,
+		ODS:    true,
+	})
+
+	for _, x := range []float64{0, 30, 50, 100, 170} {
+		for _, y := range []float64{10, 25, 60, 75} {
+			ray := cam.GenerateRay(x, y)
+			offset := ray.Origin.Minus(testPosition)
+
+			if d := offset.Length(); math.Abs(d-ipd/2) > 1e-9 {
+				t.Errorf("eye at (%g, %g) is %g away from the centre", x, y, d)
+			}
+			if dot := offset.Dot(ray.Direction); math.Abs(dot) > 1e-9 {
+				t.Errorf("eye at (%g, %g) is not perpendicular to its ray", x, y)
+			}
+		}
+	}
+}
+
+func newTestStereo(t *testing.T, p Projection, s StereoOptions) Camera {
+	t.Helper()
+
+	cam, err := NewStereo(Options{
+		Projection: p,
+		Position:   testPosition,
+		LookAt:     testLookAt,
+		Up:         testUp,
+		Width:      200,
+		Height:     100,
+	}, s)
+	if err != nil {
+		t.Fatalf("creating stereo %s camera: %s", p, err)
+	}
+	return cam
+}
@@ -0,0 +1,202 @@
+package camera
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ironsmile/raytracer/geometry"
+	"github.com/ironsmile/raytracer/transform"
+)
+
+// view is the position and orientation of a camera. It implements the movement
+// methods of [Camera] and is embedded in all camera types.
+type view struct {
+	camToWorld *transform.Transform
+
+	origin geometry.Vector
+	lookAt geometry.Vector
+	up     geometry.Vector
+
+	sync.RWMutex
+}
+
+// init places the view at `position`, looking at `lookAt`.
+func (v *view) init(position, lookAt, up geometry.Vector) {
+	v.origin = position
+	v.lookAt = lookAt
+	v.up = up
+	v.computeMatrix()
+}
+
+// Forward moves the camera in its lookAt direction
+func (v *view) Forward(speed float64) error {
+	v.Lock()
+	defer v.Unlock()
+
+	dir := v.lookAt.Minus(v.origin).Normalize().MultiplyScalar(speed)
+	v.move(dir)
+	return nil
+}
+
+// Backward moves the camera opposite its lookAt direction
+func (v *view) Backward(speed float64) error {
+	v.Lock()
+	defer v.Unlock()
+
+	dir := v.lookAt.Minus(v.origin).Normalize().MultiplyScalar(speed).Neg()
+	v.move(dir)
+	return nil
+}
+
+// Left moves the camera to the left relative to its lookAt and up directions
+func (v *view) Left(speed float64) error {
+	v.Lock()
+	defer v.Unlock()
+
+	dir := v.lookAt.Minus(v.origin).Normalize()
+	dir = v.up.Cross(dir).MultiplyScalar(speed).Neg()
+	v.move(dir)
+	return nil
+}
+
+// Up moves the camera straight up regardless of its orientation.
+func (v *view) Up(speed float64) error {
+	v.Lock()
+	defer v.Unlock()
+
+	v.move(geometry.NewVector(0, 1, 0).MultiplyScalar(speed))
+	return nil
+}
+
+// Down moves the camera straight down regardless of its orientation.
+func (v *view) Down(speed float64) error {
+	v.Lock()
+	defer v.Unlock()
+
+	v.move(geometry.NewVector(0, -1, 0).MultiplyScalar(speed))
+	return nil
+}
+
+// Right moves the camera to the right relative to its lookAt and up directions
+func (v *view) Right(speed float64) error {
+	v.Lock()
+	defer v.Unlock()
+
+	dir := v.lookAt.Minus(v.origin).Normalize()
+	dir = v.up.Cross(dir).MultiplyScalar(speed)
+	v.move(dir)
+	return nil
+}
+
+func (v *view) move(dir geometry.Vector) {
+	v.origin = v.origin.Plus(dir)
+	v.lookAt = v.lookAt.Plus(dir)
+	v.computeMatrix()
+}
+
+func (v *view) computeMatrix() {
+	v.camToWorld = transform.LookAt(v.origin, v.lookAt, v.up).Inverse()
+}
+
+// Yaw turns the camera around its up direction. Positive angles in degrees turn
+// it to the right.
+func (v *view) Yaw(angle float64) error {
+	v.Lock()
+	defer v.Unlock()
+
+	v.rotate(angle, v.up)
+	return nil
+}
+
+// Pitch turns the camera around its right direction. Positive angles in degrees
+// turn it down.
+func (v *view) Pitch(angle float64) error {
+	v.Lock()
+	defer v.Unlock()
+
+	v.rotate(angle, v.camToWorld.Vector(geometry.NewVector(1, 0, 0)))
+	return nil
+}
+
+// Roll turns the camera around its viewing direction. Positive angles in degrees
+// lean its top to the left.
+func (v *view) Roll(angle float64) error {
+	v.Lock()
+	defer v.Unlock()
+
+	dir := v.lookAt.Minus(v.origin).Normalize()
+	v.up = transform.Rotate(angle, dir).Vector(v.up).Normalize()
+	v.computeMatrix()
+	return nil
+}
+
+// rotate turns the lookAt point around `axis` which goes through the camera
+// origin.
+func (v *view) rotate(angle float64, axis geometry.Vector) {
+	dir := v.lookAt.Minus(v.origin)
+	v.lookAt = v.origin.Plus(transform.Rotate(angle, axis.Normalize()).Vector(dir))
+	v.computeMatrix()
+}
+
+// SetPosition moves the camera to `position` without changing the direction in
+// which it looks.
+func (v *view) SetPosition(position geometry.Vector) error {
+	v.Lock()
+	defer v.Unlock()
+
+	v.move(position.Minus(v.origin))
+	return nil
+}
+
+// LookAt turns the camera toward `point`.
+func (v *view) LookAt(point geometry.Vector) error {
+	v.Lock()
+	defer v.Unlock()
+
+	if err := checkView(v.origin, point, v.up); err != nil {
+		return err
+	}
+
+	v.lookAt = point
+	v.computeMatrix()
+	return nil
+}
+
+// setState places the view at the position, lookAt point and up direction of
+// `s`.
+func (v *view) setState(s State) error {
+	if err := checkView(s.Position, s.LookAt, s.Up); err != nil {
+		return err
+	}
+
+	v.Lock()
+	defer v.Unlock()
+
+	v.init(s.Position, s.LookAt, s.Up)
+	return nil
+}
+
+// checkView returns an error when a camera at `position` cannot look at `lookAt`
+// with the `up` direction.
+func checkView(position, lookAt, up geometry.Vector) error {
+	dir := lookAt.Minus(position)
+	if dir.Length() == 0 {
+		return fmt.Errorf("cannot look at the position of the camera")
+	}
+	if up.Length() == 0 || dir.Normalize().Cross(up.Normalize()).Length() < 1e-9 {
+		return fmt.Errorf("cannot look along the up direction of the camera")
+	}
+	return nil
+}
+
+// state returns the position, lookAt point and up direction of the view.
+func (v *view) state() State {
+	v.RLock()
+	defer v.RUnlock()
+
+	return State{
+		Position: v.origin,
+		LookAt:   v.lookAt,
+		Up:       v.up,
+	}
+}
@@ -0,0 +1,166 @@
+package camera
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ironsmile/raytracer/geometry"
+)
+
+// TestRotation checks the directions in which the cameras turn.
+func TestRotation(t *testing.T) {
+	tests := []struct {
+		name   string
+		rotate func(Camera) error
+		dir    geometry.Vector
+		up     geometry.Vector
+	}{
+		{
+			name:   "yaw",
+			rotate: func(c Camera) error { return c.Yaw(90) },
+			dir:    geometry.NewVector(1, 0, 0),
+			up:     testUp,
+		},
+		{
+			name:   "pitch",
+			rotate: func(c Camera) error { return c.Pitch(30) },
+			dir:    geometry.NewVector(0, -0.5, math.Sqrt(3)/2),
+			up:     testUp,
+		},
+		{
+			name:   "roll",
+			rotate: func(c Camera) error { return c.Roll(90) },
+			dir:    testForward,
+			up:     geometry.NewVector(-1, 0, 0),
+		},
+	}
+
+	for _, test := range tests {
+		for _, p := range Projections {
+			cam := newTestCamera(t, p, 0, 100, 100)
+			if err := test.rotate(cam); err != nil {
+				t.Fatal(err)
+			}
+
+			state := cam.State()
+			dir := state.LookAt.Minus(state.Position)
+			if angle := angleTo(dir, test.dir); angle > 1e-6 {
+				t.Errorf("%s %s: camera looks %g degrees off", p, test.name, angle)
+			}
+			if angle := angleTo(state.Up, test.up); angle > 1e-6 {
+				t.Errorf("%s %s: up is %g degrees off", p, test.name, angle)
+			}
+			if !state.Position.Equals(testPosition) {
+				t.Errorf("%s %s: camera moved to %s", p, test.name, state.Position)
+			}
+		}
+	}
+}
+
+// TestRollImage checks that a rolled camera sees the image turned.
+func TestRollImage(t *testing.T) {
+	cam := newTestCamera(t, ProjectionPinhole, 0, 100, 100)
+	if err := cam.Roll(90); err != nil {
+		t.Fatal(err)
+	}
+
+	// The top of the image is on the left of the camera.
+	top := cam.GenerateRay(50, 0).Direction
+	want := geometry.NewVector(-1, 0, 1)
+	if angle := angleTo(top, want); angle > 1e-6 {
+		t.Errorf("top of the rolled image is %g degrees away from %s", angle, want)
+	}
+}
+
+// TestSetPositionAndLookAt checks the absolute placement of the cameras.
+func TestSetPositionAndLookAt(t *testing.T) {
+	for _, p := range Projections {
+		cam := newTestCamera(t, p, 0, 100, 100)
+
+		pos := geometry.NewVector(-4, 2, 0)
+		if err := cam.SetPosition(pos); err != nil {
+			t.Fatal(err)
+		}
+		if ray := cam.GenerateRay(50, 50); !ray.Origin.Equals(pos) ||
+			angleTo(ray.Direction, testForward) > 1e-9 {
+			t.Errorf("%s: camera is at %s looking at %s after SetPosition",
+				p, ray.Origin, ray.Direction)
+		}
+
+		target := geometry.NewVector(0, 2, 0)
+		if err := cam.LookAt(target); err != nil {
+			t.Fatal(err)
+		}
+		want := geometry.NewVector(1, 0, 0)
+		if ray := cam.GenerateRay(50, 50); angleTo(ray.Direction, want) > 1e-9 {
+			t.Errorf("%s: camera looks at %s instead of %s", p, ray.Direction, want)
+		}
+
+		if err := cam.LookAt(pos); err == nil {
+			t.Errorf("%s: expected an error for looking at the camera position", p)
+		}
+		if err := cam.LookAt(pos.Plus(testUp)); err == nil {
+			t.Errorf("%s: expected an error for looking along the up direction", p)
+		}
+	}
+}
+
+// TestFOVAndZoom checks that the field of view and zoom change the visible area.
+func TestFOVAndZoom(t *testing.T) {
+	const size = 100
+
+	// edgeAngle returns the angle between the centre and the edge of the image.
+	edgeAngle := func(cam Camera) float64 {
+		return angleTo(cam.GenerateRay(size, size/2).Direction, testForward)
+	}
+
+	for _, p := range []Projection{
+		ProjectionPinhole,
+		ProjectionFisheyeEquidistant,
+		ProjectionFisheyeEquisolid,
+	} {
+		cam := newTestCamera(t, p, 0, size, size)
+
+		if err := cam.SetFOV(60); err != nil {
+			t.Fatal(err)
+		}
+		if angle := edgeAngle(cam); math.Abs(angle-30) > 1e-6 {
+			t.Errorf("%s: edge is at %g degrees with 60 degrees FOV", p, angle)
+		}
+		if fov := cam.State().FOV; math.Abs(fov-60) > 1e-9 {
+			t.Errorf("%s: state has FOV %g instead of 60", p, fov)
+		}
+
+		before := edgeAngle(cam)
+		if err := cam.Zoom(2); err != nil {
+			t.Fatal(err)
+		}
+		if after := edgeAngle(cam); after >= before {
+			t.Errorf("%s: zooming in widened the view from %g to %g", p, before, after)
+		}
+
+		if err := cam.SetFOV(-1); err == nil {
+			t.Errorf("%s: expected an error for a negative FOV", p)
+		}
+		if err := cam.Zoom(0); err == nil {
+			t.Errorf("%s: expected an error for zero zoom", p)
+		}
+	}
+
+	ortho := newTestCamera(t, ProjectionOrthographic, 0, size, size)
+	ortho.(*OrthographicCamera).halfSize = 2
+	if err := ortho.Zoom(4); err != nil {
+		t.Fatal(err)
+	}
+	if got := ortho.State().Size; math.Abs(got-1) > 1e-9 {
+		t.Errorf("orthographic size is %g after zooming instead of 1", got)
+	}
+	if err := ortho.SetFOV(60); err == nil {
+		t.Errorf("expected an error for setting the FOV of an orthographic camera")
+	}
+
+	pano := newTestCamera(t, ProjectionEquirectangular, 0, size, size)
+	if pano.Zoom(2) == nil || pano.SetFOV(60) == nil {
+		t.Errorf("expected errors for zooming an equirectangular camera")
+	}
+}
@@ -0,0 +1,210 @@
+package distributed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ironsmile/raytracer/utils"
+)
+
+// DefaultTileSize is the side in pixels of the tiles sent to workers when no other
+// is configured.
+const DefaultTileSize = 128
+
+// Coordinator renders images by sending their tiles to workers. Every worker gets
+// one tile at a time. When a request to a worker fails the worker is considered
+// dead and its tile is given to one of the rest.
+type Coordinator struct {
+	// Workers are the base URLs of the workers, for example
+	// "http://10.0.0.2:6465".
+	Workers []string
+
+	// TileSize is the side of the tiles in pixels. [DefaultTileSize] is used
+	// when it is zero.
+	TileSize int
+
+	// TileTimeout is the time after which a worker which has not returned its
+	// tile is considered dead. Zero means no timeout.
+	TileTimeout time.Duration
+
+	// Client is used for the requests to the workers. http.DefaultClient is used
+	// when it is nil.
+	Client *http.Client
+
+	// Logger receives the progress of the rendering and the dead workers. Nothing
+	// is logged when it is nil.
+	Logger *slog.Logger
+}
+
+// Render renders `job` on the workers and returns the image. It fails when all
+// workers die before all tiles are rendered.
+func (c *Coordinator) Render(ctx context.Context, job Job) (*image.NRGBA, error) {
+	if err := job.validate(); err != nil {
+		return nil, err
+	}
+	if len(c.Workers) == 0 {
+		return nil, fmt.Errorf("there are no workers")
+	}
+
+	tileSize := c.TileSize
+	if tileSize <= 0 {
+		tileSize = DefaultTileSize
+	}
+
+	logger := utils.Logger(c.Logger)
+
+	todo := tiles(job.Width, job.Height, tileSize)
+	total := len(todo)
+
+	// The queue is big enough for all tiles so returning the tiles of dead
+	// workers never blocks.
+	queue := make(chan image.Rectangle, total)
+	for _, tile := range todo {
+		queue <- tile
+	}
+
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		lock  sync.Mutex
+		done  int
+		alive = len(c.Workers)
+		img   = image.NewNRGBA(image.Rect(0, 0, job.Width, job.Height))
+		wg    sync.WaitGroup
+	)
+
+	for _, worker := range c.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				var tile image.Rectangle
+				select {
+				case tile = <-queue:
+				case <-ctx.Done():
+					return
+				}
+
+				pixels, err := c.renderTile(ctx, worker, job, tile)
+				if err != nil {
+					queue <- tile
+					if ctx.Err() != nil {
+						return
+					}
+
+					lock.Lock()
+					alive--
+					if alive == 0 {
+						cancel()
+					}
+					lock.Unlock()
+
+					logger.Warn("worker is dead", "worker", worker, "error", err)
+					return
+				}
+
+				pixels.draw(img)
+
+				lock.Lock()
+				done++
+				logger.Info("tile rendered",
+					"tile", tile,
+					"worker", worker,
+					"done", done,
+					"total", total,
+				)
+				if done == total {
+					cancel()
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if err := parent.Err(); err != nil {
+		return nil, err
+	}
+	if done < total {
+		return nil, fmt.Errorf("all workers died with %d of %d tiles rendered", done, total)
+	}
+
+	return img, nil
+}
+
+// renderTile sends `tile` to `worker` and returns its pixels.
+func (c *Coordinator) renderTile(
+	ctx context.Context,
+	worker string,
+	job Job,
+	tile image.Rectangle,
+) (*tilePixels, error) {
+	body, err := json.Marshal(tileRequest{Job: job, Tile: tile})
+	if err != nil {
+		return nil, err
+	}
+
+	if c.TileTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.TileTimeout)
+		defer cancel()
+	}
+
+	url := strings.TrimSuffix(worker, "/") + "/tile"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tile %s: %s: %s", tile, resp.Status, bytes.TrimSpace(data))
+	}
+
+	return decodeTile(tile, data)
+}
+
+// draw writes the pixels of the tile in `img` the same way [imagefilm.Image] does.
+func (t *tilePixels) draw(img *image.NRGBA) {
+	for ind, n := range t.count {
+		if n == 0 {
+			continue
+		}
+
+		img.SetNRGBA(t.rect.Min.X+ind%t.rect.Dx(), t.rect.Min.Y+ind/t.rect.Dx(), color.NRGBA{
+			R: uint8(min(t.mean[ind*3], 1) * 255),
+			G: uint8(min(t.mean[ind*3+1], 1) * 255),
+			B: uint8(min(t.mean[ind*3+2], 1) * 255),
+			A: 255,
+		})
+	}
+}
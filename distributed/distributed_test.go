@@ -0,0 +1,118 @@
+package distributed
+
+import (
+	"context"
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ironsmile/raytracer/engine"
+	"github.com/ironsmile/raytracer/imagefilm"
+	"github.com/ironsmile/raytracer/sampler"
+	"github.com/ironsmile/raytracer/scene"
+)
+
+// TestCoordinatorRender checks that an image rendered on several workers, one of
+// which is dead, is the same as the one rendered locally.
+func TestCoordinatorRender(t *testing.T) {
+	job := Job{
+		Scene:          "teapot",
+		Width:          70,
+		Height:         50,
+		SPP:            4,
+		Sampler:        sampler.KindSobol,
+		SamplesPerPass: 2,
+		Seed:           11,
+	}
+
+	var workers []string
+	for range 2 {
+		ts := httptest.NewServer(&Worker{})
+		defer ts.Close()
+		workers = append(workers, ts.URL)
+	}
+
+	dead := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "out of memory", http.StatusInternalServerError)
+	}))
+	defer dead.Close()
+	workers = append(workers, dead.URL)
+
+	c := &Coordinator{Workers: workers, TileSize: 16}
+	got, err := c.Render(context.Background(), job)
+	if err != nil {
+		t.Fatalf("rendering: %s", err)
+	}
+
+	want := renderLocally(t, job)
+	for y := range job.Height {
+		for x := range job.Width {
+			g, w := got.NRGBAAt(x, y), want.NRGBAAt(x, y)
+			if absDiff(g.R, w.R) > 1 || absDiff(g.G, w.G) > 1 || absDiff(g.B, w.B) > 1 || g.A != w.A {
+				t.Fatalf("pixel (%d, %d) is %v instead of %v", x, y, g, w)
+			}
+		}
+	}
+}
+
+// TestCoordinatorAllWorkersDead checks that rendering fails when there are no
+// workers left.
+func TestCoordinatorAllWorkersDead(t *testing.T) {
+	ts := httptest.NewServer(&Worker{})
+	ts.Close()
+
+	c := &Coordinator{Workers: []string{ts.URL}}
+	job := Job{Scene: "empty", Width: 8, Height: 8, SPP: 1}
+	if _, err := c.Render(context.Background(), job); err == nil {
+		t.Errorf("expected an error when all workers are dead")
+	}
+}
+
+// TestTiles checks that the tiles cover every pixel exactly once.
+func TestTiles(t *testing.T) {
+	const width, height = 100, 37
+
+	covered := make([]int, width*height)
+	for _, tile := range tiles(width, height, 32) {
+		for y := tile.Min.Y; y < tile.Max.Y; y++ {
+			for x := tile.Min.X; x < tile.Max.X; x++ {
+				covered[y*width+x]++
+			}
+		}
+	}
+
+	for ind, n := range covered {
+		if n != 1 {
+			t.Fatalf("pixel (%d, %d) is in %d tiles", ind%width, ind/width, n)
+		}
+	}
+}
+
+// renderLocally renders `job` with a single engine.
+func renderLocally(t *testing.T, job Job) *image.NRGBA {
+	output := imagefilm.NewImage("")
+	if err := output.Init(job.Width, job.Height); err != nil {
+		t.Fatal(err)
+	}
+
+	smpl := sampler.NewSimple(job.Width, job.Height, output, sampler.Config{
+		Kind:            job.Sampler,
+		SamplesPerPixel: job.SamplesPerPass,
+		Seed:            job.Seed,
+	})
+	defer smpl.Stop()
+
+	tracer := engine.New(smpl)
+	tracer.Scene.InitScene(job.Scene)
+	tracer.SetTarget(output, scene.GetCamera(float64(job.Width), float64(job.Height)))
+
+	if _, err := tracer.RenderProgressive(engine.Progressive{TargetSPP: job.SPP}); err != nil {
+		t.Fatal(err)
+	}
+	return output.Image()
+}
+
+func absDiff(a, b uint8) int {
+	return max(int(a), int(b)) - min(int(a), int(b))
+}
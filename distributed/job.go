@@ -0,0 +1,129 @@
+// Package distributed renders images on many machines. A [Coordinator] splits the
+// image in tiles and sends them to [Worker] processes over HTTP. Every worker
+// renders only the pixels of its tiles and returns their colours as floats. The
+// coordinator puts them together in the final image.
+//
+// Samples depend only on the seed and the pixel so an image rendered this way is
+// the same as one rendered on a single machine.
+package distributed
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"math"
+	"slices"
+
+	"github.com/ironsmile/raytracer/sampler"
+	"github.com/ironsmile/raytracer/scene"
+)
+
+// Job describes the image which is rendered.
+type Job struct {
+	// Scene is the name of one of the demo scenes. See [scene.PossibleScenes].
+	Scene string `json:"scene"`
+
+	// Animation is the name of an animation file in the animations directory of
+	// the workers. Frame is the rendered frame of the animation.
+	Animation string  `json:"animation,omitempty"`
+	Frame     float64 `json:"frame,omitempty"`
+
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// SPP is the number of samples per pixel. It is rounded up to a whole
+	// number of passes as in [engine.Progressive].
+	SPP int `json:"spp"`
+
+	// Sampler is used for taking the samples with SamplesPerPass samples for
+	// every pixel in a pass.
+	Sampler        sampler.Kind `json:"sampler"`
+	SamplesPerPass int          `json:"samplesPerPass,omitempty"`
+	Seed           uint64       `json:"seed"`
+}
+
+// validate checks that the job can be rendered.
+func (j *Job) validate() error {
+	if !slices.Contains(scene.PossibleScenes, j.Scene) {
+		return fmt.Errorf("unknown scene %q", j.Scene)
+	}
+	if j.Width <= 0 || j.Height <= 0 {
+		return fmt.Errorf("image size %dx%d is not positive", j.Width, j.Height)
+	}
+	if j.SPP <= 0 {
+		return fmt.Errorf("samples per pixel must be positive")
+	}
+	return nil
+}
+
+// tileRequest is the body of the requests for rendering a tile.
+type tileRequest struct {
+	Job  Job             `json:"job"`
+	Tile image.Rectangle `json:"tile"`
+}
+
+// pixelSize is the size in bytes of a single encoded pixel: red, green and blue as
+// 32 bit floats followed by the number of samples as a 32 bit unsigned integer.
+const pixelSize = 16
+
+// tilePixels holds the mean colour and the number of samples of every pixel in a
+// tile, row by row.
+type tilePixels struct {
+	rect  image.Rectangle
+	mean  []float64
+	count []uint32
+}
+
+func newTilePixels(rect image.Rectangle) *tilePixels {
+	n := rect.Dx() * rect.Dy()
+	return &tilePixels{
+		rect:  rect,
+		mean:  make([]float64, 3*n),
+		count: make([]uint32, n),
+	}
+}
+
+// encode returns the pixels in the format sent by the workers. All values are
+// little endian.
+func (t *tilePixels) encode() []byte {
+	data := make([]byte, 0, len(t.count)*pixelSize)
+	for ind, n := range t.count {
+		for _, v := range t.mean[ind*3 : ind*3+3] {
+			data = binary.LittleEndian.AppendUint32(data, math.Float32bits(float32(v)))
+		}
+		data = binary.LittleEndian.AppendUint32(data, n)
+	}
+	return data
+}
+
+// decodeTile decodes the pixels of the tile `rect` encoded with
+// [tilePixels.encode].
+func decodeTile(rect image.Rectangle, data []byte) (*tilePixels, error) {
+	t := newTilePixels(rect)
+	if len(data) != len(t.count)*pixelSize {
+		return nil, fmt.Errorf("tile %s has %d bytes instead of %d",
+			rect, len(data), len(t.count)*pixelSize)
+	}
+
+	le := binary.LittleEndian
+	for ind := range t.count {
+		pixel := data[ind*pixelSize:]
+		for ch := range 3 {
+			t.mean[ind*3+ch] = float64(math.Float32frombits(le.Uint32(pixel[ch*4:])))
+		}
+		t.count[ind] = le.Uint32(pixel[12:])
+	}
+	return t, nil
+}
+
+// tiles splits an image with the given size in square tiles with side `size`.
+// The tiles at the right and bottom edges may be smaller.
+func tiles(width, height, size int) []image.Rectangle {
+	var res []image.Rectangle
+	for y := 0; y < height; y += size {
+		for x := 0; x < width; x += size {
+			res = append(res, image.Rect(x, y, min(x+size, width), min(y+size, height)))
+		}
+	}
+	return res
+}
@@ -0,0 +1,192 @@
+package distributed
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/ironsmile/raytracer/engine"
+	"github.com/ironsmile/raytracer/sampler"
+	"github.com/ironsmile/raytracer/scene"
+)
+
+// Worker is an http.Handler which renders tiles for a [Coordinator]. It serves a
+// single endpoint, POST /tile. Its request body is a JSON object with the job and
+// the tile as "job" and "tile". The response is the rendered pixels of the tile.
+//
+// The scene of the last job is kept loaded so that further tiles of the same job
+// are rendered right away.
+type Worker struct {
+	// AnimationsDir is the directory with the animation files which jobs may use.
+	// Jobs cannot use animations when it is empty.
+	AnimationsDir string
+
+	// Logger receives the diagnostics of loading scenes and rendering. Nothing is
+	// logged when it is nil.
+	Logger *slog.Logger
+
+	mux  *http.ServeMux
+	once sync.Once
+
+	// lock guards the scene cache.
+	lock      sync.Mutex
+	sceneJob  Job
+	sceneAnim *scene.Animation
+	scene     *scene.Scene
+}
+
+// ServeHTTP implements http.Handler.
+func (w *Worker) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	w.once.Do(func() {
+		w.mux = http.NewServeMux()
+		w.mux.HandleFunc("POST /tile", w.renderTile)
+	})
+	w.mux.ServeHTTP(rw, r)
+}
+
+func (w *Worker) renderTile(rw http.ResponseWriter, r *http.Request) {
+	var req tileRequest
+	if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, 1<<20)).Decode(&req); err != nil {
+		http.Error(rw, fmt.Sprintf("decoding tile request: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := req.Job.validate(); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Tile.Empty() || !req.Tile.In(image.Rect(0, 0, req.Job.Width, req.Job.Height)) {
+		http.Error(rw, fmt.Sprintf("tile %s is not in the image", req.Tile), http.StatusBadRequest)
+		return
+	}
+
+	scn, anim, err := w.loadScene(req.Job)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pixels, err := renderTile(req.Job, req.Tile, scn, anim, w.Logger)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := pixels.encode()
+	rw.Header().Set("Content-Type", "application/octet-stream")
+	rw.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	rw.Write(data)
+}
+
+// loadScene returns the scene and the animation of `job`. The scene is moved to
+// the frame of the job. Scenes are shared between all tiles of a job.
+func (w *Worker) loadScene(job Job) (*scene.Scene, *scene.Animation, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.scene != nil && w.sceneJob.Scene == job.Scene &&
+		w.sceneJob.Animation == job.Animation && w.sceneJob.Frame == job.Frame {
+		return w.scene, w.sceneAnim, nil
+	}
+
+	anim := &scene.Animation{}
+	if job.Animation != "" {
+		if w.AnimationsDir == "" || !filepath.IsLocal(job.Animation) {
+			return nil, nil, fmt.Errorf("animation %q is not available", job.Animation)
+		}
+
+		var err error
+		anim, err = scene.LoadAnimation(filepath.Join(w.AnimationsDir, job.Animation))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	scn := scene.NewScene()
+	scn.Logger = w.Logger
+	scn.InitScene(job.Scene)
+	if err := scn.Animate(anim, job.Frame); err != nil {
+		return nil, nil, err
+	}
+
+	if w.scene != nil {
+		w.scene.Release()
+	}
+	w.scene, w.sceneAnim, w.sceneJob = scn, anim, job
+	return scn, anim, nil
+}
+
+// renderTile renders the pixels of `tile` for `job`.
+func renderTile(
+	job Job,
+	tile image.Rectangle,
+	scn *scene.Scene,
+	anim *scene.Animation,
+	logger *slog.Logger,
+) (*tilePixels, error) {
+	out := &tileFilm{
+		width:  job.Width,
+		height: job.Height,
+		pixels: newTilePixels(tile),
+	}
+
+	smpl := sampler.NewSimple(job.Width, job.Height, out, sampler.Config{
+		Kind:            job.Sampler,
+		SamplesPerPixel: job.SamplesPerPass,
+		Seed:            job.Seed,
+		Crop:            tile,
+	})
+	defer smpl.Stop()
+
+	tracer := engine.New(smpl)
+	tracer.SetLogger(logger)
+	tracer.Scene = scn
+	cam, err := anim.CameraAt(job.Frame, float64(job.Width), float64(job.Height))
+	if err != nil {
+		return nil, err
+	}
+	tracer.SetTarget(out, cam)
+
+	if _, err := tracer.RenderProgressive(engine.Progressive{TargetSPP: job.SPP}); err != nil {
+		return nil, err
+	}
+
+	return out.pixels, nil
+}
+
+// tileFilm is a destination which accumulates the samples only for the pixels
+// of a single tile. The mean is computed the same way as in [imagefilm.Image].
+type tileFilm struct {
+	width, height int
+	pixels        *tilePixels
+}
+
+func (f *tileFilm) Set(x, y int, clr color.Color) error {
+	p := f.pixels
+	if x < p.rect.Min.X || y < p.rect.Min.Y || x >= p.rect.Max.X || y >= p.rect.Max.Y {
+		return fmt.Errorf("pixel (%d, %d) is outside of tile %s", x, y, p.rect)
+	}
+
+	ri, gi, bi, _ := clr.RGBA()
+	sample := [3]float64{float64(ri) / 0xffff, float64(gi) / 0xffff, float64(bi) / 0xffff}
+
+	ind := (y-p.rect.Min.Y)*p.rect.Dx() + (x - p.rect.Min.X)
+	p.count[ind]++
+	n := float64(p.count[ind])
+	for ch, v := range sample {
+		p.mean[ind*3+ch] += (v - p.mean[ind*3+ch]) / n
+	}
+	return nil
+}
+
+func (f *tileFilm) StartFrame() {}
+func (f *tileFilm) DoneFrame()  {}
+func (f *tileFilm) Wait()       {}
+func (f *tileFilm) Width() int  { return f.width }
+func (f *tileFilm) Height() int { return f.height }
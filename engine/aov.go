@@ -0,0 +1,42 @@
+package engine
+
+import (
+	"github.com/ironsmile/raytracer/aov"
+	"github.com/ironsmile/raytracer/geometry"
+	"github.com/ironsmile/raytracer/primitive"
+)
+
+// addAOVs records in e.AOVs the output variables of the surface hit by the primary
+// `ray` through the screen position (x, y). `in` is the intersection of the ray.
+// `viewDir` is the viewing direction of the camera as returned by
+// [Engine.viewDirection].
+func (e *Engine) addAOVs(
+	ray geometry.Ray,
+	x, y float64,
+	viewDir geometry.Vector,
+	in *primitive.Intersection,
+) {
+	sp := e.surfaceAt(ray, in)
+	material := in.DfGeometry.Shape.MaterialAt(sp.objP)
+
+	s := aov.Sample{
+		Depth:       sp.p.Minus(ray.Origin).Dot(viewDir),
+		Normal:      sp.normal.Normalize(),
+		Position:    sp.p,
+		U:           in.DfGeometry.U,
+		V:           in.DfGeometry.V,
+		PrimitiveID: in.Primitive.GetID(),
+		MaterialID:  aov.MaterialID(material),
+	}
+	if material.Color != nil {
+		s.Albedo = *material.Color
+	}
+
+	e.AOVs.Add(int(x), int(y), &s)
+}
+
+// viewDirection returns the direction in which the camera is looking. This is the
+// direction of the ray through the centre of the screen.
+func (e *Engine) viewDirection() geometry.Vector {
+	return e.Camera.GenerateRay(float64(e.Width)/2, float64(e.Height)/2).Direction
+}
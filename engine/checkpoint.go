@@ -0,0 +1,117 @@
+package engine
+
+import (
+	"encoding"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ironsmile/raytracer/sampler"
+)
+
+// Checkpoint is the state of an unfinished progressive rendering. It is enough for
+// continuing the rendering in another process. See [Progressive.Resume].
+type Checkpoint struct {
+	// Progress is the progress of the rendering up to the checkpoint.
+	Progress ProgressiveResult
+
+	// Sampler is the state of the sampler after the last rendered pass.
+	Sampler sampler.State
+
+	// Destination holds the samples accumulated in the destination as returned
+	// by its MarshalBinary method.
+	Destination []byte
+
+	// AOVs holds the output variables collected by the engine, which are also the
+	// guide of the denoiser, as returned by [aov.Buffers.MarshalBinary]. It is
+	// empty when they are not collected.
+	AOVs []byte
+}
+
+// LoadCheckpoint reads a checkpoint written by [Checkpoint.Save].
+func LoadCheckpoint(filename string) (*Checkpoint, error) {
+	in, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	var c Checkpoint
+	if err := gob.NewDecoder(in).Decode(&c); err != nil {
+		return nil, fmt.Errorf("decoding checkpoint %s: %w", filename, err)
+	}
+	return &c, nil
+}
+
+// Save writes the checkpoint to `filename`. It is written in a temporary file
+// first so that a crash while saving does not destroy the previous checkpoint.
+func (c *Checkpoint) Save(filename string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := gob.NewEncoder(tmp).Encode(c); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// checkpoint returns the current state of the rendering with progress `res`.
+func (e *Engine) checkpoint(res ProgressiveResult) (*Checkpoint, error) {
+	dest, ok := e.Dest.(encoding.BinaryMarshaler)
+	if !ok {
+		return nil, fmt.Errorf("destination does not support checkpoints")
+	}
+
+	data, err := dest.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("saving the destination state: %w", err)
+	}
+
+	c := &Checkpoint{
+		Progress:    res,
+		Sampler:     e.Sampler.State(),
+		Destination: data,
+	}
+
+	if e.AOVs != nil {
+		c.AOVs, err = e.AOVs.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("saving the output variables: %w", err)
+		}
+	}
+
+	return c, nil
+}
+
+// restore continues the rendering from checkpoint `c`.
+func (e *Engine) restore(c *Checkpoint) error {
+	dest, ok := e.Dest.(encoding.BinaryUnmarshaler)
+	if !ok {
+		return fmt.Errorf("destination does not support checkpoints")
+	}
+
+	if err := dest.UnmarshalBinary(c.Destination); err != nil {
+		return fmt.Errorf("restoring the destination state: %w", err)
+	}
+
+	if e.AOVs != nil {
+		if c.AOVs == nil {
+			return fmt.Errorf("checkpoint has no output variables")
+		}
+		if err := e.AOVs.UnmarshalBinary(c.AOVs); err != nil {
+			return fmt.Errorf("restoring the output variables: %w", err)
+		}
+	}
+
+	// The destination goes first as adaptive sampling uses its samples.
+	e.Sampler.Restore(c.Sampler)
+	return nil
+}
@@ -0,0 +1,146 @@
+package engine_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ironsmile/raytracer/aov"
+	"github.com/ironsmile/raytracer/engine"
+	"github.com/ironsmile/raytracer/imagefilm"
+	"github.com/ironsmile/raytracer/sampler"
+)
+
+// TestResumeFromCheckpoint checks that a rendering continued from a checkpoint
+// produces the same image as one which was never stopped.
+func TestResumeFromCheckpoint(t *testing.T) {
+	const width, height = 64, 48
+	cfg := sampler.Config{SamplesPerPixel: 2, TileSize: 8, Seed: 7}
+	checkpoint := filepath.Join(t.TempDir(), "render.checkpoint")
+
+	render := func(p engine.Progressive) *image.NRGBA {
+		tracer, output := newTestEngine(t, width, height, cfg)
+		tracer.Scene.InitScene("teapot")
+
+		if _, err := tracer.RenderProgressive(p); err != nil {
+			t.Fatalf("rendering failed: %s", err)
+		}
+		return output.Image()
+	}
+
+	want := render(engine.Progressive{TargetSPP: 8})
+	render(engine.Progressive{TargetSPP: 4, CheckpointFile: checkpoint})
+
+	c, err := engine.LoadCheckpoint(checkpoint)
+	if err != nil {
+		t.Fatalf("loading checkpoint: %s", err)
+	}
+	if c.Progress.Passes != 2 {
+		t.Errorf("expected a checkpoint after 2 passes but got %d", c.Progress.Passes)
+	}
+
+	got := render(engine.Progressive{TargetSPP: 8, Resume: c})
+	if !bytes.Equal(got.Pix, want.Pix) {
+		t.Errorf("resumed rendering differs from the uninterrupted one")
+	}
+}
+
+// TestResumeInterruptedPass checks that a pass which is interrupted part way is not
+// counted and that resuming from the checkpoint renders it again. Every pixel must
+// get the same samples and output variables as in a rendering which was never
+// interrupted.
+func TestResumeInterruptedPass(t *testing.T) {
+	const width, height, spp = 48, 32, 2
+	cfg := sampler.Config{SamplesPerPixel: spp, TileSize: 8, Seed: 11}
+	checkpoint := filepath.Join(t.TempDir(), "render.checkpoint")
+
+	type rendering struct {
+		img    *image.NRGBA
+		counts *image.Gray16
+		aovs   *aov.Buffers
+		res    engine.ProgressiveResult
+	}
+
+	render := func(p engine.Progressive, interruptAfter int64) rendering {
+		img := imagefilm.NewImage("")
+		if err := img.Init(width, height); err != nil {
+			t.Fatalf("initializing output failed: %s", err)
+		}
+		output := &interruptingImage{Image: img, after: interruptAfter}
+		if interruptAfter > 0 {
+			output.interrupt = make(chan struct{})
+			p.Interrupt = output.interrupt
+		}
+
+		tracer := newTestEngineFor(t, output, cfg)
+		tracer.Scene.InitScene("teapot")
+		tracer.AOVs = aov.NewBuffers(width, height, []aov.Kind{aov.KindDepth, aov.KindNormal})
+
+		res, err := tracer.RenderProgressive(p)
+		if err != nil {
+			t.Fatalf("rendering failed: %s", err)
+		}
+		return rendering{img.Image(), img.SampleCounts(), tracer.AOVs, res}
+	}
+
+	want := render(engine.Progressive{TargetSPP: 4 * spp}, 0)
+
+	// The interrupt comes in the middle of the third pass. The sampler is stopped
+	// a bit later, so the pass may be finished and the next one interrupted.
+	interrupted := render(engine.Progressive{
+		TargetSPP:      4 * spp,
+		CheckpointFile: checkpoint,
+	}, 2*width*height*spp+width*height/2)
+	res := interrupted.res
+	if res.StoppedBy != "interrupt" {
+		t.Fatalf("rendering was stopped by %s", res.StoppedBy)
+	}
+	if res.Passes < 2 || res.Passes > 3 || res.SamplesPerPixel != res.Passes*spp {
+		t.Errorf("expected 2 or 3 whole passes with %d samples per pixel each but got %s",
+			spp, res)
+	}
+
+	c, err := engine.LoadCheckpoint(checkpoint)
+	if err != nil {
+		t.Fatalf("loading checkpoint: %s", err)
+	}
+	if c.Progress.Passes != res.Passes {
+		t.Errorf("expected a checkpoint after %d passes but got %d",
+			res.Passes, c.Progress.Passes)
+	}
+
+	got := render(engine.Progressive{TargetSPP: 4 * spp, Resume: c}, 0)
+	if !bytes.Equal(got.counts.Pix, want.counts.Pix) {
+		t.Errorf("resumed rendering has other sample counts than the uninterrupted one")
+	}
+	if !bytes.Equal(got.img.Pix, want.img.Pix) {
+		t.Errorf("resumed rendering differs from the uninterrupted one")
+	}
+	for _, kind := range want.aovs.Kinds() {
+		if !slices.Equal(got.aovs.Values(kind), want.aovs.Values(kind)) {
+			t.Errorf("resumed %s differs from the uninterrupted one", kind)
+		}
+	}
+}
+
+// interruptingImage is an image which closes `interrupt` after `after` samples.
+type interruptingImage struct {
+	*imagefilm.Image
+
+	after     int64
+	interrupt chan struct{}
+	samples   atomic.Int64
+	once      sync.Once
+}
+
+func (i *interruptingImage) Set(x, y int, clr color.Color) error {
+	if i.samples.Add(1) == i.after {
+		i.once.Do(func() { close(i.interrupt) })
+	}
+	return i.Image.Set(x, y, clr)
+}
@@ -2,16 +2,21 @@ package engine
 
 import (
 	"fmt"
+	"log/slog"
 	"math"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/ironsmile/raytracer/accel"
+	"github.com/ironsmile/raytracer/aov"
 	"github.com/ironsmile/raytracer/camera"
 	"github.com/ironsmile/raytracer/geometry"
 	"github.com/ironsmile/raytracer/primitive"
 	"github.com/ironsmile/raytracer/sampler"
 	"github.com/ironsmile/raytracer/scene"
+	"github.com/ironsmile/raytracer/utils"
 )
 
 const (
@@ -30,7 +35,46 @@ type Engine struct {
 	Sampler       *sampler.SimpleSampler
 	ShowBBoxes    bool
 
+	// Mode controls what is painted for every sample. See [RenderMode].
+	Mode RenderMode
+
+	// CollectStats makes the engine count the scene accelerator work for every
+	// ray. Accumulated statistics are available with [Engine.Stats].
+	CollectStats bool
+
+	// HeatmapScale is the traversal cost which is painted with the hottest colour
+	// when rendering in [RenderHeatmap] mode. Zero means a default value is used.
+	HeatmapScale float64
+
+	// UsePackets makes the engine trace primary and shadow rays for neighbouring
+	// pixels together as ray packets. It is only used for the [RenderShaded] mode
+	// when statistics are not collected.
+	UsePackets bool
+
+	// AOVs collects the arbitrary output variables of the surfaces hit by the
+	// primary rays when not nil. They are collected only in the [RenderShaded] mode.
+	// Ray packets are not used while collecting them.
+	AOVs *aov.Buffers
+
 	debugged bool
+
+	// logger receives the diagnostics of the engine. See [Engine.SetLogger].
+	logger *slog.Logger
+
+	// stats holds the work done since the last [Engine.ResetStats]. The frame
+	// counters are always updated, the rest only when CollectStats is set.
+	stats      renderStats
+	frames     int
+	renderTime time.Duration
+	statsLock  sync.Mutex
+
+	// err is the first error of the last frame. It is guarded by errLock.
+	err     error
+	errLock sync.Mutex
+
+	// selected is the primitive whose bounding box is highlighted. See
+	// [Engine.Select].
+	selected atomic.Pointer[selection]
 }
 
 // SetTarget sets the camera and film for rendering.
@@ -39,41 +83,82 @@ func (e *Engine) SetTarget(target Destination, cam camera.Camera) {
 	e.Height = target.Height()
 	e.Dest = target
 	e.Camera = cam
+
+	if lt, ok := target.(loggerSetter); ok && e.logger != nil {
+		lt.SetLogger(e.logger)
+	}
+}
+
+// SetLogger makes the engine write its diagnostics to `l`. The logger is also
+// given to the scene and to the destination when it has a SetLogger method, so
+// the scene should be loaded after calling it. Nil disables logging, which is
+// the default.
+func (e *Engine) SetLogger(l *slog.Logger) {
+	e.logger = l
+	if e.Scene != nil {
+		e.Scene.Logger = l
+	}
+	if lt, ok := e.Dest.(loggerSetter); ok {
+		lt.SetLogger(l)
+	}
+}
+
+// loggerSetter is implemented by destinations which log, such as [imagefilm.Image].
+type loggerSetter interface {
+	SetLogger(*slog.Logger)
 }
 
 // Raytrace returns intersection information for particular ray in the engine's
 // scene.
 func (e *Engine) Raytrace(ray geometry.Ray, depth int64, in *primitive.Intersection) geometry.Color {
+	return e.raytrace(ray, depth, in, nil)
+}
+
+// raytrace is the implementation of [Engine.Raytrace]. When `st` is not nil the
+// work done by the scene accelerator for all traced rays is recorded in it.
+func (e *Engine) raytrace(
+	ray geometry.Ray,
+	depth int64,
+	in *primitive.Intersection,
+	st *renderStats,
+) geometry.Color {
 	var retColor geometry.Color
 
 	if depth > TraceDepth {
 		return retColor
 	}
 
-	if ok := e.Scene.Intersect(ray, in); !ok {
+	if ok := e.intersect(ray, in, st); !ok {
 		return retColor
 	}
 
-	prim := in.Primitive
-	pi := ray.At(in.DfGeometry.Distance)
+	return e.shade(ray, depth, in, st, nil)
+}
 
-	if prim.IsLight() {
-		return *prim.Shape().MaterialAt(pi).Color
-	}
+// shade returns the colour at the intersection `in` of `ray`. `occluded` holds whether
+// every light in the scene is hidden from the intersection point. When it is nil
+// shadow rays are traced for finding that out.
+func (e *Engine) shade(
+	ray geometry.Ray,
+	depth int64,
+	in *primitive.Intersection,
+	st *renderStats,
+	occluded []bool,
+) geometry.Color {
+	var retColor geometry.Color
 
-	o2w, w2o := prim.GetTransforms()
-	pio := w2o.Point(pi)
-	InNormal := o2w.Normal(in.DfGeometry.Shape.NormalAt(pio))
+	if st != nil && depth == 1 {
+		defer st.addShadingTime(time.Now())
+	}
 
-	cosI := InNormal.Dot(ray.Direction)
-	if cosI > 0 {
-		// The hit is from the inside of the primitive. Normally, all normals would be
-		// pointing toward the primitive exterior. So we have to invert it to the interior
-		// for proper calculations.
-		InNormal = InNormal.Neg()
+	prim := in.Primitive
+	if prim.IsLight() {
+		return *prim.Shape().MaterialAt(ray.At(in.DfGeometry.Distance)).Color
 	}
 
-	primMat := in.DfGeometry.Shape.MaterialAt(pio)
+	sp := e.surfaceAt(ray, in)
+	InNormal := sp.normal
+	primMat := in.DfGeometry.Shape.MaterialAt(sp.objP)
 
 	// /* Debugging */
 	// var debugging bool
@@ -88,13 +173,18 @@ func (e *Engine) Raytrace(ray geometry.Ray, depth int64, in *primitive.Intersect
 		light := e.Scene.GetLight(l)
 
 		source := light.GetLightSource()
-		shadowRayStart := pi.Plus(InNormal.MultiplyScalar(geometry.EPSILON))
-		L := source.Minus(shadowRayStart).Normalize()
-		shadowRay := geometry.NewRay(shadowRayStart, L)
-		shadowRay.Maxt = shadowRayStart.Distance(source)
+		shadowRay := sp.shadowRay(source)
+		L := shadowRay.Direction
 
-		if intersected := e.Scene.IntersectP(shadowRay); intersected {
-			continue
+		if occluded != nil {
+			if occluded[l] {
+				continue
+			}
+		} else {
+			st.countRay(rayShadow)
+			if intersected := e.intersectP(shadowRay, st); intersected {
+				continue
+			}
 		}
 
 		dot := InNormal.Product(L)
@@ -125,11 +215,11 @@ func (e *Engine) Raytrace(ray geometry.Ray, depth int64, in *primitive.Intersect
 			ray.Direction.Product(InNormal) * 2.0),
 		)
 
-		refRay := geometry.NewRay(pi, R)
-		refRay.Mint = geometry.EPSILON
+		refRay := sp.spawnRay(R)
 
 		// refRay.Debug = ray.Debug
-		refColor := e.Raytrace(refRay, depth+1, in)
+		st.countRay(rayReflection)
+		refColor := e.raytrace(refRay, depth+1, in, st)
 
 		retColor.PlusIP(primMat.Color.Multiply(
 			&refColor).MultiplyScalarIP(primMat.Refl))
@@ -155,9 +245,9 @@ func (e *Engine) Raytrace(ray geometry.Ray, depth int64, in *primitive.Intersect
 		transmittance = 1 - reflectance
 
 		if transmittance > 0 {
-			reflRay := geometry.NewRay(pi, refrDirection)
-			reflRay.Mint = geometry.EPSILON * 2
-			refrColor := e.Raytrace(reflRay, depth+1, in)
+			reflRay := sp.spawnRay(refrDirection)
+			st.countRay(rayRefraction)
+			refrColor := e.raytrace(reflRay, depth+1, in, st)
 			endColor.PlusIP(refrColor.MultiplyScalarIP(transmittance))
 		}
 
@@ -165,9 +255,9 @@ func (e *Engine) Raytrace(ray geometry.Ray, depth int64, in *primitive.Intersect
 			cosI := -refrNormal.Dot(ray.Direction)
 			R := ray.Direction.Plus(refrNormal.MultiplyScalar(2 * cosI))
 
-			refRay := geometry.NewRay(pi, R)
-			refRay.Mint = geometry.EPSILON
-			refColor := e.Raytrace(refRay, depth+1, in)
+			refRay := sp.spawnRay(R)
+			st.countRay(rayReflection)
+			refColor := e.raytrace(refRay, depth+1, in, st)
 			endColor.PlusIP(refColor.MultiplyScalarIP(reflectance))
 		}
 
@@ -178,12 +268,92 @@ func (e *Engine) Raytrace(ray geometry.Ray, depth int64, in *primitive.Intersect
 	return retColor
 }
 
+// surfacePoint describes the point of a ray intersection in world space.
+type surfacePoint struct {
+	// p is the intersection point and pError is the bound on its absolute error.
+	p, pError geometry.Vector
+
+	// objP is the intersection point in the object space of the primitive.
+	objP geometry.Vector
+
+	// normal is the shading normal. It always faces the side from which the ray came.
+	normal geometry.Vector
+
+	// geomNormal is the geometric normal used for offsetting rays leaving the
+	// surface.
+	geomNormal geometry.Vector
+}
+
+// surfaceAt returns the surface point for the intersection `in` of `ray`.
+func (e *Engine) surfaceAt(ray geometry.Ray, in *primitive.Intersection) surfacePoint {
+	o2w, _ := in.Primitive.GetTransforms()
+	dg := &in.DfGeometry
+
+	var sp surfacePoint
+	sp.objP = dg.Point
+	sp.p, sp.pError = o2w.PointWithError(dg.Point, dg.PointError)
+	sp.normal = o2w.Normal(dg.Shape.NormalAt(dg.Point))
+	sp.geomNormal = o2w.Normal(dg.Normal)
+
+	cosI := sp.normal.Dot(ray.Direction)
+	if cosI > 0 {
+		// The hit is from the inside of the primitive. Normally, all normals would be
+		// pointing toward the primitive exterior. So we have to invert it to the interior
+		// for proper calculations.
+		sp.normal = sp.normal.Neg()
+	}
+
+	return sp
+}
+
+// spawnRay returns a ray with direction `d` leaving the surface point.
+func (sp *surfacePoint) spawnRay(d geometry.Vector) geometry.Ray {
+	return geometry.SpawnRay(sp.p, sp.pError, sp.geomNormal, d)
+}
+
+// shadowRay returns a ray from the surface point toward the light `source`.
+func (sp *surfacePoint) shadowRay(source geometry.Vector) geometry.Ray {
+	return geometry.SpawnRayTo(sp.p, sp.pError, sp.geomNormal, source)
+}
+
+func (e *Engine) intersect(
+	ray geometry.Ray,
+	in *primitive.Intersection,
+	st *renderStats,
+) bool {
+	if st == nil {
+		return e.Scene.Intersect(ray, in)
+	}
+
+	hit := e.Scene.IntersectStats(ray, in, &st.traversal)
+	if hit {
+		st.intersections++
+	}
+	return hit
+}
+
+func (e *Engine) intersectP(ray geometry.Ray, st *renderStats) bool {
+	if st == nil {
+		return e.Scene.IntersectP(ray)
+	}
+
+	hit := e.Scene.IntersectPStats(ray, &st.traversal)
+	if hit {
+		st.intersections++
+	}
+	return hit
+}
+
 // Render starts the rendering process. Exits when one full frame is done. It does that
 // by starting multiple concurrent renderer goroutines.
 func (e *Engine) Render() {
 
 	var wg sync.WaitGroup
 
+	e.errLock.Lock()
+	e.err = nil
+	e.errLock.Unlock()
+
 	e.Dest.StartFrame()
 
 	engineTimer := time.Now()
@@ -195,17 +365,65 @@ func (e *Engine) Render() {
 
 	wg.Wait()
 
-	fmt.Printf("Engine frame time: %s\n", time.Since(engineTimer))
+	frameTime := time.Since(engineTimer)
+	e.statsLock.Lock()
+	e.frames++
+	e.renderTime += frameTime
+	e.statsLock.Unlock()
+
+	e.log().Debug("frame rendered",
+		"duration", frameTime,
+		"subSamplers", e.Sampler.SubSamplers(),
+	)
 
 	e.Dest.DoneFrame()
 }
 
+// log returns the logger of the engine. It is never nil.
+func (e *Engine) log() *slog.Logger {
+	return utils.Logger(e.logger)
+}
+
+// Err returns the first error which stopped a renderer goroutine during the last
+// frame. The rest of the goroutines finish the frame without it.
+func (e *Engine) Err() error {
+	e.errLock.Lock()
+	defer e.errLock.Unlock()
+	return e.err
+}
+
+// fail records `err` as the error of the current frame unless there already is
+// one.
+func (e *Engine) fail(err error) {
+	e.errLock.Lock()
+	defer e.errLock.Unlock()
+	if e.err == nil {
+		e.err = err
+	}
+}
+
 func (e *Engine) subRender(wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	var accColor geometry.Color
 	var in primitive.Intersection
 
+	var st *renderStats
+	if e.CollectStats {
+		st = &renderStats{}
+		defer e.addStats(st)
+	}
+
+	if e.UsePackets && e.Mode == RenderShaded && st == nil && e.AOVs == nil {
+		e.subRenderPackets()
+		return
+	}
+
+	var viewDir geometry.Vector
+	if e.AOVs != nil {
+		viewDir = e.viewDirection()
+	}
+
 	for {
 
 		subSampler, err := e.Sampler.GetSubSampler()
@@ -224,36 +442,201 @@ func (e *Engine) subRender(wg *sync.WaitGroup) {
 				break
 			}
 			if err != nil {
-				fmt.Printf("Error while getting sample: %s\n", err)
+				e.fail(fmt.Errorf("getting sample: %w", err))
 				return
 			}
 
 			// fmt.Printf("x: %f, y: %f\n", x, y)
 
 			ray := e.Camera.GenerateRay(x, y)
-			accColor = e.Raytrace(ray, 1, &in)
+			if camera.Blind(ray) {
+				accColor = geometry.Color{}
+				subSampler.UpdateScreen(x, y, &accColor)
+				continue
+			}
+			st.countRay(rayPrimary)
+
+			// Shading traces further rays with `in`, so the distance to the
+			// primary hit is kept for painting the bounding boxes in front of it.
+			hitDistance := math.Inf(1)
+			if e.Mode == RenderHeatmap {
+				var hit bool
+				accColor, hit = e.heatmap(ray, &in, st)
+				if hit {
+					hitDistance = in.DfGeometry.Distance
+				}
+			} else {
+				accColor = geometry.Color{}
+				if e.intersect(ray, &in, st) {
+					hitDistance = in.DfGeometry.Distance
+					if e.AOVs != nil {
+						e.addAOVs(ray, x, y, viewDir, &in)
+					}
+					accColor = e.shade(ray, 1, &in, st, nil)
+				}
+			}
 
 			if e.ShowBBoxes {
-				if in.Primitive != nil {
-					ray.Maxt = in.DfGeometry.Distance
+				e.paintBBoxes(ray, hitDistance, &accColor)
+			}
+			e.paintSelection(ray, hitDistance, &accColor)
+
+			subSampler.UpdateScreen(x, y, &accColor)
+		}
+	}
+}
+
+// subRenderPackets is the same as the per-sample loop in [Engine.subRender] but
+// traces primary and shadow rays in packets.
+func (e *Engine) subRenderPackets() {
+	var (
+		buf      []sampler.Sample
+		packet   accel.RayPacket
+		shadow   accel.RayPacket
+		shadowed [][accel.PacketSize]bool
+		occluded []bool
+		blind    [accel.PacketSize]bool
+	)
+
+	for {
+		subSampler, err := e.Sampler.GetSubSampler()
+
+		if err == sampler.ErrEndOfSampling {
+			return
+		}
+
+		if len(buf) < subSampler.PacketLen() {
+			buf = make([]sampler.Sample, subSampler.PacketLen())
+		}
+
+		for {
+			n, err := subSampler.GetPacket(buf)
+
+			if err == sampler.ErrEndOfSampling {
+				return
+			}
+			if err == sampler.ErrSubSamplerEnd {
+				break
+			}
+			if err != nil {
+				e.fail(fmt.Errorf("getting packet: %w", err))
+				return
+			}
+
+			for start := 0; start < n; start += accel.PacketSize {
+				samples := buf[start:min(start+accel.PacketSize, n)]
+
+				// Blind rays are traced forward so that the packet stays whole
+				// but their hits are not shaded.
+				packet.Len = len(samples)
+				for i, smpl := range samples {
+					ray := e.Camera.GenerateRay(smpl.X, smpl.Y)
+					blind[i] = camera.Blind(ray)
+					if blind[i] {
+						ray = geometry.NewRay(ray.Origin, geometry.NewVector(0, 0, 1))
+					}
+					packet.Rays[i] = ray
+				}
+				e.Scene.IntersectPacket(&packet)
+
+				nrLights := e.Scene.GetNrLights()
+				if len(shadowed) < nrLights {
+					shadowed = make([][accel.PacketSize]bool, nrLights)
+					occluded = make([]bool, nrLights)
 				}
 
-				if e.Scene.IntersectBBoxEdge(ray) {
-					accColor = *geometry.NewColor(0, 0, 1)
+				for l := 0; l < nrLights; l++ {
+					e.traceShadowPacket(&packet, &shadow, l, &shadowed[l])
 				}
 
-				// debugRay := geometry.NewRay(
-				// 	geometry.NewVector(-10.000000, -0.097124, 0.562618),
-				// 	geometry.NewVector(0.151860, -0.768368, 0.621731),
-				// )
-				// if _, ok := ray.Intersect(debugRay); ok {
-				// 	accColor = *geometry.NewColor(1, 1, 0)
-				// }
+				for i, smpl := range samples {
+					var accColor geometry.Color
+					ray := packet.Rays[i]
+
+					hitDistance := math.Inf(1)
+					if packet.Hit[i] && !blind[i] {
+						for l := 0; l < nrLights; l++ {
+							occluded[l] = shadowed[l][i]
+						}
+						in := &packet.Isect[i]
+						hitDistance = in.DfGeometry.Distance
+						accColor = e.shade(ray, 1, in, nil, occluded[:nrLights])
+					}
+
+					if !blind[i] {
+						if e.ShowBBoxes {
+							e.paintBBoxes(ray, hitDistance, &accColor)
+						}
+						e.paintSelection(ray, hitDistance, &accColor)
+					}
+
+					subSampler.UpdateScreen(smpl.X, smpl.Y, &accColor)
+				}
 			}
+		}
+	}
+}
 
-			e.Sampler.UpdateScreen(x, y, &accColor)
+// traceShadowPacket intersects the scene with shadow rays from every hit in
+// `packet` toward the light with index `l`. Afterwards occluded[i] tells whether
+// the light is hidden for the i-th ray in `packet`. `shadow` is used as a scratch
+// space for the shadow rays.
+func (e *Engine) traceShadowPacket(
+	packet, shadow *accel.RayPacket,
+	l int,
+	occluded *[accel.PacketSize]bool,
+) {
+	source := e.Scene.GetLight(l).GetLightSource()
+
+	// Only surface hits have shadow rays. They are packed at the start of `shadow`
+	// and `index` maps them back to their rays in `packet`.
+	var index [accel.PacketSize]int
+	shadow.Len = 0
+	for i := 0; i < packet.Len; i++ {
+		occluded[i] = false
+
+		in := &packet.Isect[i]
+		if !packet.Hit[i] || in.Primitive.IsLight() {
+			continue
 		}
+
+		sp := e.surfaceAt(packet.Rays[i], in)
+		shadow.Rays[shadow.Len] = sp.shadowRay(source)
+		index[shadow.Len] = i
+		shadow.Len++
+	}
+
+	if shadow.Len == 0 {
+		return
+	}
+
+	e.Scene.IntersectPPacket(shadow)
+	for j := 0; j < shadow.Len; j++ {
+		occluded[index[j]] = shadow.Hit[j]
+	}
+}
+
+// paintBBoxes replaces `color` with the bounding boxes edge colour when `ray` hits
+// an edge of any bounding box in the scene before `hitDistance`, the distance to
+// its first intersection. It is infinite for rays which hit nothing.
+func (e *Engine) paintBBoxes(
+	ray geometry.Ray,
+	hitDistance float64,
+	color *geometry.Color,
+) {
+	ray.Maxt = min(ray.Maxt, hitDistance)
+
+	if e.Scene.IntersectBBoxEdge(ray) {
+		*color = *geometry.NewColor(0, 0, 1)
 	}
+
+	// debugRay := geometry.NewRay(
+	// 	geometry.NewVector(-10.000000, -0.097124, 0.562618),
+	// 	geometry.NewVector(0.151860, -0.768368, 0.621731),
+	// )
+	// if _, ok := ray.Intersect(debugRay); ok {
+	// 	*color = *geometry.NewColor(1, 1, 0)
+	// }
 }
 
 // New returns a new engine which would use the argument's sampler
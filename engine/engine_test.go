@@ -0,0 +1,59 @@
+package engine_test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/ironsmile/raytracer/engine"
+	"github.com/ironsmile/raytracer/imagefilm"
+	"github.com/ironsmile/raytracer/sampler"
+	"github.com/ironsmile/raytracer/scene"
+)
+
+// TestMain runs the tests from the root of the repository because the models of
+// the scenes are loaded relative to it.
+func TestMain(m *testing.M) {
+	if err := os.Chdir(".."); err != nil {
+		fmt.Fprintf(os.Stderr, "cannot change to the root of the repository: %s\n", err)
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+// newTestEngine returns an engine which renders into an image with the given size
+// using the default camera. Its scene is not loaded yet. The sampler is stopped
+// when the test ends.
+func newTestEngine(
+	t *testing.T,
+	width, height int,
+	cfg sampler.Config,
+) (*engine.Engine, *imagefilm.Image) {
+	t.Helper()
+
+	output := imagefilm.NewImage("")
+	if err := output.Init(width, height); err != nil {
+		t.Fatalf("initializing output failed: %s", err)
+	}
+	return newTestEngineFor(t, output, cfg), output
+}
+
+// testOutput is a destination of the engine in which the sampler writes.
+type testOutput interface {
+	engine.Destination
+	sampler.Output
+}
+
+// newTestEngineFor is the same as newTestEngine but renders into `output` which
+// must be initialized already.
+func newTestEngineFor(t *testing.T, output testOutput, cfg sampler.Config) *engine.Engine {
+	t.Helper()
+
+	width, height := output.Width(), output.Height()
+	smpl := sampler.NewSimple(width, height, output, cfg)
+	t.Cleanup(smpl.Stop)
+
+	tracer := engine.New(smpl)
+	tracer.SetTarget(output, scene.GetCamera(float64(width), float64(height)))
+	return tracer
+}
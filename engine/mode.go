@@ -0,0 +1,49 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RenderMode controls what the engine writes in the output film for every sample.
+type RenderMode int
+
+const (
+	// RenderShaded is the normal mode of operation. Every sample is raytraced and
+	// shaded.
+	RenderShaded RenderMode = iota
+
+	// RenderHeatmap paints a false colour which represents how expensive it was
+	// for the scene accelerator to find the intersection of the primary ray. No
+	// shading is done in this mode.
+	RenderHeatmap
+)
+
+// String implements the [fmt.Stringer] interface.
+func (m RenderMode) String() string {
+	switch m {
+	case RenderShaded:
+		return "shaded"
+	case RenderHeatmap:
+		return "heatmap"
+	default:
+		return fmt.Sprintf("RenderMode(%d)", int(m))
+	}
+}
+
+// ParseRenderMode returns the render mode with the name `name`. Names are the same
+// as the ones returned by [RenderMode.String].
+func ParseRenderMode(name string) (RenderMode, error) {
+	for _, m := range RenderModes {
+		if strings.EqualFold(m.String(), name) {
+			return m, nil
+		}
+	}
+	return RenderShaded, fmt.Errorf("unknown render mode `%s`", name)
+}
+
+// RenderModes is a list of all supported render modes.
+var RenderModes = []RenderMode{
+	RenderShaded,
+	RenderHeatmap,
+}
@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/ironsmile/raytracer/bbox"
+	"github.com/ironsmile/raytracer/camera"
+	"github.com/ironsmile/raytracer/geometry"
+	"github.com/ironsmile/raytracer/mat"
+	"github.com/ironsmile/raytracer/primitive"
+)
+
+// PickResult describes what is seen at a point of the image. See [Engine.Pick].
+type PickResult struct {
+	Primitive primitive.Primitive
+
+	// Name is the name of the primitive. See [primitive.GetName].
+	Name string
+
+	// Material is the material of the surface at the hit point.
+	Material mat.Material
+
+	// Distance is the distance from the camera to the hit point.
+	Distance float64
+
+	// Point is the hit point and Normal is the shading normal there in world
+	// space. The normal faces the camera.
+	Point  geometry.Vector
+	Normal geometry.Vector
+}
+
+// String implements fmt.Stringer.
+func (p PickResult) String() string {
+	s := fmt.Sprintf("%q at distance %.4f, point %s, normal %s",
+		p.Name, p.Distance, p.Point, p.Normal)
+
+	m := p.Material
+	if m.Color != nil {
+		s += fmt.Sprintf(", colour %.3f %.3f %.3f", m.Color.Red(), m.Color.Green(), m.Color.Blue())
+	}
+	return s + fmt.Sprintf(", diffuse %g, reflection %g, refraction %g (index %g)",
+		m.Diff, m.Refl, m.Refr, m.RefrIndex)
+}
+
+// Pick casts a ray from the camera through the raster position (x, y) and returns
+// the first surface which it hits. It returns false when nothing is hit.
+func (e *Engine) Pick(x, y float64) (PickResult, bool) {
+	ray := e.Camera.GenerateRay(x, y)
+	if camera.Blind(ray) {
+		return PickResult{}, false
+	}
+
+	var in primitive.Intersection
+	if !e.Scene.Intersect(ray, &in) {
+		return PickResult{}, false
+	}
+
+	sp := e.surfaceAt(ray, &in)
+	res := PickResult{
+		Primitive: in.Primitive,
+		Name:      primitive.GetName(in.Primitive.GetID()),
+		Distance:  in.DfGeometry.Distance,
+		Point:     sp.p,
+		Normal:    sp.normal,
+	}
+	if m := in.DfGeometry.Shape.MaterialAt(sp.objP); m != nil {
+		res.Material = *m
+	}
+
+	return res, true
+}
+
+// selection is the primitive highlighted with [Engine.Select].
+type selection struct {
+	prim  primitive.Primitive
+	bound *bbox.BBox
+}
+
+// Select highlights the bounding box of `prim` in the rendered images. The
+// highlight is removed when it is nil. It is safe to call while rendering.
+func (e *Engine) Select(prim primitive.Primitive) {
+	if prim == nil {
+		e.selected.Store(nil)
+		return
+	}
+
+	e.selected.Store(&selection{
+		prim:  prim,
+		bound: prim.GetWorldBBox(),
+	})
+}
+
+// Selected returns the primitive highlighted with [Engine.Select] or nil.
+func (e *Engine) Selected() primitive.Primitive {
+	if sel := e.selected.Load(); sel != nil {
+		return sel.prim
+	}
+	return nil
+}
+
+// paintSelection replaces `color` with the highlight colour when `ray` hits an
+// edge of the bounding box of the selected primitive before `hitDistance`. See
+// [Engine.paintBBoxes].
+func (e *Engine) paintSelection(
+	ray geometry.Ray,
+	hitDistance float64,
+	color *geometry.Color,
+) {
+	sel := e.selected.Load()
+	if sel == nil || sel.bound == nil {
+		return
+	}
+
+	ray.Maxt = min(ray.Maxt, hitDistance)
+
+	if ok, _ := sel.bound.IntersectEdge(ray); ok {
+		*color = *geometry.NewColor(1, 1, 0)
+	}
+}
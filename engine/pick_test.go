@@ -0,0 +1,130 @@
+package engine_test
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/ironsmile/raytracer/engine"
+	"github.com/ironsmile/raytracer/geometry"
+	"github.com/ironsmile/raytracer/sampler"
+)
+
+// TestPick checks that picking finds the object in the centre of the teapot scene
+// and that selecting it highlights its bounding box.
+func TestPick(t *testing.T) {
+	// The edges of bounding boxes are thin so the image must be big enough for
+	// them to cover whole pixels.
+	const width, height = 320, 240
+
+	tracer, output := newTestEngine(t, width, height, sampler.Config{SamplesPerPixel: 1})
+	tracer.Scene.InitScene("teapot")
+
+	res, ok := tracer.Pick(width/2, height/2)
+	if !ok {
+		t.Fatalf("nothing picked in the centre of the image")
+	}
+	if res.Name != "big red sphere" {
+		t.Errorf("picked %q instead of the big red sphere", res.Name)
+	}
+
+	// The sphere has a radius of 2.5 and is at (1, -0.8, 3). The camera is at
+	// (0, 0, -5) and looks along the Z axis.
+	wantDist := 8 - math.Sqrt(2.5*2.5-1-0.64)
+	if math.Abs(res.Distance-wantDist) > 1e-6 {
+		t.Errorf("expected distance %g but got %g", wantDist, res.Distance)
+	}
+	wantNormal := res.Point.Minus(geometry.NewVector(1, -0.8, 3)).Normalize()
+	if !res.Normal.Equals(wantNormal) || res.Normal.Z >= 0 {
+		t.Errorf("expected normal %s facing the camera but got %s", wantNormal, res.Normal)
+	}
+	if res.Material.Color == nil || res.Material.Diff != 0.9 {
+		t.Errorf("unexpected material %+v", res.Material)
+	}
+
+	if _, ok := tracer.Pick(width/2, 0); !ok {
+		t.Errorf("expected to pick the ceiling")
+	}
+
+	tracer.Select(res.Primitive)
+	if tracer.Selected() != res.Primitive {
+		t.Errorf("the picked primitive is not selected")
+	}
+
+	if _, err := tracer.RenderProgressive(engine.Progressive{TargetSPP: 1}); err != nil {
+		t.Fatalf("rendering failed: %s", err)
+	}
+
+	var highlighted int
+	img := output.Image()
+	for i := 0; i < len(img.Pix); i += 4 {
+		if img.Pix[i] == 255 && img.Pix[i+1] == 255 && img.Pix[i+2] == 0 {
+			highlighted++
+		}
+	}
+	if highlighted == 0 {
+		t.Errorf("the bounding box of the selected primitive is not highlighted")
+	}
+
+	tracer.Select(nil)
+	if tracer.Selected() != nil {
+		t.Errorf("the selection is not removed")
+	}
+}
+
+// TestSelectionBehindReflections checks that the highlight of the selection is
+// clipped at the primary hit of every sample and not at the hits of the rays
+// traced for reflections and refractions. The heatmap mode traces only the
+// primary rays, so the shaded images must highlight the same pixels as it.
+func TestSelectionBehindReflections(t *testing.T) {
+	const width, height = 320, 240
+
+	render := func(mode engine.RenderMode, packets, selected bool) []uint8 {
+		tracer, output := newTestEngine(t, width, height,
+			sampler.Config{SamplesPerPixel: 1, Seed: 5})
+		tracer.Scene.InitScene("teapot")
+		tracer.Mode = mode
+		tracer.UsePackets = packets
+
+		if selected {
+			res, ok := tracer.Pick(width/2, height/2)
+			if !ok {
+				t.Fatalf("nothing picked in the centre of the image")
+			}
+			tracer.Select(res.Primitive)
+		}
+
+		if _, err := tracer.RenderProgressive(engine.Progressive{TargetSPP: 1}); err != nil {
+			t.Fatalf("rendering failed: %s", err)
+		}
+		return output.Image().Pix
+	}
+
+	// highlighted returns which pixels are painted with the highlight colour only
+	// because of the selection.
+	highlighted := func(mode engine.RenderMode, packets bool) []bool {
+		with, without := render(mode, packets, true), render(mode, packets, false)
+		mask := make([]bool, len(with)/4)
+		for i := range mask {
+			p := with[i*4 : i*4+3]
+			mask[i] = p[0] == 255 && p[1] == 255 && p[2] == 0 &&
+				!bytes.Equal(p, without[i*4:i*4+3])
+		}
+		return mask
+	}
+
+	want := highlighted(engine.RenderHeatmap, false)
+	for _, packets := range []bool{false, true} {
+		got := highlighted(engine.RenderShaded, packets)
+		var diff int
+		for i := range want {
+			if got[i] != want[i] {
+				diff++
+			}
+		}
+		if diff > 0 {
+			t.Errorf("with packets %t %d pixels are highlighted differently than "+
+				"with the heatmap", packets, diff)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package engine
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Progressive configures progressive rendering. See [Engine.RenderProgressive].
+// Rendering stops when any of the set limits is reached. At least one of them must
+// be set.
+type Progressive struct {
+	// TargetSPP is the number of samples per pixel after which rendering stops.
+	// It is rounded up to a whole number of passes. Zero means no limit.
+	TargetSPP int
+
+	// TimeBudget is the time after which rendering stops. It is checked at the end
+	// of every pass so rendering may take longer by up to one pass. Zero means no
+	// limit.
+	TimeBudget time.Duration
+
+	// NoiseThreshold stops rendering once the noise estimate of the destination
+	// falls below it. It works only for destinations which implement
+	// [NoiseEstimator]. Zero means no limit.
+	NoiseThreshold float64
+
+	// CheckpointFile is the file in which the state of the rendering is saved
+	// every CheckpointInterval and once more when rendering stops. The rendering
+	// may be continued from it with Resume. It works only for destinations which
+	// implement [encoding.BinaryMarshaler]. Empty means no checkpoints.
+	CheckpointFile string
+
+	// CheckpointInterval is the minimum time between two checkpoints. They are
+	// saved only at the end of passes. Zero means after every pass.
+	CheckpointInterval time.Duration
+
+	// Resume continues the rendering from a checkpoint instead of starting from
+	// scratch. The destination must implement [encoding.BinaryUnmarshaler]. The
+	// passes and time in the checkpoint count towards the limits.
+	Resume *Checkpoint
+
+	// Interrupt stops the rendering when it is closed. Sub samplers in flight stop
+	// after their current sample so the last pass is only partially done. The
+	// destination keeps the samples taken so far but the partial pass is not
+	// counted in the result. The checkpoint is the one from the end of the last
+	// whole pass, so resuming renders the partial pass again.
+	Interrupt <-chan struct{}
+
+	// OnPass is called after every pass with the progress so far when it is not
+	// nil. The destination is not written while it runs.
+	OnPass func(ProgressiveResult)
+}
+
+// NoiseEstimator is implemented by destinations which are able to estimate how
+// noisy their accumulated image is.
+type NoiseEstimator interface {
+	// Noise returns the relative noise of the image, smaller is better, and the
+	// number of pixels from which it is estimated. Nothing is known about the noise
+	// while there are no such pixels.
+	Noise() (float64, int)
+}
+
+// ProgressiveResult describes a finished progressive rendering.
+type ProgressiveResult struct {
+	// Passes is the number of rendered passes.
+	Passes int
+
+	// SamplesPerPixel is the number of samples taken for every pixel. With adaptive
+	// sampling individual pixels get more or fewer samples than that.
+	SamplesPerPixel int
+
+	// Elapsed is the total rendering time.
+	Elapsed time.Duration
+
+	// Noise is the last noise estimate of the destination. It is zero when the
+	// destination does not implement [NoiseEstimator].
+	Noise float64
+
+	// StoppedBy describes which limit stopped the rendering.
+	StoppedBy string
+}
+
+// String implements fmt.Stringer.
+func (r ProgressiveResult) String() string {
+	return fmt.Sprintf("%d passes, %d samples per pixel, noise %.5f in %s (stopped by %s)",
+		r.Passes, r.SamplesPerPixel, r.Noise, r.Elapsed, r.StoppedBy)
+}
+
+// RenderProgressive keeps rendering passes over the whole frame until one of the
+// limits in `p` is reached. Every pass takes new samples for every pixel which the
+// destination is expected to accumulate. It finishes a frame after every pass so
+// destinations may present or store intermediate images.
+func (e *Engine) RenderProgressive(p Progressive) (ProgressiveResult, error) {
+	var res ProgressiveResult
+
+	if p.TargetSPP <= 0 && p.TimeBudget <= 0 && p.NoiseThreshold <= 0 {
+		return res, fmt.Errorf("progressive rendering needs at least one limit")
+	}
+
+	noiseEst, hasNoise := e.Dest.(NoiseEstimator)
+	if p.NoiseThreshold > 0 && !hasNoise {
+		return res, fmt.Errorf("destination does not support noise estimation")
+	}
+
+	if p.Resume != nil {
+		if err := e.restore(p.Resume); err != nil {
+			return res, err
+		}
+		res = p.Resume.Progress
+		res.StoppedBy = ""
+		e.log().Info("resuming rendering",
+			"passes", res.Passes,
+			"samplesPerPixel", res.SamplesPerPixel,
+		)
+	}
+
+	var interrupted atomic.Bool
+	if p.Interrupt != nil {
+		done := make(chan struct{})
+		defer close(done)
+
+		go func() {
+			select {
+			case <-p.Interrupt:
+				interrupted.Store(true)
+				e.Sampler.Stop()
+			case <-done:
+			}
+		}()
+	}
+
+	start := time.Now().Add(-res.Elapsed)
+	lastCheckpoint := time.Now()
+
+	// lastPass is the state at the end of the last whole pass. It is kept only
+	// when a pass may be interrupted, for checkpointing in place of the partial
+	// pass.
+	var lastPass *Checkpoint
+
+	for rendered := 0; ; rendered++ {
+		if p.CheckpointFile != "" && p.Interrupt != nil {
+			var err error
+			if lastPass, err = e.checkpoint(res); err != nil {
+				return res, err
+			}
+		}
+
+		if rendered > 0 {
+			e.Sampler.NextPass()
+		}
+
+		if e.Sampler.Converged() {
+			res.StoppedBy = "adaptive sampling convergence"
+			return res, e.saveCheckpoint(p, res)
+		}
+
+		e.Render()
+		if err := e.Err(); err != nil {
+			return res, err
+		}
+
+		if interrupted.Load() {
+			res.Elapsed = time.Since(start)
+			res.StoppedBy = "interrupt"
+			e.log().Info("pass interrupted",
+				"pass", res.Passes+1,
+				"samplesPerPixel", res.SamplesPerPixel,
+			)
+			if lastPass == nil {
+				return res, nil
+			}
+			lastPass.Progress.StoppedBy = res.StoppedBy
+			return res, e.writeCheckpoint(p, lastPass)
+		}
+
+		res.Passes++
+		res.SamplesPerPixel += e.Sampler.SamplesPerPixel()
+		res.Elapsed = time.Since(start)
+
+		var noisePixels int
+		if hasNoise {
+			res.Noise, noisePixels = noiseEst.Noise()
+		}
+
+		e.log().Info("pass done",
+			"pass", res.Passes,
+			"samplesPerPixel", res.SamplesPerPixel,
+			"noise", res.Noise,
+			"elapsed", res.Elapsed,
+		)
+
+		if p.OnPass != nil {
+			p.OnPass(res)
+		}
+
+		switch {
+		case p.TargetSPP > 0 && res.SamplesPerPixel >= p.TargetSPP:
+			res.StoppedBy = "target samples per pixel"
+		case p.TimeBudget > 0 && res.Elapsed >= p.TimeBudget:
+			res.StoppedBy = "time budget"
+		case p.NoiseThreshold > 0 && noisePixels > 0 && res.Noise <= p.NoiseThreshold:
+			res.StoppedBy = "noise threshold"
+		default:
+			if time.Since(lastCheckpoint) < p.CheckpointInterval {
+				continue
+			}
+			if err := e.saveCheckpoint(p, res); err != nil {
+				e.log().Error("cannot save checkpoint", "error", err)
+			}
+			lastCheckpoint = time.Now()
+			continue
+		}
+
+		return res, e.saveCheckpoint(p, res)
+	}
+}
+
+// saveCheckpoint writes the state of the rendering with progress `res` in the
+// checkpoint file of `p`, if it has one.
+func (e *Engine) saveCheckpoint(p Progressive, res ProgressiveResult) error {
+	if p.CheckpointFile == "" {
+		return nil
+	}
+
+	c, err := e.checkpoint(res)
+	if err != nil {
+		return err
+	}
+	return e.writeCheckpoint(p, c)
+}
+
+// writeCheckpoint writes `c` in the checkpoint file of `p`.
+func (e *Engine) writeCheckpoint(p Progressive, c *Checkpoint) error {
+	if err := c.Save(p.CheckpointFile); err != nil {
+		return fmt.Errorf("saving checkpoint: %w", err)
+	}
+
+	e.log().Info("checkpoint saved", "file", p.CheckpointFile)
+	return nil
+}
@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"encoding/json"
+	"io"
+	"runtime"
+)
+
+// RayCounts is the number of traced rays by type.
+type RayCounts struct {
+	Primary    uint64 `json:"primary"`
+	Shadow     uint64 `json:"shadow"`
+	Reflection uint64 `json:"reflection"`
+	Refraction uint64 `json:"refraction"`
+}
+
+// Report summarises the work done by an engine since its creation or the last
+// [Engine.ResetStats]. It is meant to be written as JSON at the end of rendering.
+// The ray, intersection, traversal and shading numbers are collected only when
+// [Engine.CollectStats] is set.
+type Report struct {
+	Frames        int     `json:"frames"`
+	RenderSeconds float64 `json:"renderSeconds"`
+
+	Rays RayCounts `json:"rays"`
+
+	// Intersections is the number of traced rays which hit a primitive.
+	Intersections uint64 `json:"intersections"`
+
+	NodesVisited   uint64 `json:"nodesVisited"`
+	BoxTests       uint64 `json:"boxTests"`
+	PrimitiveTests uint64 `json:"primitiveTests"`
+
+	// ShadingSeconds is the time spent shading the hits of primary rays,
+	// summed for all goroutines. It includes tracing the secondary rays.
+	ShadingSeconds float64 `json:"shadingSeconds"`
+
+	// BVHBuildSeconds is how long building the accelerator of the scene took.
+	BVHBuildSeconds float64 `json:"bvhBuildSeconds"`
+
+	Memory MemoryReport `json:"memory"`
+}
+
+// MemoryReport describes the memory used by the program. See [runtime.MemStats].
+type MemoryReport struct {
+	HeapAllocBytes  uint64 `json:"heapAllocBytes"`
+	TotalAllocBytes uint64 `json:"totalAllocBytes"`
+	SysBytes        uint64 `json:"sysBytes"`
+	NumGC           uint32 `json:"numGC"`
+}
+
+// Report returns the statistics of the engine. The memory numbers are taken at
+// the moment of the call.
+func (e *Engine) Report() Report {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	e.statsLock.Lock()
+	defer e.statsLock.Unlock()
+
+	r := Report{
+		Frames:         e.frames,
+		RenderSeconds:  e.renderTime.Seconds(),
+		Rays:           e.stats.rays,
+		Intersections:  e.stats.intersections,
+		NodesVisited:   e.stats.traversal.NodesVisited,
+		BoxTests:       e.stats.traversal.BoxTests,
+		PrimitiveTests: e.stats.traversal.PrimitiveTests,
+		ShadingSeconds: e.stats.shading.Seconds(),
+		Memory: MemoryReport{
+			HeapAllocBytes:  ms.HeapAlloc,
+			TotalAllocBytes: ms.TotalAlloc,
+			SysBytes:        ms.Sys,
+			NumGC:           ms.NumGC,
+		},
+	}
+	if e.Scene != nil {
+		r.BVHBuildSeconds = e.Scene.BuildTime().Seconds()
+	}
+	return r
+}
+
+// WriteJSON writes the report to `w` as indented JSON.
+func (r Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
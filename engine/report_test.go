@@ -0,0 +1,61 @@
+package engine_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/ironsmile/raytracer/engine"
+	"github.com/ironsmile/raytracer/sampler"
+)
+
+// TestRenderReport checks the statistics report of a rendering and that the
+// diagnostics go to the logger given to the engine.
+func TestRenderReport(t *testing.T) {
+	const width, height, spp = 32, 24, 2
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	tracer, _ := newTestEngine(t, width, height, sampler.Config{SamplesPerPixel: spp})
+	tracer.SetLogger(logger)
+	tracer.Scene.InitScene("teapot")
+	tracer.CollectStats = true
+
+	if _, err := tracer.RenderProgressive(engine.Progressive{TargetSPP: 2 * spp}); err != nil {
+		t.Fatalf("rendering failed: %s", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tracer.Report().WriteJSON(&buf); err != nil {
+		t.Fatalf("writing report: %s", err)
+	}
+
+	var r engine.Report
+	if err := json.Unmarshal(buf.Bytes(), &r); err != nil {
+		t.Fatalf("decoding report: %s", err)
+	}
+
+	if r.Frames != 2 {
+		t.Errorf("expected 2 frames but got %d", r.Frames)
+	}
+	if r.Rays.Primary != 2*width*height*spp {
+		t.Errorf("expected %d primary rays but got %d", 2*width*height*spp, r.Rays.Primary)
+	}
+	if r.Rays.Shadow == 0 || r.Intersections == 0 || r.NodesVisited == 0 {
+		t.Errorf("expected shadow rays, intersections and visited nodes in %+v", r)
+	}
+	if r.RenderSeconds <= 0 || r.ShadingSeconds <= 0 || r.BVHBuildSeconds <= 0 {
+		t.Errorf("expected render, shading and BVH build times in %+v", r)
+	}
+	if r.Memory.SysBytes == 0 {
+		t.Errorf("expected memory statistics in %+v", r)
+	}
+
+	for _, msg := range []string{`"msg":"BVH built"`, `"msg":"frame rendered"`, `"msg":"pass done"`} {
+		if !bytes.Contains(logs.Bytes(), []byte(msg)) {
+			t.Errorf("expected %s in the log:\n%s", msg, logs.String())
+		}
+	}
+}
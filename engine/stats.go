@@ -0,0 +1,174 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ironsmile/raytracer/accel"
+	"github.com/ironsmile/raytracer/geometry"
+	"github.com/ironsmile/raytracer/primitive"
+	"github.com/ironsmile/raytracer/utils"
+)
+
+// defaultHeatmapScale is the traversal cost which is painted with the hottest colour
+// in the heatmap when [Engine.HeatmapScale] is not set.
+const defaultHeatmapScale = 100
+
+// heatmapColors are the colour stops used for painting the traversal cost heatmap.
+// Cheap traversals are at the start of the list.
+var heatmapColors = [...][3]float64{
+	{0, 0, 0},
+	{0, 0, 1},
+	{0, 1, 1},
+	{0, 1, 0},
+	{1, 1, 0},
+	{1, 0, 0},
+}
+
+// renderStats is the work done by a single renderer goroutine. Its methods may be
+// called on nil, in which case they do nothing.
+type renderStats struct {
+	traversal     accel.TraversalStats
+	rays          RayCounts
+	intersections uint64
+	shading       time.Duration
+}
+
+// rayKind is the type of a traced ray. See [RayCounts].
+type rayKind int
+
+const (
+	rayPrimary rayKind = iota
+	rayShadow
+	rayReflection
+	rayRefraction
+)
+
+// countRay counts a traced ray of the given kind.
+func (st *renderStats) countRay(kind rayKind) {
+	if st == nil {
+		return
+	}
+
+	switch kind {
+	case rayPrimary:
+		st.rays.Primary++
+	case rayShadow:
+		st.rays.Shadow++
+	case rayReflection:
+		st.rays.Reflection++
+	case rayRefraction:
+		st.rays.Refraction++
+	}
+}
+
+// addShadingTime adds the time since `start` to the shading time.
+func (st *renderStats) addShadingTime(start time.Time) {
+	st.shading += time.Since(start)
+}
+
+func (st *renderStats) add(other *renderStats) {
+	st.traversal.Add(&other.traversal)
+	st.rays.Primary += other.rays.Primary
+	st.rays.Shadow += other.rays.Shadow
+	st.rays.Reflection += other.rays.Reflection
+	st.rays.Refraction += other.rays.Refraction
+	st.intersections += other.intersections
+	st.shading += other.shading
+}
+
+// Stats returns the accumulated traversal statistics since the engine creation or
+// the last call to [Engine.ResetStats]. Statistics are collected only when
+// [Engine.CollectStats] is set.
+func (e *Engine) Stats() accel.TraversalStats {
+	e.statsLock.Lock()
+	defer e.statsLock.Unlock()
+	return e.stats.traversal
+}
+
+// ResetStats zeroes out the accumulated statistics. See [Engine.Stats] and
+// [Engine.Report].
+func (e *Engine) ResetStats() {
+	e.statsLock.Lock()
+	defer e.statsLock.Unlock()
+	e.stats = renderStats{}
+	e.frames = 0
+	e.renderTime = 0
+}
+
+// PrintStats writes human readable traversal statistics to `w`.
+func (e *Engine) PrintStats(w io.Writer) {
+	st := e.Stats()
+	rays := float64(max(st.Rays, 1))
+
+	fmt.Fprintf(w, "Traversal statistics:\n")
+	fmt.Fprintf(w, "  rays cast:               %d\n", st.Rays)
+	fmt.Fprintf(w, "  nodes per ray:           %.3f\n", float64(st.NodesVisited)/rays)
+	fmt.Fprintf(w, "  box tests per ray:       %.3f\n", float64(st.BoxTests)/rays)
+	fmt.Fprintf(w, "  primitive tests per ray: %.3f\n", float64(st.PrimitiveTests)/rays)
+
+	hist := e.Scene.LeafOccupancy()
+	if hist == nil {
+		return
+	}
+
+	fmt.Fprintf(w, "  leaf occupancy:\n")
+	for prims, leaves := range hist {
+		if leaves == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "    %3d primitives: %d leaves\n", prims, leaves)
+	}
+}
+
+func (e *Engine) addStats(st *renderStats) {
+	e.statsLock.Lock()
+	defer e.statsLock.Unlock()
+	e.stats.add(st)
+}
+
+// heatmap returns the false colour for the traversal cost of the primary ray `ray`
+// and whether it hit anything. The work done is also added to `st` when it is not
+// nil.
+func (e *Engine) heatmap(
+	ray geometry.Ray,
+	in *primitive.Intersection,
+	st *renderStats,
+) (geometry.Color, bool) {
+	var rayStats accel.TraversalStats
+	hit := e.Scene.IntersectStats(ray, in, &rayStats)
+	if st != nil {
+		st.traversal.Add(&rayStats)
+		if hit {
+			st.intersections++
+		}
+	}
+
+	scale := e.HeatmapScale
+	if scale <= 0 {
+		scale = defaultHeatmapScale
+	}
+
+	return heatColor(float64(rayStats.Cost()) / scale), hit
+}
+
+// heatColor maps `t` in the range [0, 1] to a colour from `heatmapColors`. Values
+// outside of the range are clamped.
+func heatColor(t float64) geometry.Color {
+	t = utils.Clamp(t, 0, 1) * float64(len(heatmapColors)-1)
+	low := int(t)
+	if low >= len(heatmapColors)-1 {
+		c := heatmapColors[len(heatmapColors)-1]
+		return *geometry.NewColor(c[0], c[1], c[2])
+	}
+
+	frac := t - float64(low)
+	from, to := heatmapColors[low], heatmapColors[low+1]
+
+	return *geometry.NewColor(
+		utils.Lerp(frac, from[0], to[0]),
+		utils.Lerp(frac, from[1], to[1]),
+		utils.Lerp(frac, from[2], to[2]),
+	)
+}
@@ -2,6 +2,7 @@ package film
 
 import (
 	"fmt"
+	"math"
 	"os"
 	"runtime/trace"
 	"time"
@@ -13,8 +14,89 @@ import (
 var (
 	moveSpeed   = 3.0
 	rotateSpeed = 25.0
+
+	// lookSpeed is the rotation in degrees for every pixel the cursor moves
+	// while mouse-look is on.
+	lookSpeed = 0.15
+
+	// scrollFactor is the change of the movement and rotation speed for every
+	// step of the scroll wheel.
+	scrollFactor = 1.2
 )
 
+// mouseLook turns the camera when the cursor moves while the window has captured
+// it. Capturing is toggled with the M key and the scroll wheel changes the
+// movement speed.
+type mouseLook struct {
+	window *glfw.Window
+
+	captured     bool
+	mPressed     bool
+	lastX, lastY float64
+
+	// scroll is the scroll wheel offset since the last call of handle.
+	scroll float64
+}
+
+// newMouseLook sets up the scroll wheel of `window` for use with mouse-look.
+func newMouseLook(window *glfw.Window) *mouseLook {
+	m := &mouseLook{window: window}
+	window.SetScrollCallback(func(_ *glfw.Window, _, yoff float64) {
+		m.scroll += yoff
+	})
+	return m
+}
+
+// Captured tells whether the cursor is captured for mouse-look.
+func (m *mouseLook) Captured() bool {
+	return m.captured
+}
+
+// handle turns `cam` with the cursor movements since the last call. It returns
+// true when the camera was moved.
+func (m *mouseLook) handle(cam camera.Camera) bool {
+	if m.scroll != 0 {
+		factor := math.Pow(scrollFactor, m.scroll)
+		moveSpeed *= factor
+		rotateSpeed *= factor
+		m.scroll = 0
+	}
+
+	if !m.mPressed && m.window.GetKey(glfw.KeyM) == glfw.Press {
+		m.mPressed = true
+		m.captured = !m.captured
+
+		mode := glfw.CursorNormal
+		if m.captured {
+			mode = glfw.CursorDisabled
+		}
+		m.window.SetInputMode(glfw.CursorMode, mode)
+		if m.captured && glfw.RawMouseMotionSupported() {
+			m.window.SetInputMode(glfw.RawMouseMotion, glfw.True)
+		}
+		m.lastX, m.lastY = m.window.GetCursorPos()
+	}
+	if m.mPressed && m.window.GetKey(glfw.KeyM) == glfw.Release {
+		m.mPressed = false
+	}
+
+	if !m.captured {
+		return false
+	}
+
+	x, y := m.window.GetCursorPos()
+	dx, dy := x-m.lastX, y-m.lastY
+	m.lastX, m.lastY = x, y
+
+	if dx == 0 && dy == 0 {
+		return false
+	}
+
+	cam.Yaw(dx * lookSpeed)
+	cam.Pitch(dy * lookSpeed)
+	return true
+}
+
 func handleInteractionEvents(
 	window *glfw.Window,
 	cam camera.Camera,
@@ -63,6 +145,26 @@ func handleInteractionEvents(
 		cam.Yaw(rotateSpeed * dur.Seconds())
 		moved = true
 	}
+	if window.GetKey(glfw.KeyZ) == glfw.Press {
+		cam.Roll(rotateSpeed * dur.Seconds())
+		moved = true
+	}
+	if window.GetKey(glfw.KeyX) == glfw.Press {
+		cam.Roll(-rotateSpeed * dur.Seconds())
+		moved = true
+	}
+
+	// zoom controls. The field of view halves or doubles every second.
+	if window.GetKey(glfw.KeyR) == glfw.Press {
+		if cam.Zoom(math.Pow(2, dur.Seconds())) == nil {
+			moved = true
+		}
+	}
+	if window.GetKey(glfw.KeyF) == glfw.Press {
+		if cam.Zoom(math.Pow(2, -dur.Seconds())) == nil {
+			moved = true
+		}
+	}
 
 	// movement speed controls.
 	if window.GetKey(glfw.Key1) == glfw.Press {
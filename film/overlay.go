@@ -0,0 +1,180 @@
+package film
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"unicode/utf8"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/ironsmile/raytracer/camera"
+)
+
+const (
+	// overlayMargin is the distance in pixels between the overlay text and the
+	// corner of the image and between the text and the edges of its background.
+	overlayMargin = 6
+
+	// overlayShade is the fraction of the brightness of the image which is kept
+	// behind the overlay text.
+	overlayShade = 0.35
+)
+
+// overlay draws statistics about the rendering over the top left corner of the
+// presented frames. It is shown and hidden with the O key. The frames are drawn
+// on a copy so the film itself is never changed.
+type overlay struct {
+	window *glfw.Window
+	shown  bool
+	lines  []string
+	frame  []uint8
+
+	oPressed bool
+}
+
+// overlayStats are the values shown in the overlay.
+type overlayStats struct {
+	FPS     float64
+	SPP     int
+	Samples uint64
+	Pixels  int
+	Camera  camera.State
+
+	// Selected is the quoted name of the selected object or empty when nothing
+	// is selected.
+	Selected string
+
+	RenderMode string
+}
+
+// newOverlay returns an overlay which is initially visible when `shown` is true.
+func newOverlay(window *glfw.Window, shown bool) *overlay {
+	return &overlay{window: window, shown: shown}
+}
+
+// handle toggles the overlay with the O key. It returns true when it was toggled.
+func (o *overlay) handle() bool {
+	toggled := false
+	if !o.oPressed && o.window.GetKey(glfw.KeyO) == glfw.Press {
+		o.oPressed = true
+		o.shown = !o.shown
+		toggled = true
+	}
+	if o.oPressed && o.window.GetKey(glfw.KeyO) == glfw.Release {
+		o.oPressed = false
+	}
+	return toggled
+}
+
+// update sets the statistics shown in the overlay.
+func (o *overlay) update(st overlayStats) {
+	o.lines = st.lines()
+}
+
+// lines returns the text of the overlay, one line for every statistic.
+func (st overlayStats) lines() []string {
+	perPixel := 0.0
+	if st.Pixels > 0 {
+		perPixel = float64(st.Samples) / float64(st.Pixels)
+	}
+
+	selected := st.Selected
+	if selected == "" {
+		selected = "none"
+	}
+
+	cam := st.Camera
+	dir := cam.LookAt.Minus(cam.Position).Normalize()
+	view := fmt.Sprintf("Looking: %.2f, %.2f, %.2f", dir.X, dir.Y, dir.Z)
+	if cam.FOV != 0 {
+		view += fmt.Sprintf(" FOV: %.1f", cam.FOV)
+	}
+
+	return []string{
+		fmt.Sprintf("FPS: %.1f", st.FPS),
+		fmt.Sprintf("SPP per frame: %d", st.SPP),
+		fmt.Sprintf("Samples: %d (%.1f per pixel)", st.Samples, perPixel),
+		fmt.Sprintf("Camera: %s at %.2f, %.2f, %.2f",
+			cam.Projection, cam.Position.X, cam.Position.Y, cam.Position.Z),
+		view,
+		"Selected: " + selected,
+		"Mode: " + st.RenderMode,
+	}
+}
+
+// draw returns `frame` with the overlay drawn over it when the overlay is shown.
+// The frame is in RGBA format and has the given width in pixels.
+func (o *overlay) draw(frame []uint8, width int) []uint8 {
+	if !o.shown || len(o.lines) == 0 || width <= 0 {
+		return frame
+	}
+
+	if len(o.frame) != len(frame) {
+		o.frame = make([]uint8, len(frame))
+	}
+	copy(o.frame, frame)
+
+	img := &image.RGBA{
+		Pix:    o.frame,
+		Stride: width * 4,
+		Rect:   image.Rect(0, 0, width, len(frame)/(width*4)),
+	}
+	drawText(img, o.lines)
+
+	return o.frame
+}
+
+// drawText writes `lines` in the top left corner of `img` over a darkened
+// background.
+func drawText(img *image.RGBA, lines []string) {
+	var textWidth int
+	for _, line := range lines {
+		textWidth = max(textWidth, utf8.RuneCountInString(line)*glyphAdvance)
+	}
+
+	background := image.Rect(
+		overlayMargin,
+		overlayMargin,
+		3*overlayMargin+textWidth,
+		3*overlayMargin+len(lines)*glyphHeight,
+	).Intersect(img.Rect)
+	shade(img, background)
+
+	for i, line := range lines {
+		x, y := 2*overlayMargin, 2*overlayMargin+i*glyphHeight
+		for _, r := range line {
+			drawGlyph(img, glyph(r), x, y)
+			x += glyphAdvance
+		}
+	}
+}
+
+// drawGlyph draws `g` in white with its top left corner at (x, y). The parts of it
+// outside of `img` are skipped.
+func drawGlyph(img *image.RGBA, g *[glyphHeight]uint8, x, y int) {
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	for row, bits := range g {
+		for col := range 8 {
+			if bits&(0x80>>col) == 0 {
+				continue
+			}
+			if p := image.Pt(x+col, y+row); p.In(img.Rect) {
+				img.SetRGBA(p.X, p.Y, white)
+			}
+		}
+	}
+}
+
+// shade darkens the pixels of `img` in `rect`.
+func shade(img *image.RGBA, rect image.Rectangle) {
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		row := img.Pix[img.PixOffset(rect.Min.X, y):img.PixOffset(rect.Max.X, y)]
+		for i := range row {
+			if i%4 == 3 {
+				row[i] = 0xff
+				continue
+			}
+			row[i] = uint8(float64(row[i]) * overlayShade)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package film
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+// TestOverlayDraw checks that the overlay writes its text in the top left corner
+// of a copy of the frame and leaves the frame itself unchanged.
+func TestOverlayDraw(t *testing.T) {
+	const width, height = 320, 200
+
+	frame := bytes.Repeat([]uint8{100, 150, 200, 255}, width*height)
+	orig := bytes.Clone(frame)
+
+	o := &overlay{}
+	o.update(overlayStats{FPS: 60, SPP: 4, Samples: 1000, Pixels: width * height})
+	if drawn := o.draw(frame, width); &drawn[0] != &frame[0] {
+		t.Fatalf("the frame was changed while the overlay is hidden")
+	}
+
+	o.shown = true
+	drawn := o.draw(frame, width)
+	if !bytes.Equal(frame, orig) {
+		t.Fatalf("the overlay was drawn in the frame itself")
+	}
+
+	img := &image.RGBA{Pix: drawn, Stride: width * 4, Rect: image.Rect(0, 0, width, height)}
+
+	maxY := 3*overlayMargin + len(o.lines)*glyphHeight
+
+	var white int
+	for y := range height {
+		for x := range width {
+			c := img.RGBAAt(x, y)
+			if c.R == 0xff && c.G == 0xff && c.B == 0xff {
+				white++
+				if x < overlayMargin || y < overlayMargin || y > maxY {
+					t.Fatalf("text at (%d, %d) is not in the top left corner", x, y)
+				}
+			}
+		}
+	}
+	if white == 0 {
+		t.Errorf("no text was drawn")
+	}
+
+	if c := img.RGBAAt(width-1, height-1); c.R != 100 || c.G != 150 || c.B != 200 {
+		t.Errorf("the corner away from the overlay changed to %v", c)
+	}
+	if c := img.RGBAAt(overlayMargin+1, overlayMargin+1); c.R >= 100 {
+		t.Errorf("the background of the text is not darkened: %v", c)
+	}
+}
@@ -0,0 +1,132 @@
+package film
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/ironsmile/raytracer/camera"
+	"github.com/ironsmile/raytracer/scene"
+)
+
+// cameraPath records the movement of the camera in the window and plays it back.
+// Recording is started and stopped with the K key and the path is saved as an
+// animation file. The P key plays the last recorded path or, before recording
+// any, the path given in the window arguments.
+type cameraPath struct {
+	window   *glfw.Window
+	fps      float64
+	filename string
+
+	recorder *scene.CameraRecorder
+	path     *scene.Animation
+	playing  bool
+	start    time.Time
+
+	kPressed, pPressed bool
+}
+
+// newCameraPath returns a camera path which is saved in `filename` and may play
+// `path` before anything is recorded.
+func newCameraPath(
+	window *glfw.Window,
+	path *scene.Animation,
+	filename string,
+	fps float64,
+) *cameraPath {
+	if fps <= 0 {
+		fps = scene.DefaultRecordFPS
+	}
+
+	return &cameraPath{
+		window:   window,
+		fps:      fps,
+		filename: filename,
+		path:     path,
+	}
+}
+
+// handle records or plays the movement of `cam` and toggles them with the keys.
+// It returns true when the camera was moved.
+func (p *cameraPath) handle(cam camera.Camera) bool {
+	if !p.kPressed && p.window.GetKey(glfw.KeyK) == glfw.Press {
+		p.kPressed = true
+		p.toggleRecording(cam)
+	}
+	if p.kPressed && p.window.GetKey(glfw.KeyK) == glfw.Release {
+		p.kPressed = false
+	}
+
+	if !p.pPressed && p.window.GetKey(glfw.KeyP) == glfw.Press {
+		p.pPressed = true
+		p.togglePlaying()
+	}
+	if p.pPressed && p.window.GetKey(glfw.KeyP) == glfw.Release {
+		p.pPressed = false
+	}
+
+	if p.recorder != nil {
+		p.recorder.Record(time.Since(p.start), cam.State())
+		return false
+	}
+
+	if !p.playing {
+		return false
+	}
+
+	frame := time.Since(p.start).Seconds() * p.fps
+	if err := cam.SetState(p.path.CameraStateAt(frame)); err != nil {
+		fmt.Printf("\nStopped playing the camera path: %s\n", err)
+		p.playing = false
+		return false
+	}
+
+	if frame > p.path.CameraEnd() {
+		fmt.Printf("\nCamera path finished\n")
+		p.playing = false
+	}
+	return true
+}
+
+// toggleRecording starts recording or stops it and saves the recorded path.
+func (p *cameraPath) toggleRecording(cam camera.Camera) {
+	if p.recorder == nil {
+		p.playing = false
+		p.recorder = scene.NewCameraRecorder(p.fps)
+		p.start = time.Now()
+		p.recorder.Record(0, cam.State())
+		fmt.Printf("\nRecording the camera path\n")
+		return
+	}
+
+	p.recorder.Record(time.Since(p.start), cam.State())
+	p.path = p.recorder.Animation()
+	frames := p.recorder.Frames()
+	p.recorder = nil
+
+	if err := p.path.Save(p.filename); err != nil {
+		fmt.Printf("\nError saving the camera path: %s\n", err)
+		return
+	}
+	fmt.Printf("\nCamera path with %.0f frames saved in %s\n", frames, p.filename)
+}
+
+// togglePlaying starts or stops playing the camera path.
+func (p *cameraPath) togglePlaying() {
+	if p.recorder != nil {
+		return
+	}
+
+	if p.playing {
+		p.playing = false
+		return
+	}
+
+	if p.path == nil {
+		fmt.Printf("\nThere is no camera path to play\n")
+		return
+	}
+
+	p.playing = true
+	p.start = time.Now()
+}
@@ -0,0 +1,51 @@
+package film
+
+import (
+	"time"
+
+	"github.com/ironsmile/raytracer/scene"
+	"github.com/ironsmile/raytracer/utils"
+)
+
+// hotReloadInterval is how often the files of the scene are checked for changes.
+const hotReloadInterval = 500 * time.Millisecond
+
+// watchScene reloads `scn` every time some of its files change and sends the
+// reloaded scene to `reloaded`. It returns when `done` is closed. See
+// [scene.Scene.Files] for the watched files.
+func watchScene(scn *scene.Scene, reloaded chan<- *scene.Scene, done <-chan struct{}) {
+	log := utils.Logger(scn.Logger)
+	files := scn.Files()
+	watcher := scene.NewFileWatcher(files)
+	log.Info("watching the scene files", "files", files)
+
+	ticker := time.NewTicker(hotReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		}
+
+		changed := watcher.Changed()
+		if len(changed) == 0 {
+			continue
+		}
+
+		log.Info("reloading the scene", "files", changed)
+		newScn, err := scn.Reload(changed)
+		if err != nil {
+			log.Error("cannot reload the scene", "files", changed, "error", err)
+			continue
+		}
+
+		select {
+		case reloaded <- newScn:
+			scn = newScn
+		case <-done:
+			return
+		}
+	}
+}
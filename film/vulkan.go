@@ -3,15 +3,19 @@ package film
 import (
     "cmp"
     "fmt"
+    "log/slog"
     "math"
     "slices"
     "time"
     "unsafe"
 
+    "github.com/ironsmile/raytracer/aov"
     "github.com/ironsmile/raytracer/camera"
     "github.com/ironsmile/raytracer/engine"
     "github.com/ironsmile/raytracer/film/shaders"
+    "github.com/ironsmile/raytracer/imagefilm"
     "github.com/ironsmile/raytracer/optional"
+    "github.com/ironsmile/raytracer/primitive"
     "github.com/ironsmile/raytracer/sampler"
     "github.com/ironsmile/raytracer/scene"
     "github.com/ironsmile/raytracer/unsafer"
@@ -30,6 +34,39 @@ type VulkanAppArgs struct {
     FPSCap      uint
     ShowFPS     bool
     SceneName   string
+    RenderMode  engine.RenderMode
+    UsePackets  bool
+    Sampler     sampler.Config
+
+    // Camera is the initial state of the camera. The camera of the demo scenes
+    // is used when it is nil.
+    Camera *camera.State
+
+    // CameraPath is the path played with the P key before recording any. See
+    // [scene.CameraRecorder].
+    CameraPath *scene.Animation
+
+    // RecordFile is the file in which the camera paths recorded with the K key
+    // are saved. They have PathFPS frames for every second.
+    RecordFile string
+    PathFPS    float64
+
+    // Preview is the side in pixels of the blocks in the low resolution preview
+    // shown after the camera moves in interactive mode. Values below 2 disable
+    // it. See [sampler.SimpleSampler.SetPreview].
+    Preview int
+
+    // HotReload makes the window watch the model files of the scene and render
+    // it again after they change. Only models are reloaded. See
+    // [scene.Scene.Reload].
+    HotReload bool
+
+    // Overlay makes the statistics overlay shown from the start. It is toggled
+    // with the O key.
+    Overlay bool
+
+    // Denoise makes every presented frame denoised. See [imagefilm.Denoiser].
+    Denoise bool
 
     // Debug causes few additional diagnostics messages to be printed while working.
     Debug bool
@@ -38,6 +75,10 @@ type VulkanAppArgs struct {
 const (
     title             = "Raytracer"
     maxFramesInFlight = 2
+
+    // convergedFrames is the number of full resolution frames accumulated after
+    // the last change in interactive mode before tracing pauses.
+    convergedFrames = 32
 )
 
 func NewVulkanWindow(args VulkanAppArgs) *VulkanApp {
@@ -113,6 +154,9 @@ type VulkanApp struct {
     tracer  *engine.FPSEngine
     cam     camera.Camera
 
+    // overlay shows statistics over the presented frames.
+    overlay *overlay
+
     // Copying film to GPU memory stuff
     filmStagingBuffer       vk.Buffer
     filmStagingBufferMemory vk.DeviceMemory
@@ -177,6 +221,7 @@ func (a *VulkanApp) initWindow() error {
     window.SetFramebufferSizeCallback(a.frameBufferResizeCallback)
 
     a.window = window
+    a.overlay = newOverlay(window, a.args.Overlay)
     return nil
 }
 
@@ -1178,6 +1223,7 @@ func (a *VulkanApp) createFilmImage() error {
     texHeight := uint32(a.swapChainExtend.Height)
 
     a.film = newVulkanFilm(texWidth, texHeight)
+    a.film.logger = slog.Default()
 
     imgSize := vk.DeviceSize(a.film.getBufferSize())
     a.filmImageFormat = a.film.getFormat()
@@ -1282,7 +1328,7 @@ func (a *VulkanApp) recreateFilmImage() error {
 }
 
 func (a *VulkanApp) copyFilmToGPUImage() error {
-    filmBytes := a.film.asVkBuffer()
+    filmBytes := a.overlay.draw(a.film.asVkBuffer(), a.film.Width())
 
     // copy data to the staging buffer
     vk.Memcopy(a.filmBufferData, filmBytes)
@@ -1856,17 +1902,31 @@ func (a *VulkanApp) createShaderModule(code []byte) (vk.ShaderModule, error) {
 func (a *VulkanApp) initEngine() error {
     width, height := a.swapChainExtend.Width, a.swapChainExtend.Height
 
-    smpl := sampler.NewSimple(int(width), int(height), a.film)
+    smpl := sampler.NewSimple(int(width), int(height), a.film, a.args.Sampler)
 
     if a.args.Interactive {
         smpl.MakeContinuous()
+        smpl.SetPreview(a.args.Preview)
     }
 
     cam := scene.GetCamera(float64(width), float64(height))
+    if a.args.Camera != nil {
+        var err error
+        cam, err = camera.New(a.args.Camera.Options(float64(width), float64(height)))
+        if err != nil {
+            return fmt.Errorf("creating camera: %w", err)
+        }
+    }
 
     tracer := engine.NewFPS(smpl)
+    tracer.SetLogger(slog.Default())
     tracer.SetTarget(a.film, cam)
     tracer.ShowBBoxes = a.args.ShowBBoxes
+    tracer.Mode = a.args.RenderMode
+    tracer.UsePackets = a.args.UsePackets
+    if err := a.setupDenoiser(&tracer.Engine); err != nil {
+        return err
+    }
 
     fmt.Printf("Loading scene...\n")
     loadingStart := time.Now()
@@ -1880,7 +1940,9 @@ func (a *VulkanApp) initEngine() error {
     return nil
 }
 
-func (a *VulkanApp) recreateEngine() error {
+// recreateEngine replaces the engine with a new one which renders `scn` on the
+// current film.
+func (a *VulkanApp) recreateEngine(scn *scene.Scene) error {
     if a.args.Debug {
         fmt.Println("recreating engine")
     }
@@ -1888,9 +1950,10 @@ func (a *VulkanApp) recreateEngine() error {
     a.cleanEngine()
 
     width, height := a.swapChainExtend.Width, a.swapChainExtend.Height
-    smpl := sampler.NewSimple(int(width), int(height), a.film)
+    smpl := sampler.NewSimple(int(width), int(height), a.film, a.args.Sampler)
     if a.args.Interactive {
         smpl.MakeContinuous()
+        smpl.SetPreview(a.args.Preview)
     }
 
     if a.args.Debug {
@@ -1898,9 +1961,16 @@ func (a *VulkanApp) recreateEngine() error {
     }
 
     tracer := engine.NewFPS(smpl)
+    tracer.SetLogger(slog.Default())
     tracer.SetTarget(a.film, a.cam)
-    tracer.ShowBBoxes = a.args.ShowBBoxes
-    tracer.Scene = a.tracer.Scene
+    tracer.ShowBBoxes = a.tracer.ShowBBoxes
+    tracer.Mode = a.tracer.Mode
+    tracer.UsePackets = a.tracer.UsePackets
+    tracer.Scene = scn
+    tracer.Select(a.tracer.Selected())
+    if err := a.setupDenoiser(&tracer.Engine); err != nil {
+        return err
+    }
 
     a.sampler = smpl
     a.tracer = tracer
@@ -1913,6 +1983,65 @@ func (a *VulkanApp) recreateEngine() error {
     return nil
 }
 
+// pick prints what is seen under the cursor and highlights its bounding box.
+// Clicking on nothing removes the highlight.
+func (a *VulkanApp) pick() {
+    winW, winH := a.window.GetSize()
+    if winW == 0 || winH == 0 {
+        return
+    }
+
+    // The cursor position is in screen coordinates which may differ from the
+    // pixels of the film on high DPI displays.
+    x, y := a.window.GetCursorPos()
+    x *= float64(a.swapChainExtend.Width) / float64(winW)
+    y *= float64(a.swapChainExtend.Height) / float64(winH)
+
+    res, ok := a.tracer.Pick(x, y)
+    if !ok {
+        fmt.Printf("\nNothing at %.0f, %.0f\n", x, y)
+        a.tracer.Select(nil)
+        return
+    }
+
+    fmt.Printf("\nPicked %s\n", res)
+    a.tracer.Select(res.Primitive)
+}
+
+// overlayStats returns the current statistics shown in the overlay.
+func (a *VulkanApp) overlayStats(fps float64) overlayStats {
+    st := overlayStats{
+        FPS:        fps,
+        SPP:        a.sampler.SamplesPerPixel(),
+        Samples:    a.film.TotalSamples(),
+        Pixels:     a.film.Width() * a.film.Height(),
+        Camera:     a.cam.State(),
+        RenderMode: a.tracer.Mode.String(),
+    }
+    if sel := a.tracer.Selected(); sel != nil {
+        st.Selected = fmt.Sprintf("%q", primitive.GetName(sel.GetID()))
+    }
+    return st
+}
+
+// setupDenoiser makes `tracer` collect the output variables needed for denoising
+// and the film denoise every presented frame with them. It does nothing unless
+// denoising is enabled.
+func (a *VulkanApp) setupDenoiser(tracer *engine.Engine) error {
+    if !a.args.Denoise {
+        return nil
+    }
+
+    tracer.AOVs = aov.NewBuffers(a.film.Width(), a.film.Height(), imagefilm.DenoiserGuides)
+    denoiser, err := imagefilm.NewDenoiser(tracer.AOVs)
+    if err != nil {
+        return fmt.Errorf("creating denoiser: %w", err)
+    }
+    a.film.denoiser = denoiser
+
+    return nil
+}
+
 func (a *VulkanApp) cleanEngine() {
     a.sampler.Resume()
     a.sampler.Stop()
@@ -1926,17 +2055,47 @@ func (a *VulkanApp) mainLoop() error {
     var (
         traceStarted bool
         bPressed     bool
+        hPressed     bool
+        cPressed     bool
+        clicked      bool
+        mouse        = newMouseLook(a.window)
+        path         = newCameraPath(a.window, a.args.CameraPath, a.args.RecordFile, a.args.PathFPS)
+
+        reloaded = make(chan *scene.Scene)
+        done     = make(chan struct{})
 
         frameCounter uint64
+        fps          float64
         lastShowFPS  = time.Now()
 
-        // When `dirty` is "false" after a full raytraced frame is completed then tracing
-        // could stop for a bit and wit for some movement before continuing.
+        // When `dirty` is "false" after enough raytraced frames are accumulated then
+        // tracing could stop for a bit and wit for some movement before continuing.
         dirty     bool = true
         prevDrity bool
     )
 
+    defer close(done)
+    if a.args.HotReload {
+        go watchScene(a.tracer.Scene, reloaded, done)
+    }
+
     for !a.window.ShouldClose() {
+        select {
+        case scn := <-reloaded:
+            // The old engine stops rendering and is replaced with one for the
+            // reloaded scene. The samples of the old scene are discarded.
+            a.tracer.Pause()
+            old := a.tracer.Scene
+            if err := a.recreateEngine(scn); err != nil {
+                return fmt.Errorf("recreating engine with the reloaded scene: %w", err)
+            }
+            old.ReleaseReplaced(scn)
+            a.film.reset()
+            a.tracer.Resume()
+            dirty = true
+        default:
+        }
+
         renderStart := time.Now()
         err := a.drawFrame()
         if err != nil {
@@ -1950,6 +2109,25 @@ func (a *VulkanApp) mainLoop() error {
                 dirty = true
             }
 
+            if mouse.handle(a.cam) {
+                dirty = true
+            }
+
+            if path.handle(a.cam) {
+                dirty = true
+            }
+
+            leftButton := a.window.GetMouseButton(glfw.MouseButtonLeft)
+            if !clicked && leftButton == glfw.Press && !mouse.Captured() {
+                a.pick()
+                clicked = true
+                dirty = true
+            }
+
+            if clicked && leftButton == glfw.Release {
+                clicked = false
+            }
+
             if !bPressed && a.window.GetKey(glfw.KeyB) == glfw.Press {
                 a.tracer.ShowBBoxes = !a.tracer.ShowBBoxes
                 bPressed = true
@@ -1961,6 +2139,30 @@ func (a *VulkanApp) mainLoop() error {
                 dirty = true
             }
 
+            if !hPressed && a.window.GetKey(glfw.KeyH) == glfw.Press {
+                if a.tracer.Mode == engine.RenderHeatmap {
+                    a.tracer.Mode = engine.RenderShaded
+                } else {
+                    a.tracer.Mode = engine.RenderHeatmap
+                }
+                hPressed = true
+                dirty = true
+            }
+
+            if hPressed && a.window.GetKey(glfw.KeyH) == glfw.Release {
+                hPressed = false
+                dirty = true
+            }
+
+            if !cPressed && a.window.GetKey(glfw.KeyC) == glfw.Press {
+                fmt.Printf("\nCamera state: %s\n", a.cam.State())
+                cPressed = true
+            }
+
+            if cPressed && a.window.GetKey(glfw.KeyC) == glfw.Release {
+                cPressed = false
+            }
+
             if !traceStarted && a.window.GetKey(glfw.KeyT) == glfw.Press {
                 dirty = true
                 traceStarted = true
@@ -1977,33 +2179,40 @@ func (a *VulkanApp) mainLoop() error {
             time.Sleep(minFrameTime - elapsed)
         }
 
-        if a.args.ShowFPS {
-            frameCounter++
-            now := time.Now()
-            elapsed = now.Sub(lastShowFPS)
+        frameCounter++
+        elapsed = time.Since(lastShowFPS)
 
-            if elapsed > time.Second {
-                fps := float64(frameCounter) / elapsed.Seconds()
+        if elapsed > time.Second {
+            fps = float64(frameCounter) / elapsed.Seconds()
+            if a.args.ShowFPS {
                 fmt.Printf("\r                                                               ")
                 fmt.Printf("\rFPS: %5.3f Render time: %8s Last frame: %12s",
                     fps, renderTime, a.film.FrameTime(),
                 )
-
-                frameCounter = 0
-                lastShowFPS = time.Now()
             }
+
+            frameCounter = 0
+            lastShowFPS = time.Now()
+        }
+
+        a.overlay.handle()
+        if a.overlay.shown {
+            a.overlay.update(a.overlayStats(fps))
         }
 
         if dirty {
+            if a.args.Interactive {
+                // The image is rendered again from a low resolution preview.
+                a.sampler.Restart()
+                a.film.reset()
+            }
             if !prevDrity {
                 a.tracer.Resume()
             }
             prevDrity = dirty
             dirty = false
-        } else {
-            if prevDrity {
-                a.tracer.Pause()
-            }
+        } else if prevDrity && (!a.args.Interactive || a.sampler.Frames() >= convergedFrames) {
+            a.tracer.Pause()
             prevDrity = dirty
         }
     }
@@ -2038,7 +2247,7 @@ func (a *VulkanApp) drawFrame() error {
         if err := a.recreateFilmImage(); err != nil {
             return fmt.Errorf("recreating film image: %w", err)
         }
-        if err := a.recreateEngine(); err != nil {
+        if err := a.recreateEngine(a.tracer.Scene); err != nil {
             return fmt.Errorf("recreating engine: %w", err)
         }
         return nil
@@ -2105,7 +2314,7 @@ func (a *VulkanApp) drawFrame() error {
         if err := a.recreateFilmImage(); err != nil {
             return fmt.Errorf("recreating film image: %w", err)
         }
-        if err := a.recreateEngine(); err != nil {
+        if err := a.recreateEngine(a.tracer.Scene); err != nil {
             return fmt.Errorf("recreating engine: %w", err)
         }
     } else if res != vk.Success {
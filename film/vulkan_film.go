@@ -1,16 +1,31 @@
 package film
 
 import (
+	"image"
 	"image/color"
+	"log/slog"
 	"sync"
 	"time"
 
+	"github.com/ironsmile/raytracer/imagefilm"
+	"github.com/ironsmile/raytracer/utils"
 	vk "github.com/vulkan-go/vulkan"
 )
 
 type vulkanFilm struct {
-	pixBuffer  []uint8
-	pixSamples []uint16
+	pixBuffer []uint8
+
+	// PixelStats holds the running mean and variance of the samples for every
+	// pixel since the last reset. See [vulkanFilm.reset].
+	imagefilm.PixelStats
+
+	// denoiser is applied to every presented frame when not nil. The denoised
+	// frame is written in denoised. It is removed after the first error.
+	denoiser *imagefilm.Denoiser
+	denoised []uint8
+
+	// logger receives the errors of denoising. Nothing is logged when it is nil.
+	logger *slog.Logger
 
 	pixBufferFormat vk.Format
 
@@ -27,7 +42,7 @@ func newVulkanFilm(width, height uint32) *vulkanFilm {
 		width:           width,
 		height:          height,
 		pixBuffer:       make([]uint8, width*height*4),
-		pixSamples:      make([]uint16, width*height),
+		PixelStats:      imagefilm.NewPixelStats(int(width), int(height)),
 		pixBufferFormat: vk.FormatR8g8b8a8Srgb,
 
 		frameTimeLock: &sync.RWMutex{},
@@ -35,24 +50,35 @@ func newVulkanFilm(width, height uint32) *vulkanFilm {
 }
 
 func (f *vulkanFilm) Set(x int, y int, clr color.Color) error {
-	sampleInd := f.width*uint32(y) + uint32(x)
-	samples := f.pixSamples[sampleInd]
-
-	oldWeight := float32(samples) / float32(samples+1)
-	newWeight := 1 - oldWeight
+	r, g, b := f.Add(x, y, clr)
+	f.setPixel(x, y, r, g, b)
+	return nil
+}
 
-	ri, gi, bi, _ := clr.RGBA()
+// SetBlock records the sample for the pixel at (x, y) and shows its colour in the
+// pixels of `block` which have no samples of their own yet. It implements
+// [sampler.BlockOutput] for the preview frames.
+func (f *vulkanFilm) SetBlock(x, y int, block image.Rectangle, clr color.Color) error {
+	r, g, b := f.Add(x, y, clr)
+
+	for py := block.Min.Y; py < block.Max.Y; py++ {
+		for px := block.Min.X; px < block.Max.X; px++ {
+			if (px != x || py != y) && f.Samples(px, py) > 0 {
+				continue
+			}
+			f.setPixel(px, py, r, g, b)
+		}
+	}
 
-	nc := (newWeight * 255) / 0xffff
+	return nil
+}
 
+// setPixel writes the colour of the pixel at (x, y) in the pixel buffer.
+func (f *vulkanFilm) setPixel(x, y int, r, g, b float64) {
 	ind := f.width*uint32(y)*4 + uint32(x)*4
-	f.pixBuffer[ind] = uint8(float32(f.pixBuffer[ind])*oldWeight + float32(ri)*nc)
-	f.pixBuffer[ind+1] = uint8(float32(f.pixBuffer[ind+1])*oldWeight + float32(gi)*nc)
-	f.pixBuffer[ind+2] = uint8(float32(f.pixBuffer[ind+2])*oldWeight + float32(bi)*nc)
-
-	f.pixSamples[sampleInd]++
-
-	return nil
+	f.pixBuffer[ind] = uint8(min(r, 1) * 255)
+	f.pixBuffer[ind+1] = uint8(min(g, 1) * 255)
+	f.pixBuffer[ind+2] = uint8(min(b, 1) * 255)
 }
 
 func (f *vulkanFilm) DoneFrame() {
@@ -63,8 +89,14 @@ func (f *vulkanFilm) DoneFrame() {
 
 func (f *vulkanFilm) StartFrame() {
 	f.frameStart = time.Now()
-	for i := 0; i < len(f.pixSamples); i++ {
-		f.pixSamples[i] = 0
+}
+
+// reset discards the accumulated samples. The pixels keep showing their colours
+// until they are sampled again.
+func (f *vulkanFilm) reset() {
+	f.PixelStats.Reset()
+	if f.denoiser != nil {
+		f.denoiser.ResetGuide()
 	}
 }
 
@@ -90,9 +122,37 @@ func (f *vulkanFilm) Wait() {
 // for copying in a buffer for a Vulkan Image.
 //
 // This function uses the pixel buffer data "in-place" where possible in order
-// to avoid copying.
+// to avoid copying. With a denoiser the frame is denoised in a separate buffer
+// which is returned instead. The denoiser is disabled when it fails so that the
+// error is logged only once.
 func (f *vulkanFilm) asVkBuffer() []byte {
-	return f.pixBuffer
+	if f.denoiser == nil {
+		return f.pixBuffer
+	}
+
+	if f.denoised == nil {
+		f.denoised = make([]uint8, len(f.pixBuffer))
+	}
+
+	rgb, err := f.denoiser.Denoise(&f.PixelStats)
+	if err != nil {
+		utils.Logger(f.logger).Error("cannot denoise frame, denoising is disabled",
+			"error", err)
+		f.denoiser = nil
+		return f.pixBuffer
+	}
+	for ind := range len(f.pixBuffer) / 4 {
+		if f.Samples(ind%int(f.width), ind/int(f.width)) == 0 {
+			copy(f.denoised[ind*4:ind*4+4], f.pixBuffer[ind*4:ind*4+4])
+			continue
+		}
+		f.denoised[ind*4] = uint8(min(rgb[ind*3], 1) * 255)
+		f.denoised[ind*4+1] = uint8(min(rgb[ind*3+1], 1) * 255)
+		f.denoised[ind*4+2] = uint8(min(rgb[ind*3+2], 1) * 255)
+		f.denoised[ind*4+3] = f.pixBuffer[ind*4+3]
+	}
+
+	return f.denoised
 }
 
 func (f *vulkanFilm) getFormat() vk.Format {
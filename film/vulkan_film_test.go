@@ -0,0 +1,38 @@
+package film
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/ironsmile/raytracer/aov"
+	"github.com/ironsmile/raytracer/imagefilm"
+)
+
+// TestVulkanFilmDenoiserError checks that a failing denoiser is logged once and
+// then disabled so that the frames are presented without it.
+func TestVulkanFilmDenoiserError(t *testing.T) {
+	denoiser, err := imagefilm.NewDenoiser(aov.NewBuffers(2, 2, imagefilm.DenoiserGuides))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var logs bytes.Buffer
+	f := newVulkanFilm(4, 4)
+	f.denoiser = denoiser
+	f.logger = slog.New(slog.NewTextHandler(&logs, nil))
+
+	for range 3 {
+		if buf := f.asVkBuffer(); &buf[0] != &f.pixBuffer[0] {
+			t.Fatalf("expected the frame without denoising")
+		}
+	}
+
+	if f.denoiser != nil {
+		t.Errorf("the failing denoiser was not disabled")
+	}
+	if n := bytes.Count(logs.Bytes(), []byte("cannot denoise")); n != 1 {
+		t.Errorf("expected the error logged once but it was logged %d times:\n%s",
+			n, logs.String())
+	}
+}
@@ -0,0 +1,81 @@
+package geometry
+
+import "math"
+
+// MachineEpsilon is the maximum relative error of rounding a real number to the
+// nearest float64. It is half the difference between 1 and the next float64.
+const MachineEpsilon = 0x1p-53
+
+// ShadowEpsilon is the fraction of the distance to a target which is cut off the
+// end of rays spawned toward that target. It keeps such rays from hitting the
+// surface they are aimed at.
+const ShadowEpsilon = 0.0001
+
+// Gamma returns the bound on the relative error accumulated by `n` consecutive
+// floating point operations. It is the γn term from "Physically Based Rendering",
+// section 3.9.
+func Gamma(n int) float64 {
+	return (float64(n) * MachineEpsilon) / (1 - float64(n)*MachineEpsilon)
+}
+
+// NextFloatUp returns the smallest float64 which is greater than `v`.
+func NextFloatUp(v float64) float64 {
+	return math.Nextafter(v, math.Inf(1))
+}
+
+// NextFloatDown returns the biggest float64 which is less than `v`.
+func NextFloatDown(v float64) float64 {
+	return math.Nextafter(v, math.Inf(-1))
+}
+
+// OffsetRayOrigin returns a point which is safe to use as an origin for rays with
+// direction `w` leaving a surface at `p`. The surface has normal `n` and `pError`
+// is the absolute error of each coordinate of `p`. The returned point is moved
+// along the normal just enough to be on the same side of the surface as `w`, no
+// matter where within its error bounds the real `p` is.
+func OffsetRayOrigin(p, pError, n, w Vector) Vector {
+	d := math.Abs(n.X)*pError.X + math.Abs(n.Y)*pError.Y + math.Abs(n.Z)*pError.Z
+	offset := n.MultiplyScalar(d)
+	if w.Dot(n) < 0 {
+		offset = offset.Neg()
+	}
+
+	po := p.Plus(offset)
+
+	// Round the offset point away from `p` so that rounding errors in the addition
+	// above do not bring it back inside the error bounds.
+	if offset.X > 0 {
+		po.X = NextFloatUp(po.X)
+	} else if offset.X < 0 {
+		po.X = NextFloatDown(po.X)
+	}
+	if offset.Y > 0 {
+		po.Y = NextFloatUp(po.Y)
+	} else if offset.Y < 0 {
+		po.Y = NextFloatDown(po.Y)
+	}
+	if offset.Z > 0 {
+		po.Z = NextFloatUp(po.Z)
+	} else if offset.Z < 0 {
+		po.Z = NextFloatDown(po.Z)
+	}
+
+	return po
+}
+
+// SpawnRay returns a ray with direction `d` leaving the surface point `p`. See
+// [OffsetRayOrigin] for the meaning of `pError` and `n`.
+func SpawnRay(p, pError, n, d Vector) Ray {
+	return NewRay(OffsetRayOrigin(p, pError, n, d), d)
+}
+
+// SpawnRayTo returns a ray leaving the surface point `p` toward the point `target`.
+// Its direction is normalized and it ends just before reaching `target`. See
+// [OffsetRayOrigin] for the meaning of `pError` and `n`.
+func SpawnRayTo(p, pError, n, target Vector) Ray {
+	origin := OffsetRayOrigin(p, pError, n, target.Minus(p))
+	d := target.Minus(origin)
+	ray := NewRay(origin, d.Normalize())
+	ray.Maxt = d.Length() * (1 - ShadowEpsilon)
+	return ray
+}
@@ -52,6 +52,11 @@ func (v Vector) Cross(other Vector) Vector {
 		v.X*other.Y - v.Y*other.X}
 }
 
+// Abs returns a vector with the absolute values of the components of `v`.
+func (v Vector) Abs() Vector {
+	return Vector{math.Abs(v.X), math.Abs(v.Y), math.Abs(v.Z)}
+}
+
 func (v Vector) Neg() Vector {
 	return Vector{-v.X, -v.Y, -v.Z}
 }
@@ -86,6 +91,20 @@ func (v Vector) ByAxis(index int) float64 {
 	return [3]float64{v.X, v.Y, v.Z}[index]
 }
 
+// MaxDimension returns the index of the axis with the biggest component of `v`.
+func (v Vector) MaxDimension() int {
+	if v.X > v.Y {
+		if v.X > v.Z {
+			return 0
+		}
+		return 2
+	}
+	if v.Y > v.Z {
+		return 1
+	}
+	return 2
+}
+
 func (v *Vector) SetByAxis(index int, val float64) {
 	*([3]*float64{&v.X, &v.Y, &v.Z}[index]) = val
 }
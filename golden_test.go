@@ -0,0 +1,140 @@
+package main
+
+import (
+	"flag"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ironsmile/raytracer/imagecmp"
+	"github.com/ironsmile/raytracer/sampler"
+	"github.com/ironsmile/raytracer/scene"
+)
+
+var (
+	updateGolden = flag.Bool("update-golden", false,
+		"write the rendered images as the new references for TestGoldenImages")
+	goldenDiffs = flag.String("golden-diffs", filepath.Join(os.TempDir(), "raytracer-golden"),
+		"directory in which TestGoldenImages writes the rendered and diff images of\n"+
+			"the failed scenes")
+)
+
+const (
+	goldenDir    = "testdata/golden"
+	goldenWidth  = 128
+	goldenHeight = 96
+)
+
+// goldenTolerance is the largest difference from the reference image which is
+// accepted for a scene.
+type goldenTolerance struct {
+	maxRMSE float64
+	minSSIM float64
+	maxFLIP float64
+}
+
+// defaultGoldenTolerance is used for scenes without their own tolerance. Rendering
+// is deterministic so differences come only from floating point operations which
+// are done differently on other platforms.
+var defaultGoldenTolerance = goldenTolerance{
+	maxRMSE: 0.005,
+	minSSIM: 0.99,
+	maxFLIP: 0.01,
+}
+
+// goldenTolerances holds the tolerances of scenes which need them to be different
+// from defaultGoldenTolerance.
+var goldenTolerances = map[string]goldenTolerance{
+	// Nothing is lit in the empty scene. It must stay completely black.
+	"empty": {maxRMSE: 0, minSSIM: 1, maxFLIP: 0},
+}
+
+// TestGoldenImages renders every scene in scene.PossibleScenes and compares it to a
+// reference image in testdata/golden. When the difference is too big the rendered
+// image and an image which shows the differences are written in the directory
+// given with -golden-diffs. Scenes whose model files are missing, for example
+// because they are not distributed with the repository, are skipped.
+//
+// Run the test with -update-golden after intended changes to the rendered images.
+func TestGoldenImages(t *testing.T) {
+	cfg := sampler.Config{
+		Kind:            sampler.KindSobol,
+		SamplesPerPixel: 4,
+		Seed:            1,
+	}
+
+	for _, name := range scene.PossibleScenes {
+		t.Run(name, func(t *testing.T) {
+			rendered := renderScene(t, name, goldenWidth, goldenHeight, cfg)
+			refFile := filepath.Join(goldenDir, name+".png")
+
+			if *updateGolden {
+				if err := writePNG(refFile, rendered); err != nil {
+					t.Fatalf("writing reference image: %s", err)
+				}
+				return
+			}
+
+			ref, err := readPNG(refFile)
+			if err != nil {
+				t.Fatalf("reading reference image: %s (run with -update-golden to create it)",
+					err)
+			}
+
+			res, diff, err := imagecmp.Compare(ref, rendered)
+			if err != nil {
+				t.Fatalf("comparing images: %s", err)
+			}
+
+			tol, ok := goldenTolerances[name]
+			if !ok {
+				tol = defaultGoldenTolerance
+			}
+
+			if res.RMSE <= tol.maxRMSE && res.SSIM >= tol.minSSIM && res.FLIP <= tol.maxFLIP {
+				return
+			}
+
+			t.Errorf("rendered image differs from %s: %s", refFile, res)
+
+			if err := os.MkdirAll(*goldenDiffs, 0o755); err != nil {
+				t.Fatalf("creating directory for diff images: %s", err)
+			}
+			for suffix, img := range map[string]image.Image{
+				"rendered": rendered,
+				"diff":     diff,
+			} {
+				file := filepath.Join(*goldenDiffs, name+"_"+suffix+".png")
+				if err := writePNG(file, img); err != nil {
+					t.Fatalf("writing %s image: %s", suffix, err)
+				}
+				t.Logf("%s image written to %s", suffix, file)
+			}
+		})
+	}
+}
+
+func readPNG(filename string) (image.Image, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return png.Decode(f)
+}
+
+func writePNG(filename string, img image.Image) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := png.Encode(f, img); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,276 @@
+package imagecmp
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/ironsmile/raytracer/utils"
+)
+
+const (
+	// pixelsPerDegree is the assumed number of pixels in one degree of the field of
+	// view of the observer.
+	pixelsPerDegree = 67
+
+	// flipQc, flipPc and flipPt control the compression and remapping of the colour
+	// differences. flipQf is the exponent of the feature differences. The values
+	// are the ones from the ꟻLIP paper.
+	flipQc = 0.7
+	flipPc = 0.4
+	flipPt = 0.95
+	flipQf = 0.5
+
+	// featureWidth is the width in degrees of the edges and points which are
+	// detected by the feature pipeline.
+	featureWidth = 0.082
+)
+
+// csfSigma returns the standard deviation in pixels of the Gaussian which
+// corresponds to a contrast sensitivity function with parameter `b` as given in
+// the ꟻLIP paper.
+func csfSigma(b float64) float64 {
+	return math.Sqrt(b/(2*math.Pi*math.Pi)) * pixelsPerDegree
+}
+
+var (
+	// The contrast sensitivity of the achromatic channel and the two chromatic
+	// ones. The blue-yellow one is a sum of two Gaussians in the paper. Only the
+	// wider of them is used here.
+	sigmaAchromatic = csfSigma(0.0047)
+	sigmaRedGreen   = csfSigma(0.0053)
+	sigmaBlueYellow = csfSigma(0.04)
+
+	// maxColorDiff is the compressed colour difference between pure green and
+	// pure blue. This is the largest colour difference between two colours in
+	// the sRGB gamut.
+	maxColorDiff = math.Pow(hyab(
+		huntAdjust(linearToLab(0, 1, 0)),
+		huntAdjust(linearToLab(0, 0, 1)),
+	), flipQc)
+)
+
+// flip returns the per pixel perceptual error between `a` and `b`.
+func flip(a, b pixels) plane {
+	ya, yb := newYCxCz(a), newYCxCz(b)
+
+	colorErr := colorDifference(ya.filter(), yb.filter())
+	featureErr := featureDifference(ya.normalizedLuminance(), yb.normalizedLuminance())
+
+	res := newPlane(a.width, a.height)
+	for i := range res.v {
+		res.v[i] = math.Pow(colorErr.v[i], 1-featureErr.v[i])
+	}
+	return res
+}
+
+// ycxcz is an image in the YCxCz colour space. It is a linearized version of the
+// CIELAB space which makes it suitable for spatial filtering.
+type ycxcz struct {
+	y, cx, cz plane
+}
+
+func newYCxCz(p pixels) ycxcz {
+	res := ycxcz{
+		y:  newPlane(p.width, p.height),
+		cx: newPlane(p.width, p.height),
+		cz: newPlane(p.width, p.height),
+	}
+
+	for i := range p.r.v {
+		x, y, z := linearToXYZ(srgbToLinear(p.r.v[i]), srgbToLinear(p.g.v[i]),
+			srgbToLinear(p.b.v[i]))
+
+		res.y.v[i] = 116*y/whiteY - 16
+		res.cx.v[i] = 500 * (x/whiteX - y/whiteY)
+		res.cz.v[i] = 200 * (y/whiteY - z/whiteZ)
+	}
+
+	return res
+}
+
+// filter applies the contrast sensitivity functions to all channels. This removes
+// the details which the observer is not able to see.
+func (c ycxcz) filter() ycxcz {
+	return ycxcz{
+		y:  c.y.blur(sigmaAchromatic),
+		cx: c.cx.blur(sigmaRedGreen),
+		cz: c.cz.blur(sigmaBlueYellow),
+	}
+}
+
+// normalizedLuminance returns the luminance of every pixel in [0, 1].
+func (c ycxcz) normalizedLuminance() plane {
+	res := newPlane(c.y.w, c.y.h)
+	for i, v := range c.y.v {
+		res.v[i] = (v + 16) / 116
+	}
+	return res
+}
+
+// lab returns the CIELAB colour of the pixel with index `i`. Colours outside of the
+// sRGB gamut are clamped to it.
+func (c ycxcz) lab(i int) [3]float64 {
+	y := (c.y.v[i] + 16) / 116
+	x := c.cx.v[i]/500 + y
+	z := y - c.cz.v[i]/200
+
+	r, g, b := xyzToLinear(x*whiteX, y*whiteY, z*whiteZ)
+	return linearToLab(utils.Clamp(r, 0, 1), utils.Clamp(g, 0, 1), utils.Clamp(b, 0, 1))
+}
+
+// colorDifference returns the remapped colour difference in [0, 1] for every pixel.
+func colorDifference(a, b ycxcz) plane {
+	res := newPlane(a.y.w, a.y.h)
+	for i := range res.v {
+		diff := math.Pow(hyab(huntAdjust(a.lab(i)), huntAdjust(b.lab(i))), flipQc)
+
+		if diff < flipPc*maxColorDiff {
+			res.v[i] = flipPt / (flipPc * maxColorDiff) * diff
+		} else {
+			res.v[i] = flipPt + (diff-flipPc*maxColorDiff)/
+				(maxColorDiff-flipPc*maxColorDiff)*(1-flipPt)
+		}
+	}
+	return res
+}
+
+// featureDifference returns the difference of the edges and points in the
+// luminance planes `a` and `b` for every pixel. It is in [0, 1].
+func featureDifference(a, b plane) plane {
+	sigma := 0.5 * featureWidth * pixelsPerDegree
+
+	smooth := gaussian(sigma)
+	edge := normalizeSigned(kernel(sigma, func(x float64) float64 {
+		return -x * math.Exp(-x*x/(2*sigma*sigma))
+	}))
+	point := normalizeSigned(kernel(sigma, func(x float64) float64 {
+		return (x*x/(sigma*sigma) - 1) * math.Exp(-x*x/(2*sigma*sigma))
+	}))
+
+	magnitude := func(p plane, k []float64) plane {
+		gx, gy := p.convolve(k, smooth), p.convolve(smooth, k)
+		res := newPlane(p.w, p.h)
+		for i := range res.v {
+			res.v[i] = math.Hypot(gx.v[i], gy.v[i])
+		}
+		return res
+	}
+
+	edgeA, edgeB := magnitude(a, edge), magnitude(b, edge)
+	pointA, pointB := magnitude(a, point), magnitude(b, point)
+
+	res := newPlane(a.w, a.h)
+	for i := range res.v {
+		diff := max(math.Abs(edgeA.v[i]-edgeB.v[i]), math.Abs(pointA.v[i]-pointB.v[i]))
+		res.v[i] = math.Pow(min(diff/math.Sqrt2, 1), flipQf)
+	}
+	return res
+}
+
+// normalizeSigned scales the positive values of `k` so that they sum to one and
+// the negative ones so that they sum to minus one.
+func normalizeSigned(k []float64) []float64 {
+	var pos, neg float64
+	for _, v := range k {
+		if v > 0 {
+			pos += v
+		} else {
+			neg -= v
+		}
+	}
+
+	for i, v := range k {
+		if v > 0 {
+			k[i] = v / pos
+		} else {
+			k[i] = v / neg
+		}
+	}
+	return k
+}
+
+// The D65 reference white in the XYZ colour space.
+const (
+	whiteX = 0.950428545
+	whiteY = 1.0
+	whiteZ = 1.088900371
+)
+
+func srgbToLinear(c float64) float64 {
+	if c <= 0.04045 {
+		return c / 12.92
+	}
+	return math.Pow((c+0.055)/1.055, 2.4)
+}
+
+func linearToXYZ(r, g, b float64) (x, y, z float64) {
+	x = 0.4124564*r + 0.3575761*g + 0.1804375*b
+	y = 0.2126729*r + 0.7151522*g + 0.0721750*b
+	z = 0.0193339*r + 0.1191920*g + 0.9503041*b
+	return
+}
+
+func xyzToLinear(x, y, z float64) (r, g, b float64) {
+	r = 3.2404542*x - 1.5371385*y - 0.4985314*z
+	g = -0.9692660*x + 1.8760108*y + 0.0415560*z
+	b = 0.0556434*x - 0.2040259*y + 1.0572252*z
+	return
+}
+
+func linearToLab(r, g, b float64) [3]float64 {
+	x, y, z := linearToXYZ(r, g, b)
+
+	f := func(t float64) float64 {
+		const delta = 6.0 / 29
+		if t > delta*delta*delta {
+			return math.Cbrt(t)
+		}
+		return t/(3*delta*delta) + 4.0/29
+	}
+
+	fx, fy, fz := f(x/whiteX), f(y/whiteY), f(z/whiteZ)
+	return [3]float64{116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)}
+}
+
+// huntAdjust models the Hunt effect: the chroma of dark colours is perceived as
+// weaker.
+func huntAdjust(lab [3]float64) [3]float64 {
+	return [3]float64{lab[0], 0.01 * lab[0] * lab[1], 0.01 * lab[0] * lab[2]}
+}
+
+// hyab returns the HyAB distance between two colours in the CIELAB space. It works
+// better than the Euclidean distance for big colour differences.
+func hyab(a, b [3]float64) float64 {
+	return math.Abs(a[0]-b[0]) + math.Hypot(a[1]-b[1], a[2]-b[2])
+}
+
+// magma holds the control points of the colour map used for error images.
+var magma = [][3]float64{
+	{0.001, 0.000, 0.014},
+	{0.317, 0.071, 0.485},
+	{0.716, 0.215, 0.475},
+	{0.987, 0.536, 0.382},
+	{0.987, 0.991, 0.750},
+}
+
+// heatmap returns an image in which every value of `p` in [0, 1] is mapped to a
+// colour from dark purple for no error to bright yellow for the largest one.
+func (p plane) heatmap() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, p.w, p.h))
+
+	for i, v := range p.v {
+		t := utils.Clamp(v, 0, 1) * float64(len(magma)-1)
+		low := min(int(t), len(magma)-2)
+		frac := t - float64(low)
+
+		var c [3]uint8
+		for ch := range c {
+			c[ch] = uint8(utils.Lerp(frac, magma[low][ch], magma[low+1][ch])*255 + 0.5)
+		}
+
+		img.SetNRGBA(i%p.w, i/p.w, color.NRGBA{R: c[0], G: c[1], B: c[2], A: 255})
+	}
+
+	return img
+}
@@ -0,0 +1,187 @@
+// Package imagecmp measures the differences between two images. It is used for
+// comparing rendered images to reference ones.
+//
+// Three metrics are supported. RMSE is the plain root mean square error of the
+// colour channels. SSIM is the structural similarity index which is closer to how
+// people judge the similarity of images. FLIP is an approximation of the
+// ꟻLIP metric from Andersson et al. (2020), "ꟻLIP: A Difference Evaluator for
+// Alternating Images". It estimates how visible the differences are when one
+// flips between the two images.
+package imagecmp
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// Result holds all metrics for a pair of images.
+type Result struct {
+	// RMSE is the root mean square error of the colour channels in the [0, 1]
+	// range. Zero means that the images are the same.
+	RMSE float64
+
+	// SSIM is the mean structural similarity of the luminance. It is in the
+	// [-1, 1] range and one means that the images are the same.
+	SSIM float64
+
+	// FLIP is the mean perceptual error in the [0, 1] range. Zero means that there
+	// are no visible differences.
+	FLIP float64
+}
+
+// String implements fmt.Stringer.
+func (r Result) String() string {
+	return fmt.Sprintf("RMSE %.5f, SSIM %.5f, FLIP %.5f", r.RMSE, r.SSIM, r.FLIP)
+}
+
+// Compare returns all metrics for the images `a` and `b` together with an image
+// which shows where the perceptual differences are. See [FLIP].
+func Compare(a, b image.Image) (Result, *image.NRGBA, error) {
+	var res Result
+
+	pa, pb, err := toPixels(a, b)
+	if err != nil {
+		return res, nil, err
+	}
+
+	errMap := flip(pa, pb)
+
+	res.RMSE = rmse(pa, pb)
+	res.SSIM = ssim(pa, pb)
+	res.FLIP = errMap.mean()
+
+	return res, errMap.heatmap(), nil
+}
+
+// RMSE returns the root mean square error of the colour channels of `a` and `b`.
+// Colours are in the [0, 1] range. The images must be of the same size.
+func RMSE(a, b image.Image) (float64, error) {
+	pa, pb, err := toPixels(a, b)
+	if err != nil {
+		return 0, err
+	}
+	return rmse(pa, pb), nil
+}
+
+// SSIM returns the mean structural similarity index of the luminance of `a` and `b`.
+// Local statistics are computed in a Gaussian window with standard deviation of 1.5
+// pixels as in Wang et al. (2004), "Image Quality Assessment: From Error Visibility
+// to Structural Similarity". The images must be of the same size.
+func SSIM(a, b image.Image) (float64, error) {
+	pa, pb, err := toPixels(a, b)
+	if err != nil {
+		return 0, err
+	}
+	return ssim(pa, pb), nil
+}
+
+// FLIP returns the mean perceptual error between `a` and `b`. The images are
+// assumed to be in sRGB and viewed at 67 pixels per degree, which is about
+// 0.7 meters from a 24 inch 4K monitor. The images must be of the same size.
+//
+// This is not an exact implementation of ꟻLIP. Its contrast sensitivity filters
+// are approximated with Gaussians. So the results are close but not equal to those
+// of the reference implementation.
+func FLIP(a, b image.Image) (float64, error) {
+	pa, pb, err := toPixels(a, b)
+	if err != nil {
+		return 0, err
+	}
+	return flip(pa, pb).mean(), nil
+}
+
+// pixels holds the colours of an image as floating point numbers in [0, 1].
+type pixels struct {
+	width, height int
+	r, g, b       plane
+}
+
+// toPixels converts both images to pixels. It returns an error if their sizes
+// differ.
+func toPixels(a, b image.Image) (pixels, pixels, error) {
+	ab, bb := a.Bounds(), b.Bounds()
+	if ab.Dx() != bb.Dx() || ab.Dy() != bb.Dy() {
+		return pixels{}, pixels{}, fmt.Errorf("images have different sizes: %dx%d and %dx%d",
+			ab.Dx(), ab.Dy(), bb.Dx(), bb.Dy())
+	}
+	if ab.Empty() {
+		return pixels{}, pixels{}, fmt.Errorf("images are empty")
+	}
+
+	return newPixels(a), newPixels(b), nil
+}
+
+func newPixels(img image.Image) pixels {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	p := pixels{
+		width:  w,
+		height: h,
+		r:      newPlane(w, h),
+		g:      newPlane(w, h),
+		b:      newPlane(w, h),
+	}
+
+	for y := range h {
+		for x := range w {
+			clr := color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y))
+			c := clr.(color.NRGBA)
+
+			ind := y*w + x
+			p.r.v[ind] = float64(c.R) / 255
+			p.g.v[ind] = float64(c.G) / 255
+			p.b.v[ind] = float64(c.B) / 255
+		}
+	}
+
+	return p
+}
+
+// luma returns the luma of every pixel.
+func (p pixels) luma() plane {
+	l := newPlane(p.width, p.height)
+	for i := range l.v {
+		l.v[i] = 0.2126*p.r.v[i] + 0.7152*p.g.v[i] + 0.0722*p.b.v[i]
+	}
+	return l
+}
+
+func rmse(a, b pixels) float64 {
+	var sum float64
+	for _, ch := range [][2]plane{{a.r, b.r}, {a.g, b.g}, {a.b, b.b}} {
+		for i, v := range ch[0].v {
+			d := v - ch[1].v[i]
+			sum += d * d
+		}
+	}
+	return math.Sqrt(sum / float64(3*len(a.r.v)))
+}
+
+func ssim(a, b pixels) float64 {
+	const (
+		sigma = 1.5
+		c1    = 0.01 * 0.01
+		c2    = 0.03 * 0.03
+	)
+
+	la, lb := a.luma(), b.luma()
+
+	muA, muB := la.blur(sigma), lb.blur(sigma)
+	sqA, sqB, prod := la.mul(la).blur(sigma), lb.mul(lb).blur(sigma), la.mul(lb).blur(sigma)
+
+	var sum float64
+	for i := range la.v {
+		ma, mb := muA.v[i], muB.v[i]
+		varA := sqA.v[i] - ma*ma
+		varB := sqB.v[i] - mb*mb
+		cov := prod.v[i] - ma*mb
+
+		sum += ((2*ma*mb + c1) * (2*cov + c2)) /
+			((ma*ma + mb*mb + c1) * (varA + varB + c2))
+	}
+
+	return sum / float64(len(la.v))
+}
@@ -0,0 +1,100 @@
+package imagecmp
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+// TestCompareIdentical checks that identical images have no differences.
+func TestCompareIdentical(t *testing.T) {
+	img := testImage(64, 48, 0, 1)
+
+	res, diff, err := Compare(img, img)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if res.RMSE != 0 || res.FLIP != 0 {
+		t.Errorf("expected no error for identical images but got %s", res)
+	}
+	if res.SSIM < 1-1e-9 {
+		t.Errorf("expected SSIM of 1 for identical images but got %f", res.SSIM)
+	}
+	if diff.Bounds() != img.Bounds() {
+		t.Errorf("diff image has bounds %s instead of %s", diff.Bounds(), img.Bounds())
+	}
+}
+
+// TestCompareOrdering checks that all metrics get worse as the differences between
+// images grow.
+func TestCompareOrdering(t *testing.T) {
+	ref := testImage(64, 48, 0, 1)
+
+	var prev Result
+	for i, noise := range []float64{0.02, 0.1, 0.4} {
+		res, _, err := Compare(ref, testImage(64, 48, noise, 2))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if i > 0 && (res.RMSE <= prev.RMSE || res.SSIM >= prev.SSIM || res.FLIP <= prev.FLIP) {
+			t.Errorf("noise %.2f: metrics %s are not worse than %s", noise, res, prev)
+		}
+		prev = res
+	}
+
+	black := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	white := image.NewNRGBA(black.Bounds())
+	for i := range white.Pix {
+		white.Pix[i] = 255
+	}
+
+	flip, err := FLIP(black, white)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if flip < 0.9 || flip > 1 {
+		t.Errorf("expected FLIP close to 1 for black and white images but got %f", flip)
+	}
+}
+
+// TestCompareSizeMismatch checks that images with different sizes are not compared.
+func TestCompareSizeMismatch(t *testing.T) {
+	_, err := RMSE(testImage(10, 10, 0, 1), testImage(10, 11, 0, 1))
+	if err == nil {
+		t.Errorf("expected an error for images with different sizes")
+	}
+}
+
+// testImage returns an image with a smooth gradient and a few sharp edges with
+// uniform noise with amplitude `noise` added to it.
+func testImage(w, h int, noise float64, seed int64) *image.NRGBA {
+	rnd := rand.New(rand.NewSource(seed))
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+
+	for y := range h {
+		for x := range w {
+			v := float64(x) / float64(w)
+			if (x/8+y/8)%2 == 0 {
+				v = 1 - v
+			}
+
+			clr := [3]float64{v, 0.5 * v, 1 - v}
+			for ch := range clr {
+				clr[ch] += (rnd.Float64()*2 - 1) * noise
+				clr[ch] = min(max(clr[ch], 0), 1)
+			}
+
+			img.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(clr[0] * 255),
+				G: uint8(clr[1] * 255),
+				B: uint8(clr[2] * 255),
+				A: 255,
+			})
+		}
+	}
+
+	return img
+}
@@ -0,0 +1,101 @@
+package imagecmp
+
+import "math"
+
+// plane is a single channel image of floating point values.
+type plane struct {
+	w, h int
+	v    []float64
+}
+
+func newPlane(w, h int) plane {
+	return plane{w: w, h: h, v: make([]float64, w*h)}
+}
+
+// mul returns the product of `p` and `o` pixel by pixel.
+func (p plane) mul(o plane) plane {
+	res := newPlane(p.w, p.h)
+	for i, v := range p.v {
+		res.v[i] = v * o.v[i]
+	}
+	return res
+}
+
+// mean returns the mean of all values.
+func (p plane) mean() float64 {
+	var sum float64
+	for _, v := range p.v {
+		sum += v
+	}
+	return sum / float64(len(p.v))
+}
+
+// blur returns `p` convolved with a Gaussian with standard deviation `sigma`.
+func (p plane) blur(sigma float64) plane {
+	k := gaussian(sigma)
+	return p.convolve(k, k)
+}
+
+// convolve returns `p` convolved with the separable kernel which is the product of
+// `kx` in the horizontal and `ky` in the vertical direction. Both must have odd
+// lengths. Pixels outside of the plane are the same as the nearest edge pixel.
+func (p plane) convolve(kx, ky []float64) plane {
+	tmp := newPlane(p.w, p.h)
+	rx := len(kx) / 2
+	for y := range p.h {
+		row := p.v[y*p.w : (y+1)*p.w]
+		for x := range p.w {
+			var sum float64
+			for i, k := range kx {
+				sum += k * row[clampIndex(x+i-rx, p.w)]
+			}
+			tmp.v[y*p.w+x] = sum
+		}
+	}
+
+	res := newPlane(p.w, p.h)
+	ry := len(ky) / 2
+	for y := range p.h {
+		for x := range p.w {
+			var sum float64
+			for i, k := range ky {
+				sum += k * tmp.v[clampIndex(y+i-ry, p.h)*p.w+x]
+			}
+			res.v[y*p.w+x] = sum
+		}
+	}
+
+	return res
+}
+
+func clampIndex(i, n int) int {
+	return min(max(i, 0), n-1)
+}
+
+// gaussian returns a normalized Gaussian kernel with standard deviation `sigma`
+// which extends to three standard deviations.
+func gaussian(sigma float64) []float64 {
+	k := kernel(sigma, func(x float64) float64 {
+		return math.Exp(-x * x / (2 * sigma * sigma))
+	})
+
+	var sum float64
+	for _, v := range k {
+		sum += v
+	}
+	for i := range k {
+		k[i] /= sum
+	}
+	return k
+}
+
+// kernel returns the values of `f` for all integer positions within three standard
+// deviations from the centre.
+func kernel(sigma float64, f func(x float64) float64) []float64 {
+	radius := int(math.Ceil(3 * sigma))
+	k := make([]float64, 2*radius+1)
+	for i := range k {
+		k[i] = f(float64(i - radius))
+	}
+	return k
+}
@@ -0,0 +1,377 @@
+package imagefilm
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+
+	"github.com/ironsmile/raytracer/aov"
+)
+
+// DenoiserGuides are the output variables which a [Denoiser] needs for finding the
+// edges in the image.
+var DenoiserGuides = []aov.Kind{aov.KindDepth, aov.KindNormal, aov.KindAlbedo}
+
+// atrousKernel is the 1D B3 spline kernel of the à-trous wavelet transform.
+var atrousKernel = [5]float64{1.0 / 16, 1.0 / 4, 3.0 / 8, 1.0 / 4, 1.0 / 16}
+
+// Denoiser removes the noise from images rendered with few samples per pixel. It
+// is the edge-avoiding à-trous wavelet filter from Schied et al. (2017),
+// "Spatiotemporal Variance-Guided Filtering", without the temporal part.
+//
+// The image is repeatedly blurred with a sparse 5x5 kernel whose taps are twice as
+// far apart on every iteration. Taps across edges are rejected with weights derived
+// from the depth, normals and luminance of the pixels. The luminance weight is
+// scaled by the estimated variance of every pixel so that noisy pixels are blurred
+// more than converged ones. Lighting is filtered separately from the albedo so that
+// textures and material edges stay sharp.
+type Denoiser struct {
+	// Iterations is the number of filter passes. Every pass doubles the filter
+	// radius.
+	Iterations int
+
+	// LuminanceSigma controls how much pixels with different luminance are mixed.
+	// Bigger is blurrier.
+	LuminanceSigma float64
+
+	// NormalPower controls how much pixels with different normals are mixed.
+	// Bigger keeps the geometric edges sharper.
+	NormalPower float64
+
+	// DepthSigma controls how much pixels at different depths are mixed. Bigger is
+	// blurrier.
+	DepthSigma float64
+
+	guide *aov.Buffers
+}
+
+// NewDenoiser returns a denoiser with the default settings which uses the output
+// variables in `guide`. It must collect all of [DenoiserGuides] for the primary
+// rays of the denoised image.
+func NewDenoiser(guide *aov.Buffers) (*Denoiser, error) {
+	for _, kind := range DenoiserGuides {
+		if guide.Values(kind) == nil {
+			return nil, fmt.Errorf("denoiser guide does not collect %s", kind)
+		}
+	}
+
+	return &Denoiser{
+		Iterations:     5,
+		LuminanceSigma: 4,
+		NormalPower:    128,
+		DepthSigma:     1,
+		guide:          guide,
+	}, nil
+}
+
+// ResetGuide discards the output variables collected in the guide.
+func (d *Denoiser) ResetGuide() {
+	d.guide.Reset()
+}
+
+// denoise returns the denoised mean colours of all pixels in `p`. It fails when
+// the guide and `p` have different sizes.
+func (d *Denoiser) Denoise(p *PixelStats) ([]float64, error) {
+	w, h := p.width, p.height
+	if d.guide.Width() != w || d.guide.Height() != h {
+		return nil, fmt.Errorf("denoiser guide is %dx%d but the image is %dx%d",
+			d.guide.Width(), d.guide.Height(), w, h)
+	}
+
+	depth := d.guide.Values(aov.KindDepth)
+	normal := d.guide.Values(aov.KindNormal)
+	albedo := d.guide.Values(aov.KindAlbedo)
+
+	f := atrousFilter{
+		d:        d,
+		width:    w,
+		height:   h,
+		depth:    depth,
+		normal:   normal,
+		hit:      make([]bool, w*h),
+		sampled:  make([]bool, w*h),
+		color:    make([]float64, 3*w*h),
+		variance: make([]float64, w*h),
+	}
+
+	for ind := range f.hit {
+		f.hit[ind] = d.guide.Hits(ind%w, ind/w) > 0
+		f.sampled[ind] = p.count[ind] > 0
+	}
+
+	// Demodulate the albedo and estimate the variance of the lighting.
+	for ind := range f.hit {
+		alb := [3]float64{1, 1, 1}
+		if f.hit[ind] {
+			copy(alb[:], albedo[ind*3:ind*3+3])
+		}
+
+		for ch := range 3 {
+			f.color[ind*3+ch] = demodulate(p.mean[ind*3+ch], alb[ch])
+		}
+
+		albLum := max(luminance(alb[0], alb[1], alb[2]), albedoEpsilon)
+		n := float64(p.count[ind])
+		if n < 2 {
+			f.variance[ind] = -1
+			continue
+		}
+		f.variance[ind] = p.lumM2[ind] / (n - 1) / n / (albLum * albLum)
+	}
+
+	f.estimateMissingVariance()
+	f.variance = f.blurVariance()
+	f.depthDer = f.depthDerivatives()
+
+	for i := range d.Iterations {
+		f.pass(1 << i)
+	}
+
+	out := make([]float64, len(f.color))
+	for ind := range f.hit {
+		alb := [3]float64{1, 1, 1}
+		if f.hit[ind] {
+			copy(alb[:], albedo[ind*3:ind*3+3])
+		}
+		for ch := range 3 {
+			out[ind*3+ch] = remodulate(f.color[ind*3+ch], alb[ch])
+		}
+	}
+
+	return out, nil
+}
+
+// albedoEpsilon is the albedo below which the lighting is not separated from the
+// albedo. Dividing by it would only amplify the noise.
+const albedoEpsilon = 0.01
+
+func demodulate(c, albedo float64) float64 {
+	if albedo < albedoEpsilon {
+		return c
+	}
+	return c / albedo
+}
+
+func remodulate(c, albedo float64) float64 {
+	if albedo < albedoEpsilon {
+		return c
+	}
+	return c * albedo
+}
+
+// atrousFilter holds the state of a single image while it is being denoised.
+type atrousFilter struct {
+	d *Denoiser
+
+	width, height int
+
+	depth  []float64
+	normal []float64
+	hit    []bool
+
+	// sampled is false for the pixels without samples, for example the ones
+	// outside of the crop window. They are neither filtered nor used as taps.
+	sampled []bool
+
+	// depthDer is the biggest screen space derivative of the depth for every
+	// pixel. It makes the depth weight tolerant to surfaces at grazing angles.
+	depthDer []float64
+
+	// color is the lighting without the albedo which is being filtered.
+	color []float64
+
+	// variance is the estimated variance of the luminance of color.
+	variance []float64
+}
+
+// pass runs a single filter iteration with taps `step` pixels apart.
+func (f *atrousFilter) pass(step int) {
+	color := make([]float64, len(f.color))
+	variance := make([]float64, len(f.variance))
+
+	parallelRows(f.height, func(y int) {
+		for x := range f.width {
+			f.filterPixel(x, y, step, color, variance)
+		}
+	})
+
+	f.color = color
+	f.variance = variance
+}
+
+// filterPixel writes the filtered colour and variance of the pixel at (x, y) in
+// `color` and `variance`.
+func (f *atrousFilter) filterPixel(x, y, step int, color, variance []float64) {
+	p := y*f.width + x
+	if !f.sampled[p] {
+		return
+	}
+
+	cp := f.color[p*3 : p*3+3]
+	lumP := luminance(cp[0], cp[1], cp[2])
+	lumScale := f.d.LuminanceSigma*math.Sqrt(max(f.variance[p], 0)) + 1e-6
+
+	var (
+		sumW, sumVar float64
+		sum          [3]float64
+	)
+
+	for ky := range atrousKernel {
+		qy := y + (ky-2)*step
+		if qy < 0 || qy >= f.height {
+			continue
+		}
+
+		for kx := range atrousKernel {
+			qx := x + (kx-2)*step
+			if qx < 0 || qx >= f.width {
+				continue
+			}
+
+			q := qy*f.width + qx
+			if !f.sampled[q] || f.hit[p] != f.hit[q] {
+				continue
+			}
+
+			cq := f.color[q*3 : q*3+3]
+			lumQ := luminance(cq[0], cq[1], cq[2])
+
+			weight := atrousKernel[kx] * atrousKernel[ky] *
+				math.Exp(-math.Abs(lumP-lumQ)/lumScale)
+
+			if f.hit[p] && q != p {
+				np := f.normal[p*3 : p*3+3]
+				nq := f.normal[q*3 : q*3+3]
+				dot := max(np[0]*nq[0]+np[1]*nq[1]+np[2]*nq[2], 0)
+				weight *= math.Pow(dot, f.d.NormalPower)
+
+				dist := math.Hypot(float64(qx-x), float64(qy-y))
+				depthScale := f.d.DepthSigma*f.depthDer[p]*dist + 1e-6
+				weight *= math.Exp(-math.Abs(f.depth[p]-f.depth[q]) / depthScale)
+			}
+
+			sumW += weight
+			sumVar += weight * weight * f.variance[q]
+			for ch := range sum {
+				sum[ch] += weight * cq[ch]
+			}
+		}
+	}
+
+	// The centre tap always has a weight of at least the kernel weight so sumW is
+	// never zero.
+	for ch := range sum {
+		color[p*3+ch] = sum[ch] / sumW
+	}
+	variance[p] = sumVar / (sumW * sumW)
+}
+
+// estimateMissingVariance replaces the negative variances of pixels with less than
+// two samples with the variance of the luminance in their 3x3 neighbourhood.
+func (f *atrousFilter) estimateMissingVariance() {
+	parallelRows(f.height, func(y int) {
+		for x := range f.width {
+			p := y*f.width + x
+			if f.variance[p] >= 0 || !f.sampled[p] {
+				continue
+			}
+
+			var sum, sumSq, n float64
+			for qy := max(y-1, 0); qy <= min(y+1, f.height-1); qy++ {
+				for qx := max(x-1, 0); qx <= min(x+1, f.width-1); qx++ {
+					q := qy*f.width + qx
+					if !f.sampled[q] || f.hit[p] != f.hit[q] {
+						continue
+					}
+					c := f.color[q*3 : q*3+3]
+					lum := luminance(c[0], c[1], c[2])
+					sum += lum
+					sumSq += lum * lum
+					n++
+				}
+			}
+
+			mean := sum / n
+			f.variance[p] = max(sumSq/n-mean*mean, 0)
+		}
+	})
+}
+
+// blurVariance returns the variance blurred with a 3x3 Gaussian. This makes the
+// variance estimates from few samples more reliable.
+func (f *atrousFilter) blurVariance() []float64 {
+	kernel := [3]float64{1.0 / 4, 1.0 / 2, 1.0 / 4}
+	res := make([]float64, len(f.variance))
+
+	parallelRows(f.height, func(y int) {
+		for x := range f.width {
+			if !f.sampled[y*f.width+x] {
+				continue
+			}
+
+			var sum, sumW float64
+			for ky := range kernel {
+				for kx := range kernel {
+					qx, qy := x+kx-1, y+ky-1
+					if qx < 0 || qy < 0 || qx >= f.width || qy >= f.height ||
+						!f.sampled[qy*f.width+qx] {
+						continue
+					}
+					w := kernel[kx] * kernel[ky]
+					sum += w * f.variance[qy*f.width+qx]
+					sumW += w
+				}
+			}
+			res[y*f.width+x] = sum / sumW
+		}
+	})
+
+	return res
+}
+
+// depthDerivatives returns the biggest absolute difference of the depth of every
+// pixel from the depth of its hit neighbours.
+func (f *atrousFilter) depthDerivatives() []float64 {
+	res := make([]float64, len(f.depth))
+
+	for y := range f.height {
+		for x := range f.width {
+			p := y*f.width + x
+			if !f.hit[p] {
+				continue
+			}
+
+			for _, d := range [4][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+				qx, qy := x+d[0], y+d[1]
+				if qx < 0 || qy < 0 || qx >= f.width || qy >= f.height {
+					continue
+				}
+				q := qy*f.width + qx
+				if f.hit[q] {
+					res[p] = max(res[p], math.Abs(f.depth[p]-f.depth[q]))
+				}
+			}
+		}
+	}
+
+	return res
+}
+
+// parallelRows calls `fn` for every row in [0, height) using all CPUs.
+func parallelRows(height int, fn func(y int)) {
+	workers := min(runtime.NumCPU(), height)
+	var wg sync.WaitGroup
+
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for y := w; y < height; y += workers {
+				fn(y)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
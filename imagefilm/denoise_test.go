@@ -0,0 +1,103 @@
+package imagefilm
+
+import (
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/ironsmile/raytracer/aov"
+	"github.com/ironsmile/raytracer/geometry"
+)
+
+// TestDenoiserRemovesNoise checks that the denoiser brings a noisy image closer to
+// the noise-free one without blurring the edge between two surfaces.
+func TestDenoiserRemovesNoise(t *testing.T) {
+	const (
+		width, height = 64, 48
+		spp           = 8
+		edge          = width / 2
+	)
+
+	// The left half of the image is a grey wall facing the camera and the right one
+	// is a red wall at an angle to it, lit differently.
+	surface := func(x int) (clr [3]float64, s aov.Sample) {
+		if x < edge {
+			s.Normal = geometry.NewVector(0, 0, 1)
+			s.Depth = 5
+			s.Albedo = *geometry.NewColor(0.5, 0.5, 0.5)
+			return [3]float64{0.3, 0.3, 0.3}, s
+		}
+		s.Normal = geometry.NewVector(1, 0, 0)
+		s.Depth = 5 + float64(x-edge)*0.1
+		s.Albedo = *geometry.NewColor(0.8, 0.2, 0.2)
+		return [3]float64{0.64, 0.16, 0.16}, s
+	}
+
+	rnd := rand.New(rand.NewSource(5))
+	stats := NewPixelStats(width, height)
+	guide := aov.NewBuffers(width, height, DenoiserGuides)
+
+	for y := range height {
+		for x := range width {
+			clr, s := surface(x)
+			for range spp {
+				var noisy [3]uint16
+				for ch := range noisy {
+					v := clr[ch] * (0.4 + 1.2*rnd.Float64())
+					noisy[ch] = uint16(min(v, 1) * 0xffff)
+				}
+				stats.Add(x, y, color.NRGBA64{R: noisy[0], G: noisy[1], B: noisy[2], A: 0xffff})
+				guide.Add(x, y, &s)
+			}
+		}
+	}
+
+	denoiser, err := NewDenoiser(guide)
+	if err != nil {
+		t.Fatalf("creating denoiser: %s", err)
+	}
+	denoised, err := denoiser.Denoise(&stats)
+	if err != nil {
+		t.Fatalf("denoising: %s", err)
+	}
+
+	rmse := func(rgb []float64) float64 {
+		var sum float64
+		for ind := range width * height {
+			clr, _ := surface(ind % width)
+			for ch := range clr {
+				d := rgb[ind*3+ch] - clr[ch]
+				sum += d * d
+			}
+		}
+		return math.Sqrt(sum / float64(3*width*height))
+	}
+
+	before, after := rmse(stats.mean), rmse(denoised)
+	if after > before/3 {
+		t.Errorf("expected the error to drop at least three times but it went from %f to %f",
+			before, after)
+	}
+
+	// Pixels next to the edge must not be mixed with the other surface.
+	for y := range height {
+		for _, x := range []int{edge - 1, edge} {
+			clr, _ := surface(x)
+			ind := y*width + x
+			if math.Abs(denoised[ind*3+1]-clr[1]) > 0.03 {
+				t.Fatalf("pixel (%d, %d) at the edge has green %f instead of about %f",
+					x, y, denoised[ind*3+1], clr[1])
+			}
+		}
+	}
+}
+
+// TestDenoiserNeedsGuides checks that a denoiser is not created without all of its
+// guides.
+func TestDenoiserNeedsGuides(t *testing.T) {
+	guide := aov.NewBuffers(4, 4, []aov.Kind{aov.KindDepth, aov.KindNormal})
+	if _, err := NewDenoiser(guide); err == nil {
+		t.Errorf("expected an error for a guide without albedo")
+	}
+}
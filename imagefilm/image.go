@@ -0,0 +1,230 @@
+// Package imagefilm accumulates the rendered samples in memory. [Image] writes them
+// to PNG files and [Denoiser] removes the noise from them. It has no cgo
+// dependencies so that the renderer can be embedded without a window. The window
+// is in package film.
+package imagefilm
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"log/slog"
+	"os"
+
+	"github.com/ironsmile/raytracer/utils"
+)
+
+// Image is a film which writes the rendered frames to a PNG file. Samples for every
+// pixel are accumulated and the written pixels are their mean. Accumulation
+// continues across frames until [Image.Reset] is called. So rendering multiple
+// frames of the same scene progressively improves the image.
+type Image struct {
+	width  int
+	height int
+
+	img *image.NRGBA
+
+	// PixelStats holds the running mean and variance of the samples for every
+	// pixel. It provides the PixelError, Noise and SampleCounts methods.
+	PixelStats
+
+	// denoiser is applied to the image before writing it when not nil.
+	denoiser *Denoiser
+
+	// crop is the part of the image which is written. The whole image is written
+	// when it is empty.
+	crop image.Rectangle
+
+	filename string
+
+	// logger receives the errors of writing the image and denoising. Nothing is
+	// logged when it is nil.
+	logger *slog.Logger
+}
+
+func (i *Image) Wait() {
+
+}
+
+func (i *Image) Init(width int, height int) error {
+	i.width = width
+	i.height = height
+
+	i.img = image.NewNRGBA(image.Rect(0, 0, width, height))
+	i.PixelStats = NewPixelStats(width, height)
+
+	return nil
+}
+
+// Reset discards all accumulated samples. This includes the output variables used
+// by the denoiser.
+func (i *Image) Reset() {
+	i.PixelStats.Reset()
+	if i.denoiser != nil {
+		i.denoiser.ResetGuide()
+	}
+}
+
+// SetDenoiser makes the image denoised with `d` every time it is written. Nil
+// disables denoising.
+func (i *Image) SetDenoiser(d *Denoiser) {
+	i.denoiser = d
+}
+
+// SetLogger makes the image log its errors and saved files to `l`. Nil disables
+// logging.
+func (i *Image) SetLogger(l *slog.Logger) {
+	i.logger = l
+}
+
+// SetFilename makes the image written to `filename` from now on.
+func (i *Image) SetFilename(filename string) {
+	i.filename = filename
+}
+
+// SetCrop makes only the pixels in `r` written to the file. An empty rectangle
+// makes the whole image written.
+func (i *Image) SetCrop(r image.Rectangle) {
+	i.crop = r
+}
+
+// SetBackground fills the image with the PNG image in `filename`. Pixels without
+// samples keep their colour from it so that a region rendered with a crop window
+// is composited over a previously rendered frame. It must be called after Init and
+// the background must have the same size as the image.
+func (i *Image) SetBackground(filename string) error {
+	in, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	bg, err := png.Decode(in)
+	if err != nil {
+		return fmt.Errorf("decoding %s: %w", filename, err)
+	}
+
+	if bg.Bounds().Dx() != i.width || bg.Bounds().Dy() != i.height {
+		return fmt.Errorf("%s is %dx%d but the image is %dx%d", filename,
+			bg.Bounds().Dx(), bg.Bounds().Dy(), i.width, i.height)
+	}
+
+	draw.Draw(i.img, i.img.Bounds(), bg, bg.Bounds().Min, draw.Src)
+	return nil
+}
+
+// Image returns the image with the mean of the accumulated samples as of the last
+// finished frame.
+func (i *Image) Image() *image.NRGBA {
+	return i.img
+}
+
+func (i *Image) Width() int {
+	return i.width
+}
+
+func (i *Image) Height() int {
+	return i.height
+}
+
+func (i *Image) DoneFrame() {
+	i.resolve()
+
+	if i.filename == "" {
+		return
+	}
+
+	logger := utils.Logger(i.logger)
+
+	out, err := os.Create(i.filename)
+	if err != nil {
+		logger.Error("cannot create image file", "error", err)
+		return
+	}
+
+	defer func() {
+		out.Close()
+	}()
+
+	var img image.Image = i.img
+	if !i.crop.Empty() {
+		img = i.img.SubImage(i.crop)
+	}
+
+	err = png.Encode(out, img)
+
+	if err != nil {
+		logger.Error("cannot encode image", "file", i.filename, "error", err)
+		return
+	}
+
+	logger.Info("image saved", "file", i.filename)
+}
+
+func (i *Image) StartFrame() {
+
+}
+
+// Set adds a sample with colour `clr` for the pixel at (x, y). Concurrent calls are
+// safe as long as they are for different pixels.
+func (i *Image) Set(x, y int, clr color.Color) error {
+	if x < 0 || y < 0 || x >= i.width || y >= i.height {
+		return fmt.Errorf("pixel (%d, %d) is outside of the image", x, y)
+	}
+
+	i.Add(x, y, clr)
+	return nil
+}
+
+// SaveSampleCounts writes a PNG image in which every pixel shows how many samples
+// were taken for it. See [PixelStats.SampleCounts].
+func (i *Image) SaveSampleCounts(filename string) error {
+	out, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if err := png.Encode(out, i.SampleCounts()); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// resolve writes the mean of the accumulated samples for every pixel in the output
+// image. It is denoised first when there is a denoiser.
+func (i *Image) resolve() {
+	rgb := i.mean
+	if i.denoiser != nil {
+		denoised, err := i.denoiser.Denoise(&i.PixelStats)
+		if err != nil {
+			utils.Logger(i.logger).Error("cannot denoise image", "error", err)
+		} else {
+			rgb = denoised
+		}
+	}
+
+	for ind, n := range i.count {
+		if n == 0 {
+			continue
+		}
+
+		i.img.SetNRGBA(ind%i.width, ind/i.width, color.NRGBA{
+			R: uint8(min(rgb[ind*3], 1) * 255),
+			G: uint8(min(rgb[ind*3+1], 1) * 255),
+			B: uint8(min(rgb[ind*3+2], 1) * 255),
+			A: 255,
+		})
+	}
+}
+
+// NewImage returns an image which is written to `filname` at the end of every
+// frame. With an empty file name the image is kept only in memory. See
+// [Image.Image].
+func NewImage(filname string) *Image {
+	img := new(Image)
+	img.filename = filname
+	return img
+}
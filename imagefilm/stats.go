@@ -0,0 +1,221 @@
+package imagefilm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// minNoiseLuminance is the luminance below which pixels are considered black for
+// the purposes of error estimation. It keeps the relative error of dark pixels
+// from dominating the estimates.
+const minNoiseLuminance = 0.01
+
+// PixelStats tracks the running mean colour and the variance of the luminance of
+// the samples for every pixel. It uses Welford's online algorithm which is stable
+// even for a great number of samples. Concurrent calls to [PixelStats.Add] are
+// safe as long as they are for different pixels.
+type PixelStats struct {
+	width  int
+	height int
+
+	// mean holds the mean red, green and blue for every pixel.
+	mean []float64
+
+	// lumM2 holds the sum of the squared differences from the mean luminance.
+	lumM2 []float64
+
+	// count is the number of samples for every pixel.
+	count []uint32
+}
+
+// NewPixelStats returns statistics without samples for an image with the given
+// size.
+func NewPixelStats(width, height int) PixelStats {
+	return PixelStats{
+		width:  width,
+		height: height,
+		mean:   make([]float64, width*height*3),
+		lumM2:  make([]float64, width*height),
+		count:  make([]uint32, width*height),
+	}
+}
+
+// Add records a sample for the pixel at (x, y) and returns the new mean colour of
+// that pixel.
+func (p *PixelStats) Add(x, y int, clr color.Color) (r, g, b float64) {
+	ri, gi, bi, _ := clr.RGBA()
+	sr, sg, sb := float64(ri)/0xffff, float64(gi)/0xffff, float64(bi)/0xffff
+
+	ind := y*p.width + x
+	mean := p.mean[ind*3 : ind*3+3]
+
+	p.count[ind]++
+	n := float64(p.count[ind])
+
+	oldLum := luminance(mean[0], mean[1], mean[2])
+	mean[0] += (sr - mean[0]) / n
+	mean[1] += (sg - mean[1]) / n
+	mean[2] += (sb - mean[2]) / n
+
+	lum := luminance(sr, sg, sb)
+	p.lumM2[ind] += (lum - oldLum) * (lum - luminance(mean[0], mean[1], mean[2]))
+
+	return mean[0], mean[1], mean[2]
+}
+
+// Reset discards all samples.
+func (p *PixelStats) Reset() {
+	clear(p.mean)
+	clear(p.lumM2)
+	clear(p.count)
+}
+
+// Samples returns the number of samples for the pixel at (x, y).
+func (p *PixelStats) Samples(x, y int) uint32 {
+	return p.count[y*p.width+x]
+}
+
+// TotalSamples returns the number of samples for all pixels.
+func (p *PixelStats) TotalSamples() uint64 {
+	var total uint64
+	for _, n := range p.count {
+		total += uint64(n)
+	}
+	return total
+}
+
+// PixelError returns the standard error of the mean luminance of the pixel at
+// (x, y) relative to that luminance, together with the number of samples for the
+// pixel. The error is +Inf for pixels with less than two samples.
+func (p *PixelStats) PixelError(x, y int) (float64, int) {
+	ind := y*p.width + x
+	n := p.count[ind]
+	if n < 2 {
+		return math.Inf(1), int(n)
+	}
+
+	mean := p.mean[ind*3 : ind*3+3]
+	variance := p.lumM2[ind] / float64(n-1)
+	stdErr := math.Sqrt(max(variance, 0) / float64(n))
+
+	return stdErr / max(luminance(mean[0], mean[1], mean[2]), minNoiseLuminance), int(n)
+}
+
+// Noise returns an estimate of how noisy the image is and the number of pixels from
+// which it is estimated. It is the relative error of every pixel as returned by
+// PixelError, averaged over all pixels with at least two samples. Zero is returned
+// when there are no such pixels.
+func (p *PixelStats) Noise() (float64, int) {
+	var (
+		total  float64
+		pixels int
+	)
+
+	for y := range p.height {
+		for x := range p.width {
+			pixErr, n := p.PixelError(x, y)
+			if n < 2 {
+				continue
+			}
+			total += pixErr
+			pixels++
+		}
+	}
+
+	if pixels == 0 {
+		return 0, 0
+	}
+	return total / float64(pixels), pixels
+}
+
+// SampleCounts returns a grayscale image in which every pixel is the number of
+// samples taken for it. Counts are scaled so that the pixel with the most samples
+// is white.
+func (p *PixelStats) SampleCounts() *image.Gray16 {
+	img := image.NewGray16(image.Rect(0, 0, p.width, p.height))
+
+	var maxCount uint32
+	for _, n := range p.count {
+		maxCount = max(maxCount, n)
+	}
+	if maxCount == 0 {
+		return img
+	}
+
+	for ind, n := range p.count {
+		img.SetGray16(ind%p.width, ind/p.width, color.Gray16{
+			Y: uint16(uint64(n) * 0xffff / uint64(maxCount)),
+		})
+	}
+
+	return img
+}
+
+// statsMagic starts the binary form of PixelStats. Its last byte is the version of
+// the format.
+var statsMagic = [4]byte{'p', 'x', 's', 1}
+
+// MarshalBinary implements encoding.BinaryMarshaler. It returns the accumulated
+// samples of all pixels so that they can be restored with UnmarshalBinary after a
+// restart of the program.
+func (p *PixelStats) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Grow(len(statsMagic) + 8 + 8*len(p.mean) + 8*len(p.lumM2) + 4*len(p.count))
+	buf.Write(statsMagic[:])
+
+	for _, data := range []any{uint32(p.width), uint32(p.height), p.mean, p.lumM2, p.count} {
+		if err := binary.Write(&buf, binary.LittleEndian, data); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. It replaces all samples
+// with the ones in `data` which must be for an image with the same size.
+func (p *PixelStats) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+
+	var (
+		magic         [4]byte
+		width, height uint32
+	)
+	for _, v := range []any{&magic, &width, &height} {
+		if err := binary.Read(r, binary.LittleEndian, v); err != nil {
+			return fmt.Errorf("reading pixel samples: %w", err)
+		}
+	}
+
+	if magic != statsMagic {
+		return fmt.Errorf("unknown format of the pixel samples")
+	}
+	if int(width) != p.width || int(height) != p.height {
+		return fmt.Errorf("pixel samples are for a %dx%d image but this one is %dx%d",
+			width, height, p.width, p.height)
+	}
+
+	restored := NewPixelStats(p.width, p.height)
+	for _, v := range []any{restored.mean, restored.lumM2, restored.count} {
+		if err := binary.Read(r, binary.LittleEndian, v); err != nil {
+			return fmt.Errorf("reading pixel samples: %w", err)
+		}
+	}
+	if r.Len() != 0 {
+		return fmt.Errorf("%d unexpected bytes after the pixel samples", r.Len())
+	}
+
+	copy(p.mean, restored.mean)
+	copy(p.lumM2, restored.lumM2)
+	copy(p.count, restored.count)
+	return nil
+}
+
+// luminance returns the relative luminance of a linear RGB colour.
+func luminance(r, g, b float64) float64 {
+	return 0.2126*r + 0.7152*g + 0.0722*b
+}
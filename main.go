@@ -1,22 +1,35 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"image"
+	"image/png"
 	"log"
+	"log/slog"
+	"math"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"runtime"
 	"runtime/pprof"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/ironsmile/raytracer/aov"
+	"github.com/ironsmile/raytracer/camera"
+	"github.com/ironsmile/raytracer/distributed"
 	"github.com/ironsmile/raytracer/engine"
 	"github.com/ironsmile/raytracer/film"
+	"github.com/ironsmile/raytracer/imagefilm"
 	"github.com/ironsmile/raytracer/sampler"
 	"github.com/ironsmile/raytracer/scene"
+	"github.com/ironsmile/raytracer/server"
 )
 
 func init() {
@@ -52,6 +65,147 @@ var (
 		"scene to render. Possible values: teapot, car")
 	debugMode = flag.Bool("D", false,
 		"debug mode, will print diagnostics information")
+	renderMode = flag.String("render-mode", "shaded",
+		"what to paint for every pixel. Possible values: shaded, heatmap.\n"+
+			"heatmap shows the acceleration structure traversal cost of primary rays")
+	printStats = flag.Bool("stats", false,
+		"collect ray traversal statistics and print them at the end of a file render")
+	statsJSON = flag.String("stats-json", "",
+		"file render: collect render statistics and write them as a JSON report in\n"+
+			"this file at the end. \"-\" writes them to the standard output")
+	logLevel = flag.String("log-level", "info",
+		"minimum level of the logged messages. Possible values: debug, info, warn,\n"+
+			"error")
+	logFormat = flag.String("log-format", "text",
+		"format of the log written to the standard error. Possible values: text,\n"+
+			"json")
+	cameraName = flag.String("camera", "pinhole",
+		"file render: camera projection. Possible values: pinhole, orthographic,\n"+
+			"fisheye-equidistant, fisheye-equisolid, equirectangular")
+	cameraFOV = flag.Float64("fov", 0,
+		"file render: field of view in degrees across the shorter side of the image\n"+
+			"for pinhole and fisheye cameras. Zero means 90 for pinhole and 180 for\n"+
+			"fisheye cameras")
+	orthoSize = flag.Float64("ortho-size", 0,
+		"file render: size in world units of the shorter side of the area seen by\n"+
+			"the orthographic camera. Zero matches the default pinhole camera")
+	cameraState = flag.String("camera-state", "",
+		"camera projection, position and orientation as printed by pressing C in\n"+
+			"the interactive window, for example \"projection=pinhole position=0,0,-5\n"+
+			"lookAt=0,0,1 up=0,1,0 fov=90\". It replaces the camera of the scene and\n"+
+			"the -camera, -fov and -ortho-size flags")
+	stereoLayout = flag.String("stereo", "",
+		"file render: render the left and right eye in one image for VR. Possible\n"+
+			"values: side-by-side, top-bottom. Empty means a single camera. It is also\n"+
+			"applied to the camera of -animation")
+	stereoIPD = flag.Float64("ipd", camera.DefaultIPD,
+		"file render: distance between the eyes of the stereo camera in world units")
+	stereoConvergence = flag.Float64("convergence", 0,
+		"file render: distance at which the eyes of the stereo camera converge.\n"+
+			"Zero means infinity")
+	stereoODS = flag.Bool("ods", false,
+		"file render: omni-directional stereo for 360 degree panoramas. Use it with\n"+
+			"-stereo and -camera equirectangular")
+	samplerName = flag.String("sampler", sampler.DefaultKind.String(),
+		"how sample positions are generated. Possible values: independent, stratified,\n"+
+			"halton, sobol")
+	samplesPerPixel = flag.Int("spp", sampler.DefaultSamplesPerPixel,
+		"number of samples taken for every pixel in a single pass")
+	tileSize = flag.Int("tile-size", sampler.DefaultTileSize,
+		"side in pixels of the square tiles in which the screen is split between\n"+
+			"rendering workers")
+	seed = flag.Uint64("seed", 0,
+		"seed for all random decisions during sampling. Renders with the same seed\n"+
+			"and settings produce identical images")
+	targetSPP = flag.Int("target-spp", 0,
+		"progressive file render: keep rendering passes until this many samples\n"+
+			"per pixel are taken")
+	timeBudget = flag.Duration("time-budget", 0,
+		"progressive file render: keep rendering passes until this much time passes")
+	noiseThreshold = flag.Float64("noise-threshold", 0,
+		"progressive file render: keep rendering passes until the estimated relative\n"+
+			"noise falls below this value")
+	adaptiveThreshold = flag.Float64("adaptive-threshold", 0,
+		"progressive file render: stop sampling pixels once their estimated relative\n"+
+			"error falls below this value and give their samples to the rest")
+	animation = flag.String("animation", "",
+		"file render: JSON file with the keyframes of an animation of the camera and\n"+
+			"the objects in the scene. Used together with -frames. In the interactive\n"+
+			"window the P key plays its camera path")
+	recordPath = flag.String("record-path", "camera-path.json",
+		"interactive: file in which the camera path recorded with the K key is saved.\n"+
+			"It is an animation which may be rendered with -animation and -frames")
+	showOverlay = flag.Bool("overlay", false,
+		"window: show the statistics overlay from the start. It is toggled with the O key")
+	hotReload = flag.Bool("hot-reload", false,
+		"interactive: watch the .obj and .mtl files of the models in the scene and\n"+
+			"render it again when they change. The rest of the scene is defined in code\n"+
+			"and is not reloaded. Models which failed to load are not watched")
+	preview = flag.Int("preview", 8,
+		"interactive: after the camera moves the image is first shown with a single\n"+
+			"sample for every block with this side in pixels and then refined until the\n"+
+			"full resolution. 1 disables the preview")
+	pathFPS = flag.Float64("path-fps", scene.DefaultRecordFPS,
+		"interactive: frames for every second of the recorded and played camera paths")
+	frames = flag.String("frames", "",
+		"file render: render the frames start:end of the -animation. Every frame is\n"+
+			"written in <name>_<frame>.png, with the frame number padded to four\n"+
+			"digits. Frames which are already on disk are skipped")
+	checkpoint = flag.String("checkpoint", "",
+		"file render: periodically save the state of a progressive rendering to this\n"+
+			"file so that it can be continued with -resume. It is also saved when the\n"+
+			"rendering is interrupted with Ctrl-C")
+	checkpointInterval = flag.Duration("checkpoint-interval", time.Minute,
+		"file render: minimum time between two checkpoints. Zero means after every pass")
+	resume = flag.Bool("resume", false,
+		"file render: continue the progressive rendering from the -checkpoint file.\n"+
+			"The scene and the rest of the options must be the same as before")
+	sampleCounts = flag.String("sample-counts", "",
+		"file render: write a PNG image with the number of samples of every pixel\n"+
+			"to this file")
+	aovNames = flag.String("aovs", "",
+		"file render: comma separated list of extra outputs for the surfaces seen\n"+
+			"by the camera. Possible values: depth, normal, albedo, position, uv,\n"+
+			"primitive-id, material-id or all")
+	aovFormat = flag.String("aov-format", "png",
+		"file render: format of the extra outputs. With \"png\" every one of them is\n"+
+			"written next to the image in <name>_<output>.png. With \"exr\" all of\n"+
+			"them are layers of <name>_aovs.exr. It also has the layers used by\n"+
+			"the denoiser with -denoise")
+	denoise = flag.Bool("denoise", false,
+		"remove the noise from the rendered image. In the window every presented\n"+
+			"frame is denoised")
+	crop = flag.String("crop", "",
+		"render only the pixels in this rectangle, given as x0,y0,x1,y1. Values with\n"+
+			"a decimal point, like 0.5,0,1.0,0.5, are fractions of the image size.\n"+
+			"Otherwise they are pixels. The camera framing is that of the full image")
+	cropMode = flag.String("crop-mode", "crop",
+		"file render: how the image is written with -crop. With \"crop\" only the\n"+
+			"rectangle is written. With \"composite\" it is rendered over the full\n"+
+			"size image already in the -filename file")
+	serverAddr = flag.String("server", "",
+		"run a render server with a JSON HTTP job API on this address, for example\n"+
+			"localhost:8080. See the documentation of the server package for the API")
+	serverWorkers = flag.Int("server-workers", 1,
+		"server: number of jobs rendered at the same time")
+	serverAnimations = flag.String("server-animations", filepath.Join("data", "animations"),
+		"server: directory with the animation files which jobs may use")
+	workerAddr = flag.String("worker", "",
+		"run a distributed rendering worker on this address, for example :6465.\n"+
+			"It renders tiles for a -coordinator. Animations are loaded from\n"+
+			"the -server-animations directory")
+	coordinator = flag.String("coordinator", "",
+		"file render: render the image on these comma separated workers, for example\n"+
+			"http://10.0.0.2:6465,http://10.0.0.3:6465. The -animation is the name\n"+
+			"of a file in the animations directory of the workers")
+	coordinatorTileSize = flag.Int("coordinator-tile-size", distributed.DefaultTileSize,
+		"coordinator: side in pixels of the tiles sent to the workers")
+	tileTimeout = flag.Duration("tile-timeout", 5*time.Minute,
+		"coordinator: time after which a worker which has not returned its tile is\n"+
+			"considered dead and the tile is given to another worker. Zero means no\n"+
+			"timeout")
+	usePackets = flag.Bool("packets", false,
+		"trace primary and shadow rays for neighbouring pixels together as ray packets")
 	debugRays = flag.String("debug-rays", "",
 		"file nam which contains a list of rays which will be added to the scene\n"+
 			"with deubgging purposes. For the format of the file see the code\n"+
@@ -62,6 +216,12 @@ func main() {
 
 	flag.Parse()
 
+	logger, err := newLogger(*logLevel, *logFormat)
+	if err != nil {
+		log.Fatalf("%s\n", err)
+	}
+	slog.SetDefault(logger)
+
 	if !slices.Contains(scene.PossibleScenes, *sceneName) {
 		log.Fatalf("scene must be one of: %s", strings.Join(scene.PossibleScenes, ", "))
 	}
@@ -79,14 +239,47 @@ func main() {
 		defer pprof.StopCPUProfile()
 	}
 
+	mode, err := engine.ParseRenderMode(*renderMode)
+	if err != nil {
+		log.Fatalf("%s\n", err)
+	}
+
+	samplerKind, err := sampler.ParseKind(*samplerName)
+	if err != nil {
+		log.Fatalf("%s\n", err)
+	}
+	samplerCfg := sampler.Config{
+		Kind:              samplerKind,
+		SamplesPerPixel:   *samplesPerPixel,
+		TileSize:          *tileSize,
+		AdaptiveThreshold: *adaptiveThreshold,
+		Seed:              *seed,
+	}
+
+	if *crop != "" {
+		samplerCfg.Crop, err = parseCrop(*crop, *renderWidth, *renderHeight)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
+		if *cropMode != "crop" && *cropMode != "composite" {
+			log.Fatalf("unknown crop mode %q\n", *cropMode)
+		}
+	}
+
 	if *debugRays != "" {
 		scene.SetDebugRaysFile(*debugRays)
 	}
 
-	if *filename != "" {
-		infileRenderer()
+	if *serverAddr != "" {
+		renderServer()
+	} else if *workerAddr != "" {
+		renderWorker()
+	} else if *coordinator != "" {
+		renderDistributed(samplerCfg)
+	} else if *filename != "" {
+		infileRenderer(mode, samplerCfg)
 	} else {
-		vulkanWindowRenderer()
+		vulkanWindowRenderer(mode, samplerCfg)
 	}
 
 	if *memprofile != "" {
@@ -100,28 +293,592 @@ func main() {
 	}
 }
 
-func infileRenderer() {
-	output := film.NewImage(*filename)
+func infileRenderer(mode engine.RenderMode, samplerCfg sampler.Config) {
+	var (
+		anim        *scene.Animation
+		first, last int
+	)
+	if *frames != "" || *animation != "" {
+		if *frames == "" || *animation == "" {
+			log.Fatalf("-frames and -animation must be used together\n")
+		}
+		if *checkpoint != "" {
+			log.Fatalf("checkpoints cannot be used for animations\n")
+		}
+		if !samplerCfg.Crop.Empty() && *cropMode == "composite" {
+			log.Fatalf("-crop-mode composite cannot be used for animations\n")
+		}
+
+		// The camera of every frame comes from the animation. Only the stereo
+		// flags are applied to it.
+		for _, name := range []string{"camera", "fov", "ortho-size", "camera-state"} {
+			if isFlagSet(name) {
+				log.Fatalf("-%s cannot be used for animations, set the camera in the "+
+					"animation file instead\n", name)
+			}
+		}
+
+		var err error
+		first, last, err = parseFrames(*frames)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
+		anim, err = scene.LoadAnimation(*animation)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
+	}
+
+	output := imagefilm.NewImage(*filename)
 	if err := output.Init(*renderWidth, *renderHeight); err != nil {
 		log.Fatalf("%s\n", err)
 	}
 
-	smpl := sampler.NewSimple(output.Width(), output.Height(), output)
-	cam := scene.GetCamera(float64(output.Width()), float64(output.Height()))
+	if !samplerCfg.Crop.Empty() {
+		if *cropMode == "composite" {
+			if err := output.SetBackground(*filename); err != nil {
+				log.Fatalf("loading the image for compositing: %s\n", err)
+			}
+		} else {
+			output.SetCrop(samplerCfg.Crop)
+		}
+	}
+
+	width, height := float64(output.Width()), float64(output.Height())
+	smpl := sampler.NewSimple(output.Width(), output.Height(), output, samplerCfg)
+	cam := newCamera(width, height)
 	tracer := engine.New(smpl)
+	tracer.SetLogger(slog.Default())
 	tracer.SetTarget(output, cam)
 	tracer.Scene.InitScene(*sceneName)
 	tracer.ShowBBoxes = *showBBoxes
+	tracer.Mode = mode
+	tracer.CollectStats = *printStats || *statsJSON != ""
+	tracer.UsePackets = *usePackets
+
+	var aovKinds []aov.Kind
+	if *aovNames != "" {
+		kinds, err := aov.ParseKinds(*aovNames)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
+		if *aovFormat != "png" && *aovFormat != "exr" {
+			log.Fatalf("unknown extra outputs format %q\n", *aovFormat)
+		}
+		aovKinds = kinds
+	}
+
+	collected := aovKinds
+	if *denoise {
+		for _, kind := range imagefilm.DenoiserGuides {
+			if !slices.Contains(collected, kind) {
+				collected = append(slices.Clone(collected), kind)
+			}
+		}
+	}
+	if len(collected) > 0 {
+		tracer.AOVs = aov.NewBuffers(output.Width(), output.Height(), collected)
+	}
+
+	if *denoise {
+		denoiser, err := imagefilm.NewDenoiser(tracer.AOVs)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
+		output.SetDenoiser(denoiser)
+	}
+
+	progressive := engine.Progressive{
+		TargetSPP:          *targetSPP,
+		TimeBudget:         *timeBudget,
+		NoiseThreshold:     *noiseThreshold,
+		CheckpointFile:     *checkpoint,
+		CheckpointInterval: *checkpointInterval,
+	}
+
+	if *resume {
+		if *checkpoint == "" {
+			log.Fatalf("-resume needs a -checkpoint file\n")
+		}
+		c, err := engine.LoadCheckpoint(*checkpoint)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
+		progressive.Resume = c
+	}
+
+	if *targetSPP > 0 || *timeBudget > 0 || *noiseThreshold > 0 {
+		progressive.Interrupt = interruptSignal()
+	} else if *checkpoint != "" {
+		log.Fatalf("checkpoints need progressive rendering, see -target-spp\n")
+	}
+
+	if anim == nil {
+		renderFrame(tracer, output, *filename, *sampleCounts, aovKinds, progressive)
+	}
+
+	for frame := first; anim != nil && frame <= last; frame++ {
+		imageFile := frameFilename(*filename, frame)
+		if _, err := os.Stat(imageFile); err == nil {
+			slog.Info("frame already rendered, skipping it", "frame", frame, "file", imageFile)
+			continue
+		}
+
+		slog.Info("rendering frame", "frame", frame, "file", imageFile)
+		if err := tracer.Scene.Animate(anim, float64(frame)); err != nil {
+			log.Fatalf("%s\n", err)
+		}
+
+		// The frame is written in a temporary file until it is finished so that
+		// an interrupted frame is not skipped the next time.
+		partialFile := imageFile + ".partial"
+		output.Reset()
+		output.SetFilename(partialFile)
+		if tracer.AOVs != nil {
+			tracer.AOVs.Reset()
+		}
+
+		tracer.Sampler = sampler.NewSimple(output.Width(), output.Height(), output, samplerCfg)
+		state := anim.CameraStateAt(float64(frame))
+		tracer.SetTarget(output, newCameraWith(state.Options(width, height)))
+
+		countsFile := ""
+		if *sampleCounts != "" {
+			countsFile = frameFilename(*sampleCounts, frame)
+		}
+
+		res := renderFrame(tracer, output, imageFile, countsFile, aovKinds, progressive)
+		if res.StoppedBy == "interrupt" {
+			slog.Info("frame interrupted", "frame", frame, "partialFile", partialFile)
+			break
+		}
+
+		if err := os.Rename(partialFile, imageFile); err != nil {
+			log.Fatalf("%s\n", err)
+		}
+		slog.Info("frame saved", "frame", frame, "file", imageFile)
+	}
+
+	if *printStats {
+		tracer.PrintStats(os.Stdout)
+	}
+	if *statsJSON != "" {
+		if err := writeReport(tracer.Report(), *statsJSON); err != nil {
+			log.Fatalf("writing statistics: %s\n", err)
+		}
+	}
+}
+
+// newCamera returns the camera of the demo scenes with the projection, field of
+// view, size and stereo options from the command line flags.
+func newCamera(width, height float64) camera.Camera {
+	var opts camera.Options
+	if state := parseCameraState(); state != nil {
+		opts = state.Options(width, height)
+	} else {
+		proj, err := camera.ParseProjection(*cameraName)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
+
+		opts = scene.CameraOptions(width, height)
+		opts.Projection = proj
+		opts.FOV = *cameraFOV
+		opts.Size = *orthoSize
+	}
+
+	return newCameraWith(opts)
+}
+
+// newCameraWith returns the camera with options `opts` and the stereo options from
+// the command line flags.
+func newCameraWith(opts camera.Options) camera.Camera {
+	if *stereoLayout == "" {
+		if *stereoODS {
+			log.Fatalf("-ods needs a -stereo layout\n")
+		}
+
+		cam, err := camera.New(opts)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
+		return cam
+	}
+
+	layout, err := camera.ParseStereoLayout(*stereoLayout)
+	if err != nil {
+		log.Fatalf("%s\n", err)
+	}
+
+	cam, err := camera.NewStereo(opts, camera.StereoOptions{
+		Layout:      layout,
+		IPD:         *stereoIPD,
+		Convergence: *stereoConvergence,
+		ODS:         *stereoODS,
+	})
+	if err != nil {
+		log.Fatalf("%s\n", err)
+	}
+	return cam
+}
+
+// isFlagSet returns true when the flag `name` was given on the command line.
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// parseCameraState returns the camera state from the -camera-state flag or nil
+// when it is not set.
+func parseCameraState() *camera.State {
+	if *cameraState == "" {
+		return nil
+	}
+
+	var state camera.State
+	if err := state.UnmarshalText([]byte(*cameraState)); err != nil {
+		log.Fatalf("%s\n", err)
+	}
+	return &state
+}
+
+// writeReport writes `r` as JSON in `filename` or to the standard output when it
+// is "-".
+func writeReport(r engine.Report, filename string) error {
+	if filename == "-" {
+		return r.WriteJSON(os.Stdout)
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := r.WriteJSON(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// newLogger returns a logger which writes to the standard error with the given
+// minimum level and format. See the -log-level and -log-format flags.
+func newLogger(level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("unknown log level %q", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(os.Stderr, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+}
+
+// renderServer serves the render job API until the program is interrupted.
+func renderServer() {
+	srv := server.New(server.Config{
+		Workers:       *serverWorkers,
+		AnimationsDir: *serverAnimations,
+		Logger:        slog.Default(),
+	})
+	defer srv.Close()
+
+	httpSrv := &http.Server{Addr: *serverAddr, Handler: srv}
+	go func() {
+		<-interruptSignal()
+		httpSrv.Close()
+	}()
+
+	slog.Info("render server listening", "address", *serverAddr)
+	if err := httpSrv.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatalf("render server: %s\n", err)
+	}
+}
+
+// renderWorker renders tiles for coordinators until the program is interrupted.
+func renderWorker() {
+	worker := &distributed.Worker{
+		AnimationsDir: *serverAnimations,
+		Logger:        slog.Default(),
+	}
+	httpSrv := &http.Server{Addr: *workerAddr, Handler: worker}
+	go func() {
+		<-interruptSignal()
+		httpSrv.Close()
+	}()
+
+	slog.Info("render worker listening", "address", *workerAddr)
+	if err := httpSrv.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatalf("render worker: %s\n", err)
+	}
+}
+
+// renderDistributed renders the image, or every frame of the animation, on the
+// workers of the -coordinator flag and writes it in -filename.
+func renderDistributed(samplerCfg sampler.Config) {
+	if *filename == "" {
+		log.Fatalf("-coordinator needs a -filename\n")
+	}
+	if (*frames == "") != (*animation == "") {
+		log.Fatalf("-frames and -animation must be used together\n")
+	}
+
+	// The workers render only what a distributed.Job describes. The camera comes
+	// from the scene or the animation.
+	for _, name := range []string{
+		"crop", "crop-mode", "render-mode", "show-bboxes",
+		"camera", "fov", "ortho-size", "camera-state",
+		"stereo", "ipd", "convergence", "ods",
+		"adaptive-threshold", "time-budget", "noise-threshold",
+		"checkpoint", "resume", "denoise", "aovs", "sample-counts", "stats-json",
+	} {
+		if isFlagSet(name) {
+			log.Fatalf("-%s cannot be used with -coordinator\n", name)
+		}
+	}
+
+	spp := *targetSPP
+	if spp <= 0 {
+		spp = samplerCfg.SamplesPerPixel
+	}
+
+	job := distributed.Job{
+		Scene:          *sceneName,
+		Animation:      *animation,
+		Width:          *renderWidth,
+		Height:         *renderHeight,
+		SPP:            spp,
+		Sampler:        samplerCfg.Kind,
+		SamplesPerPass: samplerCfg.SamplesPerPixel,
+		Seed:           samplerCfg.Seed,
+	}
+
+	c := &distributed.Coordinator{
+		Workers:     strings.Split(*coordinator, ","),
+		TileSize:    *coordinatorTileSize,
+		TileTimeout: *tileTimeout,
+		Logger:      slog.Default(),
+	}
+
+	first, last := 0, 0
+	if *frames != "" {
+		var err error
+		first, last, err = parseFrames(*frames)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-interruptSignal()
+		cancel()
+	}()
+
+	for frame := first; frame <= last; frame++ {
+		job.Frame = float64(frame)
+		imageFile := *filename
+		if *frames != "" {
+			imageFile = frameFilename(*filename, frame)
+		}
+
+		img, err := c.Render(ctx, job)
+		if err != nil {
+			log.Fatalf("rendering %s: %s\n", imageFile, err)
+		}
+
+		f, err := os.Create(imageFile)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
+		if err := png.Encode(f, img); err != nil {
+			log.Fatalf("writing %s: %s\n", imageFile, err)
+		}
+		if err := f.Close(); err != nil {
+			log.Fatalf("writing %s: %s\n", imageFile, err)
+		}
+	}
+}
+
+// renderFrame renders a single image with `tracer` in `output`. The sample counts
+// and extra outputs are written next to `imageFile` unless `countsFile` is empty
+// or there are no `aovKinds`. Progressive rendering is used when `progressive`
+// has any limits. Its result is returned, otherwise the result is empty.
+func renderFrame(
+	tracer *engine.Engine,
+	output *imagefilm.Image,
+	imageFile, countsFile string,
+	aovKinds []aov.Kind,
+	progressive engine.Progressive,
+) engine.ProgressiveResult {
+	var res engine.ProgressiveResult
 
 	renderTimer := time.Now()
-	tracer.Render()
-	fmt.Printf("Rendering finished: %s\n", time.Since(renderTimer))
+	if progressive.TargetSPP > 0 || progressive.TimeBudget > 0 || progressive.NoiseThreshold > 0 {
+		var err error
+		res, err = tracer.RenderProgressive(progressive)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
+		slog.Info("progressive rendering done",
+			"passes", res.Passes,
+			"samplesPerPixel", res.SamplesPerPixel,
+			"noise", res.Noise,
+			"stoppedBy", res.StoppedBy,
+		)
+	} else {
+		tracer.Render()
+		if err := tracer.Err(); err != nil {
+			log.Fatalf("%s\n", err)
+		}
+	}
+	slog.Info("rendering finished", "duration", time.Since(renderTimer))
 
-	smpl.Stop()
+	tracer.Sampler.Stop()
 	output.Wait()
+
+	if countsFile != "" {
+		if err := output.SaveSampleCounts(countsFile); err != nil {
+			log.Fatalf("saving sample counts: %s\n", err)
+		}
+	}
+
+	if len(aovKinds) > 0 {
+		if err := saveAOVs(tracer.AOVs, aovKinds, imageFile, *aovFormat); err != nil {
+			log.Fatalf("saving extra outputs: %s\n", err)
+		}
+	}
+
+	return res
 }
 
-func vulkanWindowRenderer() {
+// parseFrames parses a range of frames in the format of the -frames flag. Both
+// ends are included.
+func parseFrames(s string) (first, last int, err error) {
+	start, end, found := strings.Cut(s, ":")
+	if !found {
+		return 0, 0, fmt.Errorf("frames %q must be start:end", s)
+	}
+
+	first, err = strconv.Atoi(start)
+	if err != nil {
+		return 0, 0, fmt.Errorf("frames %q: %w", s, err)
+	}
+	last, err = strconv.Atoi(end)
+	if err != nil {
+		return 0, 0, fmt.Errorf("frames %q: %w", s, err)
+	}
+
+	if first < 0 || last < first {
+		return 0, 0, fmt.Errorf("frames %q must be a non-empty range of non-negative numbers", s)
+	}
+	return first, last, nil
+}
+
+// frameFilename returns the name of the file for `frame` of an animation written
+// in `filename`. The frame number is appended to its name, for example
+// "out.png" becomes "out_0012.png" for frame 12.
+func frameFilename(filename string, frame int) string {
+	ext := filepath.Ext(filename)
+	return fmt.Sprintf("%s_%04d%s", strings.TrimSuffix(filename, ext), frame, ext)
+}
+
+// interruptSignal returns a channel which is closed on the first SIGINT. The
+// signal is handled only once so a second Ctrl-C kills the program as usual.
+func interruptSignal() <-chan struct{} {
+	interrupt := make(chan struct{})
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+
+	go func() {
+		<-sig
+		signal.Stop(sig)
+		slog.Info("interrupted, stopping the rendering. Press Ctrl-C again to exit now")
+		close(interrupt)
+	}()
+
+	return interrupt
+}
+
+// parseCrop parses a crop window in the format of the -crop flag for an image with
+// the given size. The returned rectangle is in pixels and is clipped to the image.
+func parseCrop(s string, width, height int) (image.Rectangle, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return image.Rectangle{}, fmt.Errorf("crop window %q must be x0,y0,x1,y1", s)
+	}
+
+	var (
+		values     [4]float64
+		normalized bool
+	)
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return image.Rectangle{}, fmt.Errorf("crop window %q: %w", s, err)
+		}
+		values[i] = v
+		normalized = normalized || strings.Contains(part, ".")
+	}
+
+	if normalized {
+		values[0] *= float64(width)
+		values[1] *= float64(height)
+		values[2] *= float64(width)
+		values[3] *= float64(height)
+	}
+
+	r := image.Rect(
+		int(math.Floor(values[0])), int(math.Floor(values[1])),
+		int(math.Ceil(values[2])), int(math.Ceil(values[3])),
+	).Intersect(image.Rect(0, 0, width, height))
+
+	if r.Empty() {
+		return image.Rectangle{}, fmt.Errorf("crop window %q has no pixels in the image", s)
+	}
+	return r, nil
+}
+
+// saveAOVs writes the extra outputs `kinds` from `buffers` next to the image
+// `imageFile` in the given format. See the -aov-format flag.
+func saveAOVs(buffers *aov.Buffers, kinds []aov.Kind, imageFile, format string) error {
+	base := strings.TrimSuffix(imageFile, filepath.Ext(imageFile))
+
+	switch format {
+	case "exr":
+		name := base + "_aovs.exr"
+		if err := buffers.SaveEXR(name); err != nil {
+			return err
+		}
+		slog.Info("extra outputs saved", "file", name)
+	case "png":
+		for _, kind := range kinds {
+			name := fmt.Sprintf("%s_%s.png", base, kind)
+			if err := buffers.SavePNG(kind, name); err != nil {
+				return err
+			}
+			slog.Info("extra output saved", "kind", kind, "file", name)
+		}
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+
+	return nil
+}
+
+func vulkanWindowRenderer(mode engine.RenderMode, samplerCfg sampler.Config) {
 	args := film.VulkanAppArgs{
 		Debug:       *debugMode,
 		Fullscreen:  *fullscreen,
@@ -133,6 +890,24 @@ func vulkanWindowRenderer() {
 		FPSCap:      *fpsCap,
 		ShowFPS:     *showFPS,
 		SceneName:   *sceneName,
+		RenderMode:  mode,
+		UsePackets:  *usePackets,
+		Sampler:     samplerCfg,
+		Denoise:     *denoise,
+		Camera:      parseCameraState(),
+		RecordFile:  *recordPath,
+		PathFPS:     *pathFPS,
+		HotReload:   *hotReload,
+		Preview:     *preview,
+		Overlay:     *showOverlay,
+	}
+
+	if *animation != "" {
+		anim, err := scene.LoadAnimation(*animation)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
+		args.CameraPath = anim
 	}
 
 	app := film.NewVulkanWindow(args)
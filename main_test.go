@@ -1,10 +1,15 @@
 package main
 
 import (
+	"bytes"
+	"image"
+	"log/slog"
+	"os"
+	"runtime"
 	"testing"
 
 	"github.com/ironsmile/raytracer/engine"
-	"github.com/ironsmile/raytracer/film"
+	"github.com/ironsmile/raytracer/imagefilm"
 	"github.com/ironsmile/raytracer/sampler"
 	"github.com/ironsmile/raytracer/scene"
 )
@@ -12,15 +17,26 @@ import (
 // The benchmark result without the sampler is 806732319 ns/op
 // Latest benchmark for the sampler            1130174954 ns/op
 func BenchmarkImageCreation(t *testing.B) {
-	output := film.NewImage("/dev/null")
+	benchmarkImageCreation(t, false)
+}
+
+// BenchmarkImageCreationPackets is the same as BenchmarkImageCreation but traces
+// primary and shadow rays in packets.
+func BenchmarkImageCreationPackets(t *testing.B) {
+	benchmarkImageCreation(t, true)
+}
+
+func benchmarkImageCreation(t *testing.B, usePackets bool) {
+	output := imagefilm.NewImage("/dev/null")
 	if err := output.Init(1024, 768); err != nil {
 		t.Fatalf("Initializing nil output failed. %s", err)
 	}
-	smpl := sampler.NewSimple(output.Width(), output.Height(), output)
+	smpl := sampler.NewSimple(output.Width(), output.Height(), output, sampler.Config{})
 	cam := scene.GetCamera(float64(output.Width()), float64(output.Height()))
 	tracer := engine.New(smpl)
 	tracer.SetTarget(output, cam)
 	tracer.Scene.InitScene("teapot")
+	tracer.UsePackets = usePackets
 
 	for i := 0; i < t.N; i++ {
 		output.StartFrame()
@@ -31,3 +47,163 @@ func BenchmarkImageCreation(t *testing.B) {
 	smpl.Stop()
 	output.Wait()
 }
+
+// TestDeterministicRendering checks that rendering the same scene with the same seed
+// produces identical images regardless of how the rendering goroutines are
+// scheduled. And that a different seed produces a different image.
+func TestDeterministicRendering(t *testing.T) {
+	cfg := sampler.Config{
+		SamplesPerPixel:   2,
+		TileSize:          8,
+		AdaptiveThreshold: 0.05,
+		Seed:              42,
+	}
+
+	render := func(procs int) []byte {
+		defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(procs))
+		return renderScene(t, "teapot", 96, 64, cfg).Pix
+	}
+
+	first := render(1)
+	second := render(runtime.NumCPU())
+	if !bytes.Equal(first, second) {
+		t.Errorf("two renders with the same seed produced different images")
+	}
+
+	cfg.Seed++
+	if other := render(runtime.NumCPU()); bytes.Equal(first, other) {
+		t.Errorf("renders with different seeds produced the same image")
+	}
+}
+
+// TestCropRendering checks that rendering with a crop window produces the same
+// pixels as rendering the full frame and leaves the rest of the image untouched.
+func TestCropRendering(t *testing.T) {
+	const width, height = 96, 64
+	cfg := sampler.Config{SamplesPerPixel: 2, TileSize: 8, Seed: 3}
+
+	full := renderScene(t, "teapot", width, height, cfg)
+
+	cfg.Crop = image.Rect(30, 20, 71, 45)
+	cropped := renderScene(t, "teapot", width, height, cfg)
+
+	for y := range height {
+		for x := range width {
+			got := cropped.NRGBAAt(x, y)
+			if !image.Pt(x, y).In(cfg.Crop) {
+				if got.A != 0 {
+					t.Fatalf("pixel (%d, %d) outside of the crop window was rendered", x, y)
+				}
+				continue
+			}
+			if want := full.NRGBAAt(x, y); got != want {
+				t.Fatalf("pixel (%d, %d) is %v instead of %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+// TestParseCrop checks parsing of crop windows in pixels and in fractions of the
+// image size.
+func TestParseCrop(t *testing.T) {
+	tests := []struct {
+		crop string
+		want image.Rectangle
+		err  bool
+	}{
+		{crop: "10,20,30,40", want: image.Rect(10, 20, 30, 40)},
+		{crop: "0.5,0,1.0,0.5", want: image.Rect(50, 0, 100, 40)},
+		{crop: " 0.25, 0.1, 0.3, 0.2", want: image.Rect(25, 8, 30, 16)},
+		{crop: "90,-5,150,10", want: image.Rect(90, 0, 100, 10)},
+		{crop: "0,0,1,1", want: image.Rect(0, 0, 1, 1)},
+		{crop: "200,0,300,10", err: true},
+		{crop: "1,2,3", err: true},
+		{crop: "a,b,c,d", err: true},
+	}
+
+	for _, test := range tests {
+		got, err := parseCrop(test.crop, 100, 80)
+		if test.err {
+			if err == nil {
+				t.Errorf("%q: expected an error but got %s", test.crop, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", test.crop, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%q: expected %s but got %s", test.crop, test.want, got)
+		}
+	}
+}
+
+// TestParseFrames checks parsing of frame ranges and the names of the frame files.
+func TestParseFrames(t *testing.T) {
+	tests := []struct {
+		frames      string
+		first, last int
+		err         bool
+	}{
+		{frames: "0:48", first: 0, last: 48},
+		{frames: "7:7", first: 7, last: 7},
+		{frames: "10:5", err: true},
+		{frames: "-1:5", err: true},
+		{frames: "12", err: true},
+		{frames: "a:b", err: true},
+	}
+
+	for _, test := range tests {
+		first, last, err := parseFrames(test.frames)
+		if test.err {
+			if err == nil {
+				t.Errorf("%q: expected an error but got %d:%d", test.frames, first, last)
+			}
+			continue
+		}
+		if err != nil || first != test.first || last != test.last {
+			t.Errorf("%q: expected %d:%d but got %d:%d, %v",
+				test.frames, test.first, test.last, first, last, err)
+		}
+	}
+
+	if got := frameFilename("out/render.png", 12); got != "out/render_0012.png" {
+		t.Errorf("unexpected frame file name %s", got)
+	}
+	if got := frameFilename("render", 12345); got != "render_12345" {
+		t.Errorf("unexpected frame file name %s", got)
+	}
+}
+
+// renderScene renders a few progressive passes of the scene `name` at the given
+// resolution and returns the resulting image. The test is skipped when some of the
+// files of the scene are missing.
+func renderScene(t *testing.T, name string, width, height int, cfg sampler.Config) *image.NRGBA {
+	output := imagefilm.NewImage(os.DevNull)
+	if err := output.Init(width, height); err != nil {
+		t.Fatalf("initializing output failed: %s", err)
+	}
+	smpl := sampler.NewSimple(output.Width(), output.Height(), output, cfg)
+	cam := scene.GetCamera(float64(output.Width()), float64(output.Height()))
+	tracer := engine.New(smpl)
+	tracer.SetTarget(output, cam)
+
+	// Scenes with missing model files are loaded without them. Rendering such a
+	// scene tests nothing about it, so the test is skipped instead.
+	var loadErrors bytes.Buffer
+	tracer.Scene.Logger = slog.New(slog.NewTextHandler(&loadErrors,
+		&slog.HandlerOptions{Level: slog.LevelError}))
+	tracer.Scene.InitScene(name)
+	if loadErrors.Len() > 0 {
+		t.Skipf("scene %s cannot be loaded completely: %s", name, loadErrors.String())
+	}
+
+	if _, err := tracer.RenderProgressive(engine.Progressive{TargetSPP: 8}); err != nil {
+		t.Fatalf("rendering failed: %s", err)
+	}
+	smpl.Stop()
+	output.Wait()
+
+	return output.Image()
+}
@@ -142,9 +142,7 @@ func (b *BasePrimitive) GetTransforms() (*transform.Transform, *transform.Transf
 
 // GetWorldBBox returns the bound box around this primitive in world space
 func (b *BasePrimitive) GetWorldBBox() *bbox.BBox {
-	objBBox := b.shape.GetObjectBBox()
-	worldBBox := bbox.FromPoint(b.objToWorld.Point(objBBox.Min))
-	return bbox.UnionPoint(worldBBox, b.objToWorld.Point(objBBox.Max))
+	return b.objToWorld.BBox(b.shape.GetObjectBBox())
 }
 
 // FromShape returns a primitive from a given shape
@@ -1,29 +1,49 @@
 package primitive
 
 import (
+	"sync"
 	"sync/atomic"
 )
 
 const unnamedPrimitive = "Unnamed Primitive"
 
-var primNames map[uint64]string
-var nextID uint64
+var (
+	primNames     map[uint64]string
+	primNamesLock sync.RWMutex
+	nextID        uint64
+)
 
 // GetNewID returns a new unique ID
 func GetNewID() uint64 {
 	return atomic.AddUint64(&nextID, 1) - 1
 }
 
-// SetName sets a global name for a primitive
+// SetName sets a global name for a primitive. It is safe for concurrent use so
+// scenes may be loaded in parallel.
 func SetName(id uint64, name string) {
+	primNamesLock.Lock()
+	defer primNamesLock.Unlock()
+
 	if primNames == nil {
 		primNames = make(map[uint64]string)
 	}
 	primNames[id] = name
 }
 
+// DeleteName forgets the global name of a primitive. Use it for primitives which
+// are not used anymore so that their names do not pile up.
+func DeleteName(id uint64) {
+	primNamesLock.Lock()
+	defer primNamesLock.Unlock()
+
+	delete(primNames, id)
+}
+
 // GetName returns the global name of a primitive by its ID
 func GetName(id uint64) string {
+	primNamesLock.RLock()
+	defer primNamesLock.RUnlock()
+
 	if primNames == nil {
 		return unnamedPrimitive
 	}
@@ -1,14 +1,16 @@
 package primitive
 
 import (
+	"log/slog"
+
 	"github.com/ironsmile/raytracer/shape"
 	"github.com/ironsmile/raytracer/transform"
 )
 
-// NewObject parses an .obj file (`filePath`) and returns an Object, which represents it. It places
-// the object at the position, given by its second argument - `center`.
-func NewObject(filePath string) (*BasePrimitive, error) {
-	oShape, err := shape.NewObject(filePath)
+// NewObject parses an .obj file (`filePath`) and returns an Object, which represents it.
+// Details about the loaded model are written to `logger`, which may be nil.
+func NewObject(filePath string, logger *slog.Logger) (*BasePrimitive, error) {
+	oShape, err := shape.NewObject(filePath, logger)
 	if err != nil {
 		return nil, err
 	}
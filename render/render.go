@@ -0,0 +1,152 @@
+// Package render is the API for using the ray tracer from other programs. It puts
+// the film, sampler, camera and engine together so that a scene is rendered with a
+// single call to [Render]:
+//
+//	scn, err := render.LoadScene("teapot", nil)
+//	if err != nil {
+//		return err
+//	}
+//	img, err := render.Render(ctx, scn, render.Options{
+//		Width:  640,
+//		Height: 480,
+//		SPP:    64,
+//	})
+package render
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ironsmile/raytracer/camera"
+	"github.com/ironsmile/raytracer/engine"
+	"github.com/ironsmile/raytracer/imagefilm"
+	"github.com/ironsmile/raytracer/sampler"
+	"github.com/ironsmile/raytracer/scene"
+)
+
+// Options configures a rendering. Width and Height are required. Rendering stops
+// when any of SPP, TimeBudget and NoiseThreshold is reached. When none of them is
+// set a single pass is rendered.
+type Options struct {
+	Width, Height int
+
+	// Camera is the camera through which the scene is seen. The default camera of
+	// the demo scenes is used when it is nil. See [scene.GetCamera].
+	Camera camera.Camera
+
+	// Sampler configures how the samples for every pixel are taken. Its
+	// SamplesPerPixel is the number of samples in a single pass.
+	Sampler sampler.Config
+
+	// Mode controls what is painted for every sample. See [engine.RenderMode].
+	Mode engine.RenderMode
+
+	// SPP is the number of samples per pixel after which rendering stops. It is
+	// rounded up to a whole number of passes. Zero means no limit.
+	SPP int
+
+	// TimeBudget is the time after which rendering stops. It is checked at the end
+	// of every pass. Zero means no limit.
+	TimeBudget time.Duration
+
+	// NoiseThreshold stops rendering once the noise estimate of the image falls
+	// below it. Zero means no limit.
+	NoiseThreshold float64
+
+	// OnProgress is called after every pass when it is not nil. Rendering does
+	// not continue until it returns.
+	OnProgress func(Progress)
+
+	// Logger receives the diagnostics of the rendering. Nothing is logged when it
+	// is nil.
+	Logger *slog.Logger
+}
+
+// Progress describes how far a rendering is.
+type Progress struct {
+	engine.ProgressiveResult
+
+	output *imagefilm.Image
+}
+
+// Image returns a copy of the image rendered so far.
+func (p Progress) Image() *image.NRGBA {
+	img := p.output.Image()
+	cp := image.NewNRGBA(img.Rect)
+	copy(cp.Pix, img.Pix)
+	return cp
+}
+
+// LoadScene returns the demo scene with `name`. See [scene.PossibleScenes].
+// Problems with loading its models are written to `logger`, which may be nil.
+func LoadScene(name string, logger *slog.Logger) (*scene.Scene, error) {
+	if !slices.Contains(scene.PossibleScenes, name) {
+		return nil, fmt.Errorf("unknown scene %q, must be one of: %s",
+			name, strings.Join(scene.PossibleScenes, ", "))
+	}
+
+	scn := scene.NewScene()
+	scn.Logger = logger
+	scn.InitScene(name)
+	return scn, nil
+}
+
+// Render renders `scn` as configured by `opts` and returns the image. It stops
+// when `ctx` is done. Then the image rendered so far is returned together with
+// the error of the context.
+func Render(ctx context.Context, scn *scene.Scene, opts Options) (*image.NRGBA, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if scn == nil {
+		return nil, fmt.Errorf("scene is nil")
+	}
+	if opts.Width <= 0 || opts.Height <= 0 {
+		return nil, fmt.Errorf("image size %dx%d is not positive", opts.Width, opts.Height)
+	}
+
+	output := imagefilm.NewImage("")
+	if err := output.Init(opts.Width, opts.Height); err != nil {
+		return nil, err
+	}
+
+	smpl := sampler.NewSimple(opts.Width, opts.Height, output, opts.Sampler)
+	defer smpl.Stop()
+
+	cam := opts.Camera
+	if cam == nil {
+		cam = scene.GetCamera(float64(opts.Width), float64(opts.Height))
+	}
+
+	tracer := engine.New(smpl)
+	tracer.SetLogger(opts.Logger)
+	tracer.Scene = scn
+	tracer.Mode = opts.Mode
+	tracer.SetTarget(output, cam)
+
+	p := engine.Progressive{
+		TargetSPP:      opts.SPP,
+		TimeBudget:     opts.TimeBudget,
+		NoiseThreshold: opts.NoiseThreshold,
+		Interrupt:      ctx.Done(),
+	}
+	if p.TargetSPP <= 0 && p.TimeBudget <= 0 && p.NoiseThreshold <= 0 {
+		p.TargetSPP = 1
+	}
+	if opts.OnProgress != nil {
+		p.OnPass = func(res engine.ProgressiveResult) {
+			opts.OnProgress(Progress{ProgressiveResult: res, output: output})
+		}
+	}
+
+	if _, err := tracer.RenderProgressive(p); err != nil {
+		return nil, err
+	}
+
+	return output.Image(), ctx.Err()
+}
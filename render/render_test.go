@@ -0,0 +1,128 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ironsmile/raytracer/sampler"
+)
+
+// TestRender checks that an image with the requested size is rendered and that
+// the progress is reported after every pass.
+func TestRender(t *testing.T) {
+	scn, err := LoadScene("teapot", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var passes []int
+	img, err := Render(context.Background(), scn, Options{
+		Width:   40,
+		Height:  30,
+		SPP:     6,
+		Sampler: sampler.Config{SamplesPerPixel: 2},
+		OnProgress: func(p Progress) {
+			passes = append(passes, p.Passes)
+			if b := p.Image().Bounds(); b.Dx() != 40 || b.Dy() != 30 {
+				t.Errorf("progress image is %dx%d", b.Dx(), b.Dy())
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("rendering: %s", err)
+	}
+
+	if b := img.Bounds(); b.Dx() != 40 || b.Dy() != 30 {
+		t.Errorf("image is %dx%d instead of 40x30", b.Dx(), b.Dy())
+	}
+	if len(passes) != 3 || passes[2] != 3 {
+		t.Errorf("expected progress after passes 1, 2 and 3 but got %v", passes)
+	}
+}
+
+// TestRenderCanceled checks that rendering stops when its context is canceled.
+func TestRenderCanceled(t *testing.T) {
+	scn, err := LoadScene("teapot", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	img, err := Render(ctx, scn, Options{
+		Width:  32,
+		Height: 32,
+		SPP:    1 << 20,
+		OnProgress: func(p Progress) {
+			cancel()
+		},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled but got %v", err)
+	}
+	if img == nil {
+		t.Errorf("expected the image rendered before canceling")
+	}
+
+	if _, err := Render(ctx, scn, Options{Width: 32, Height: 32}); !errors.Is(err, context.Canceled) {
+		t.Errorf("rendering with a canceled context: expected context.Canceled but got %v", err)
+	}
+}
+
+// TestRenderNoiseThreshold checks that rendering an image without any noise stops
+// by the noise threshold as soon as its noise can be estimated.
+func TestRenderNoiseThreshold(t *testing.T) {
+	scn, err := LoadScene("empty", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var last Progress
+	_, err = Render(context.Background(), scn, Options{
+		Width:          16,
+		Height:         16,
+		TimeBudget:     time.Minute,
+		NoiseThreshold: 0.01,
+		Sampler:        sampler.Config{SamplesPerPixel: 1},
+		OnProgress: func(p Progress) {
+			last = p
+		},
+	})
+	if err != nil {
+		t.Fatalf("rendering: %s", err)
+	}
+
+	// The time budget is far from reached so only the noise threshold could have
+	// stopped the rendering.
+	if last.Passes != 2 {
+		t.Errorf("expected to stop after 2 passes but got %s", last)
+	}
+}
+
+// TestRenderErrors checks that bad options are reported as errors.
+func TestRenderErrors(t *testing.T) {
+	if _, err := LoadScene("nope", nil); err == nil {
+		t.Errorf("expected an error for an unknown scene")
+	}
+
+	scn, err := LoadScene("teapot", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, opts := range []Options{
+		{Width: 0, Height: 10},
+		{Width: 10, Height: -1},
+	} {
+		if _, err := Render(context.Background(), scn, opts); err == nil {
+			t.Errorf("expected an error for options %+v", opts)
+		}
+	}
+
+	if _, err := Render(context.Background(), nil, Options{Width: 10, Height: 10}); err == nil {
+		t.Errorf("expected an error for a nil scene")
+	}
+}
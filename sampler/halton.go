@@ -0,0 +1,39 @@
+package sampler
+
+// HaltonSampler generates samples from the Halton sequence. Dimension `i` is the
+// radical inverse of the sample index in the base of the i-th prime number. Every
+// pixel and dimension gets its own Owen scrambling which removes the correlation
+// between pixels and between the higher dimensions of the sequence.
+type HaltonSampler struct {
+	pixelSample
+}
+
+// NewHalton returns a new [HaltonSampler].
+func NewHalton() *HaltonSampler {
+	return &HaltonSampler{}
+}
+
+// Get1D implements [Sampler].
+func (s *HaltonSampler) Get1D() float64 {
+	base := primes[s.dimension%len(primes)]
+	v := owenScrambledRadicalInverse(base, uint64(s.index), s.hash(0))
+	s.dimension++
+	return v
+}
+
+// Get2D implements [Sampler].
+func (s *HaltonSampler) Get2D() (float64, float64) {
+	return s.Get1D(), s.Get1D()
+}
+
+// GetPixel2D implements [Sampler].
+func (s *HaltonSampler) GetPixel2D() (float64, float64) {
+	return s.Get2D()
+}
+
+// Clone implements [Sampler].
+func (s *HaltonSampler) Clone() Sampler {
+	clone := NewHalton()
+	clone.seed = s.seed
+	return clone
+}
@@ -0,0 +1,37 @@
+package sampler
+
+// IndependentSampler returns uniformly distributed random samples. They are derived
+// from the pixel, sample index and dimension so the same pixel sample always gets
+// the same values.
+type IndependentSampler struct {
+	pixelSample
+}
+
+// NewIndependent returns a new [IndependentSampler].
+func NewIndependent() *IndependentSampler {
+	return &IndependentSampler{}
+}
+
+// Get1D implements [Sampler].
+func (s *IndependentSampler) Get1D() float64 {
+	v := randomFloat(s.hash(uint64(s.index)))
+	s.dimension++
+	return v
+}
+
+// Get2D implements [Sampler].
+func (s *IndependentSampler) Get2D() (float64, float64) {
+	return s.Get1D(), s.Get1D()
+}
+
+// GetPixel2D implements [Sampler].
+func (s *IndependentSampler) GetPixel2D() (float64, float64) {
+	return s.Get2D()
+}
+
+// Clone implements [Sampler].
+func (s *IndependentSampler) Clone() Sampler {
+	clone := NewIndependent()
+	clone.seed = s.seed
+	return clone
+}
@@ -0,0 +1,179 @@
+package sampler
+
+import (
+	"math"
+	"math/bits"
+)
+
+// oneMinusEpsilon is the biggest float64 which is less than 1. Sample values are
+// clamped to it so that they are always in the [0, 1) range.
+const oneMinusEpsilon = 0x1.fffffffffffffp-1
+
+// mixBits is a finalizer for 64 bit hashes. It makes every bit of the result
+// depend on every bit of `v`.
+func mixBits(v uint64) uint64 {
+	v ^= v >> 31
+	v *= 0x7fb5d329728ea185
+	v ^= v >> 27
+	v *= 0x81dadef4bc2dd44d
+	v ^= v >> 33
+	return v
+}
+
+// hash returns a well distributed hash of all its arguments.
+func hash(vals ...uint64) uint64 {
+	h := uint64(0x9e3779b97f4a7c15)
+	for _, v := range vals {
+		h = mixBits(h ^ v)
+	}
+	return h
+}
+
+// randomFloat returns a pseudo random number in [0, 1) for the given hash value.
+func randomFloat(h uint64) float64 {
+	return float64(mixBits(h)>>11) * 0x1p-53
+}
+
+// permutationElement returns the element at index `i` of a random permutation of
+// the numbers in [0, l) chosen by `p`. The permutation is never stored in memory.
+// This is the hashing approach from Andrew Kensler (2013), "Correlated
+// Multi-Jittered Sampling".
+func permutationElement(i, l, p uint32) uint32 {
+	w := l - 1
+	w |= w >> 1
+	w |= w >> 2
+	w |= w >> 4
+	w |= w >> 8
+	w |= w >> 16
+
+	for {
+		i ^= p
+		i *= 0xe170893d
+		i ^= p >> 16
+		i ^= (i & w) >> 4
+		i ^= p >> 8
+		i *= 0x0929eb3f
+		i ^= p >> 23
+		i ^= (i & w) >> 1
+		i *= 1 | p>>27
+		i *= 0x6935fa69
+		i ^= (i & w) >> 11
+		i *= 0x74dcb303
+		i ^= (i & w) >> 2
+		i *= 0x9e501cc3
+		i ^= (i & w) >> 2
+		i *= 0xc860a3df
+		i &= w
+		i ^= i >> 5
+
+		if i < l {
+			break
+		}
+	}
+
+	return (i + p) % l
+}
+
+// owenScramble applies a random Owen scrambling chosen by `seed` to the base 2
+// digits of `v`. The implementation is the hash based one from Laine and Karras
+// (2011), "Stratified Sampling for Stochastic Transparency", with the improved
+// constants of Brent Burley (2020), "Practical Hash-based Owen Scrambling".
+func owenScramble(v, seed uint32) uint32 {
+	v = bits.Reverse32(v)
+	v ^= v * 0x3d20adea
+	v += seed
+	v *= (seed >> 16) | 1
+	v ^= v * 0x05526c56
+	v ^= v * 0x53a22864
+	return bits.Reverse32(v)
+}
+
+// sobolDirections holds the generator matrices of the first two dimensions of the
+// Sobol sequence. Every element is one column of the matrix.
+var sobolDirections = [2][32]uint32{
+	sobolVanDerCorput(),
+	sobolSecondDimension(),
+}
+
+func sobolVanDerCorput() (dirs [32]uint32) {
+	for i := range dirs {
+		dirs[i] = 1 << (31 - i)
+	}
+	return
+}
+
+func sobolSecondDimension() (dirs [32]uint32) {
+	// The primitive polynomial for this dimension is x + 1 with a single initial
+	// direction number of 1. Every next direction number m[i] is
+	// m[i-1] ^ (m[i-1] << 1).
+	m := uint32(1)
+	for i := range dirs {
+		dirs[i] = m << (31 - i)
+		m ^= m << 1
+	}
+	return
+}
+
+// sobolSample returns the `dim`-th coordinate of the Sobol point with index `a`
+// with Owen scrambling applied using `seed`. Only the first two dimensions are
+// supported.
+func sobolSample(a uint32, dim int, seed uint32) float64 {
+	var v uint32
+	for i := 0; a != 0; i, a = i+1, a>>1 {
+		if a&1 != 0 {
+			v ^= sobolDirections[dim][i]
+		}
+	}
+	v = owenScramble(v, seed)
+	return min(float64(v)*0x1p-32, oneMinusEpsilon)
+}
+
+// owenScrambledRadicalInverse returns the radical inverse of `a` in base `base`
+// with Owen scrambling applied using `seed`. Every digit is permuted with a
+// permutation which depends on all of the digits before it.
+//
+// Digits are added while they still change the result. For large bases the
+// reversed digits stop earlier so that they do not overflow.
+func owenScrambledRadicalInverse(base int, a uint64, seed uint64) float64 {
+	invBase := 1 / float64(base)
+	invBaseM := 1.0
+	limit := math.MaxUint64/uint64(base) - uint64(base)
+
+	var reversed uint64
+	for 1-float64(base-1)*invBaseM < 1 && reversed < limit {
+		next := a / uint64(base)
+		digit := uint32(a - next*uint64(base))
+		digitHash := uint32(mixBits(seed ^ reversed))
+		digit = permutationElement(digit, uint32(base), digitHash)
+
+		reversed = reversed*uint64(base) + uint64(digit)
+		invBaseM *= invBase
+		a = next
+	}
+
+	return min(invBaseM*float64(reversed), oneMinusEpsilon)
+}
+
+// primes holds the first prime numbers. They are the bases of the Halton sequence
+// dimensions.
+var primes = firstPrimes(1000)
+
+func firstPrimes(n int) []int {
+	found := make([]int, 0, n)
+	for c := 2; len(found) < n; c++ {
+		isPrime := true
+		for _, p := range found {
+			if p*p > c {
+				break
+			}
+			if c%p == 0 {
+				isPrime = false
+				break
+			}
+		}
+		if isPrime {
+			found = append(found, c)
+		}
+	}
+	return found
+}
@@ -1,6 +1,9 @@
 package sampler
 
-import "image/color"
+import (
+	"image"
+	"image/color"
+)
 
 // Output supports setting colours to certain 2D pixels.
 type Output interface {
@@ -9,3 +12,13 @@ type Output interface {
 	DoneFrame()
 	StartFrame()
 }
+
+// BlockOutput is implemented by outputs which can show the colour of a single
+// sample in a whole block of pixels. It is used in the preview frames of
+// [SimpleSampler.SetPreview]. Other outputs have the colour set for every pixel in
+// the block.
+type BlockOutput interface {
+	// SetBlock records the sample for the pixel at (x, y) and shows its colour in
+	// `block`, which contains the pixel.
+	SetBlock(x, y int, block image.Rectangle, clr color.Color) error
+}
@@ -0,0 +1,211 @@
+package sampler
+
+import (
+	"fmt"
+	"image"
+)
+
+// Sampler supplies the sample vectors used for rendering a single pixel sample.
+// Every pixel sample starts with a call to [Sampler.StartPixelSample]. After that
+// every Get method consumes the next one or two dimensions of the sample vector.
+// The pixel position always uses the first two dimensions. Anything else which has
+// to be sampled, like lens, time or light positions, gets its dimensions from
+// [Sampler.Get1D] and [Sampler.Get2D] in the order in which they are requested.
+//
+// All values are in the [0, 1) range. Samplers are not safe for concurrent use.
+// Use [Sampler.Clone] for creating one for every rendering goroutine.
+type Sampler interface {
+	// StartPixelSample prepares the sampler for the sample with number `index` of
+	// the pixel at (x, y).
+	StartPixelSample(x, y, index int)
+
+	// Get1D returns the next dimension of the sample vector.
+	Get1D() float64
+
+	// Get2D returns the next two dimensions of the sample vector.
+	Get2D() (float64, float64)
+
+	// GetPixel2D returns the position within the pixel for the current sample.
+	GetPixel2D() (float64, float64)
+
+	// Clone returns a new sampler of the same type and with the same settings.
+	Clone() Sampler
+}
+
+// Kind is a type of [Sampler]. The zero Kind is [DefaultKind].
+type Kind int
+
+const (
+	// KindSobol uses the first two dimensions of the Sobol low-discrepancy sequence
+	// with Owen scrambling. Higher dimensions are padded with randomly shuffled
+	// copies of them.
+	KindSobol Kind = iota
+
+	// KindIndependent generates uniform random samples with no relation between
+	// them.
+	KindIndependent
+
+	// KindStratified divides every dimension in as many strata as there are samples
+	// per pixel and places one jittered sample in each stratum.
+	KindStratified
+
+	// KindHalton uses the Halton low-discrepancy sequence with Owen scrambling.
+	KindHalton
+)
+
+// DefaultKind is the sampler used when no other is configured, for example by the
+// zero [Config]. All ways of rendering share it so that they produce the same
+// images for the same settings.
+const DefaultKind = KindSobol
+
+// Kinds are all the supported types of samplers.
+var Kinds = []Kind{KindIndependent, KindStratified, KindHalton, KindSobol}
+
+// String implements fmt.Stringer.
+func (k Kind) String() string {
+	switch k {
+	case KindIndependent:
+		return "independent"
+	case KindStratified:
+		return "stratified"
+	case KindHalton:
+		return "halton"
+	case KindSobol:
+		return "sobol"
+	default:
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+}
+
+// ParseKind returns the Kind with the given name. The names are the same as the ones
+// returned by [Kind.String].
+func ParseKind(name string) (Kind, error) {
+	for _, kind := range Kinds {
+		if kind.String() == name {
+			return kind, nil
+		}
+	}
+	return DefaultKind, fmt.Errorf("unknown sampler %q", name)
+}
+
+// DefaultSamplesPerPixel is the number of samples taken for every pixel in a frame
+// when no other number is configured.
+const DefaultSamplesPerPixel = 4
+
+// DefaultTileSize is the tile side in pixels used when no other is configured. See
+// [Config.TileSize].
+const DefaultTileSize = 32
+
+// Config holds the settings of the sampling.
+type Config struct {
+	// Kind is the type of sampler which generates sample positions. The zero
+	// value is [DefaultKind].
+	Kind Kind
+
+	// SamplesPerPixel is the number of samples taken for every pixel in a frame.
+	// Zero means [DefaultSamplesPerPixel].
+	SamplesPerPixel int
+
+	// TileSize controls how the screen is partitioned between sub samplers. Every
+	// sub sampler is responsible for as many pixels as there are in a square tile
+	// with this side in pixels. The pixels themselves are randomly spread over the
+	// screen. Zero means [DefaultTileSize].
+	TileSize int
+
+	// Seed selects the random decisions made during sampling: which pixels go to
+	// which sub sampler and all sample positions. They are derived only from the
+	// seed, the pixel and the sample index. So rendering the same scene with the
+	// same seed and settings always produces the same image.
+	Seed uint64
+
+	// Crop is the crop window in pixels. When it is not empty samples are generated
+	// only for the pixels in it. The rest of the screen is left untouched.
+	Crop image.Rectangle
+
+	// AdaptiveThreshold enables adaptive sampling when it is greater than zero.
+	// Pixels with relative error below it stop being sampled in subsequent passes.
+	// It has effect only for outputs which implement [ErrorEstimator]. See
+	// [SimpleSampler.NextPass].
+	AdaptiveThreshold float64
+}
+
+// samplesPerPixel returns the configured number of samples per pixel with the
+// default applied.
+func (c Config) samplesPerPixel() int {
+	if c.SamplesPerPixel <= 0 {
+		return DefaultSamplesPerPixel
+	}
+	return c.SamplesPerPixel
+}
+
+// region returns the part of a screen with the given size which is sampled. This
+// is the crop window clipped to the screen or the whole screen without a crop
+// window.
+func (c Config) region(width, height int) image.Rectangle {
+	screen := image.Rect(0, 0, width, height)
+	if c.Crop.Empty() {
+		return screen
+	}
+	return c.Crop.Intersect(screen)
+}
+
+// tileSize returns the configured tile size with the default applied.
+func (c Config) tileSize() int {
+	if c.TileSize <= 0 {
+		return DefaultTileSize
+	}
+	return c.TileSize
+}
+
+// NewSampler returns a new [Sampler] for this configuration.
+func (c Config) NewSampler() Sampler {
+	spp := c.samplesPerPixel()
+
+	var smpl interface {
+		Sampler
+		setSeed(seed uint64)
+	}
+
+	switch c.Kind {
+	case KindIndependent:
+		smpl = NewIndependent()
+	case KindStratified:
+		smpl = NewStratified(spp)
+	case KindHalton:
+		smpl = NewHalton()
+	default:
+		smpl = NewSobol(spp)
+	}
+
+	smpl.setSeed(c.Seed)
+	return smpl
+}
+
+// pixelSample identifies the pixel sample which is being generated and the next
+// dimension of its sample vector. It is embedded in all samplers.
+type pixelSample struct {
+	x, y      int
+	index     int
+	dimension int
+
+	// seed is mixed in all hashes so that different seeds produce different
+	// samples.
+	seed uint64
+}
+
+// setSeed sets the seed from which all samples are derived.
+func (p *pixelSample) setSeed(seed uint64) {
+	p.seed = seed
+}
+
+// StartPixelSample implements [Sampler].
+func (p *pixelSample) StartPixelSample(x, y, index int) {
+	p.x, p.y = x, y
+	p.index = index
+	p.dimension = 0
+}
+
+// hash returns a hash of the seed, the pixel, the current dimension and `salt`.
+func (p *pixelSample) hash(salt uint64) uint64 {
+	return hash(p.seed, uint64(p.x), uint64(p.y), uint64(p.dimension), salt)
+}
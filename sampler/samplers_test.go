@@ -0,0 +1,377 @@
+package sampler
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// TestSamplersRange checks that all samplers return values in [0, 1) and that they
+// always return the same values for the same pixel sample.
+func TestSamplersRange(t *testing.T) {
+	for _, kind := range Kinds {
+		t.Run(kind.String(), func(t *testing.T) {
+			smpl := Config{Kind: kind, SamplesPerPixel: 8}.NewSampler()
+			clone := smpl.Clone()
+
+			for i := 0; i < 2000; i++ {
+				x, y, index := i%37, i/37, i%11
+
+				smpl.StartPixelSample(x, y, index)
+				clone.StartPixelSample(x, y, index)
+
+				for dim := 0; dim < 10; dim++ {
+					v := smpl.Get1D()
+					if v < 0 || v >= 1 {
+						t.Fatalf("value %f for dimension %d is out of range", v, dim)
+					}
+					if other := clone.Get1D(); other != v {
+						t.Fatalf("clone returned %f instead of %f for dimension %d",
+							other, v, dim)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestSamplersStratification checks that the samples of a single pixel are well
+// distributed for the samplers which guarantee that. When the number of samples
+// is a power of two each of them is in its own cell of a 2D grid and in its own
+// 1D stratum.
+func TestSamplersStratification(t *testing.T) {
+	const spp = 16
+
+	for _, kind := range []Kind{KindStratified, KindSobol} {
+		t.Run(kind.String(), func(t *testing.T) {
+			smpl := Config{Kind: kind, SamplesPerPixel: spp}.NewSampler()
+
+			for pixel := 0; pixel < 50; pixel++ {
+				var cells, strata [spp]bool
+
+				for index := range spp {
+					smpl.StartPixelSample(pixel, 3*pixel, index)
+					x, y := smpl.GetPixel2D()
+					v := smpl.Get1D()
+
+					cell := int(x*4) + 4*int(y*4)
+					if cells[cell] {
+						t.Fatalf("pixel %d: two samples in the cell (%d, %d)",
+							pixel, cell%4, cell/4)
+					}
+					cells[cell] = true
+
+					if strata[int(v*spp)] {
+						t.Fatalf("pixel %d: two samples in stratum %d", pixel, int(v*spp))
+					}
+					strata[int(v*spp)] = true
+				}
+			}
+		})
+	}
+
+	// Only the first dimension of Halton is in base 2. So only it is stratified in
+	// a power of two number of strata.
+	t.Run(KindHalton.String(), func(t *testing.T) {
+		smpl := NewHalton()
+
+		for pixel := 0; pixel < 50; pixel++ {
+			var strata [spp]bool
+			for index := range spp {
+				smpl.StartPixelSample(pixel, 3*pixel, index)
+				v := smpl.Get1D()
+				if strata[int(v*spp)] {
+					t.Fatalf("pixel %d: two samples in stratum %d", pixel, int(v*spp))
+				}
+				strata[int(v*spp)] = true
+			}
+		}
+	})
+}
+
+// TestSamplersDecorrelatedPixels checks that neighbouring pixels do not get the same
+// sample positions.
+func TestSamplersDecorrelatedPixels(t *testing.T) {
+	for _, kind := range Kinds {
+		t.Run(kind.String(), func(t *testing.T) {
+			smpl := Config{Kind: kind}.NewSampler()
+
+			smpl.StartPixelSample(10, 10, 0)
+			x1, y1 := smpl.GetPixel2D()
+
+			smpl.StartPixelSample(11, 10, 0)
+			x2, y2 := smpl.GetPixel2D()
+
+			if x1 == x2 && y1 == y2 {
+				t.Errorf("neighbouring pixels have the same sample position (%f, %f)",
+					x1, y1)
+			}
+		})
+	}
+}
+
+// TestSimpleSamplerCoversAllPixels checks that every pixel of the screen gets
+// exactly the configured number of samples in every pass for various screen and
+// tile sizes. With a crop window only the pixels in it must get samples.
+func TestSimpleSamplerCoversAllPixels(t *testing.T) {
+	tests := []struct {
+		width, height int
+		cfg           Config
+	}{
+		{width: 64, height: 64},
+		{width: 17, height: 90, cfg: Config{SamplesPerPixel: 3, TileSize: 8}},
+		{width: 200, height: 30, cfg: Config{Kind: KindSobol, TileSize: 64}},
+		{width: 5, height: 3, cfg: Config{SamplesPerPixel: 1}},
+		{width: 64, height: 48, cfg: Config{Crop: image.Rect(10, 5, 37, 30), TileSize: 8}},
+		{width: 30, height: 20, cfg: Config{Crop: image.Rect(20, -5, 50, 7)}},
+	}
+
+	for _, test := range tests {
+		name := fmt.Sprintf("%dx%d", test.width, test.height)
+		if !test.cfg.Crop.Empty() {
+			name += "_crop_" + test.cfg.Crop.String()
+		}
+		t.Run(name, func(t *testing.T) {
+			smpl := NewSimple(test.width, test.height, nullOutput{}, test.cfg)
+			spp := smpl.SamplesPerPixel()
+
+			for pass := range 2 {
+				if pass > 0 {
+					smpl.NextPass()
+				}
+
+				counts := make([]int, test.width*test.height)
+				for {
+					sub, err := smpl.GetSubSampler()
+					if err == ErrEndOfSampling {
+						break
+					}
+
+					for {
+						x, y, err := sub.GetSample()
+						if err == ErrSubSamplerEnd {
+							break
+						}
+						if err != nil {
+							t.Fatalf("unexpected error: %s", err)
+						}
+						counts[int(y)*test.width+int(x)]++
+					}
+				}
+
+				region := test.cfg.region(test.width, test.height)
+				for ind, count := range counts {
+					want := spp
+					if !image.Pt(ind%test.width, ind/test.width).In(region) {
+						want = 0
+					}
+					if count != want {
+						t.Fatalf("pass %d: pixel (%d, %d) got %d samples instead of %d",
+							pass, ind%test.width, ind/test.width, count, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestSimpleSamplerAdaptive checks that adaptive sampling stops sampling the pixels
+// which have converged and gives more samples to the rest.
+func TestSimpleSamplerAdaptive(t *testing.T) {
+	const width, height = 40, 30
+
+	out := &countingOutput{width: width, counts: make([]int, width*height)}
+	smpl := NewSimple(width, height, out, Config{
+		SamplesPerPixel:   4,
+		TileSize:          8,
+		AdaptiveThreshold: 0.1,
+	})
+
+	// Only the pixels in the left half of the screen never converge.
+	noisy := func(x, y int) bool { return x < width/2 }
+	out.noisy = noisy
+
+	for pass := range 4 {
+		if pass > 0 {
+			smpl.NextPass()
+		}
+		if smpl.Converged() {
+			t.Fatalf("pass %d: sampler converged while there are noisy pixels", pass)
+		}
+
+		for {
+			sub, err := smpl.GetSubSampler()
+			if err == ErrEndOfSampling {
+				break
+			}
+			for {
+				x, y, err := sub.GetSample()
+				if err == ErrSubSamplerEnd {
+					break
+				}
+				out.counts[int(y)*width+int(x)]++
+			}
+		}
+	}
+
+	// The first two passes sample everything: 8 samples are needed before the
+	// error estimate is trusted. After that the noisy pixels get double the samples.
+	for y := range height {
+		for x := range width {
+			want := 8
+			if noisy(x, y) {
+				want = 8 + 2*2*4
+			}
+			if got := out.counts[y*width+x]; got != want {
+				t.Fatalf("pixel (%d, %d) got %d samples instead of %d", x, y, got, want)
+			}
+		}
+	}
+}
+
+// TestSimpleSamplerPreview checks that the preview frames of continuous sampling
+// sample one pixel for every block and cover the whole screen with the blocks,
+// and that the frames after them sample every pixel.
+func TestSimpleSamplerPreview(t *testing.T) {
+	const width, height = 30, 20
+
+	out := &blockOutput{width: width, height: height}
+	smpl := NewSimple(width, height, out, Config{SamplesPerPixel: 2, TileSize: 8})
+	smpl.MakeContinuous()
+	smpl.SetPreview(8)
+
+	renderFrame := func() {
+		for range smpl.SubSamplers() {
+			sub, err := smpl.GetSubSampler()
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			for {
+				x, y, err := sub.GetSample()
+				if err == ErrSubSamplerEnd {
+					break
+				}
+				sub.UpdateScreen(x, y, color.Black)
+			}
+		}
+	}
+
+	for pass := range 2 {
+		for _, size := range []int{8, 4, 2, 1} {
+			out.reset()
+			renderFrame()
+
+			for y := range height {
+				for x := range width {
+					want := 1
+					if size == 1 {
+						want = 2
+					}
+					if got := out.covered[y*width+x]; got != want {
+						t.Fatalf("pass %d, block %d: pixel (%d, %d) is covered %d times "+
+							"instead of %d", pass, size, x, y, got, want)
+					}
+				}
+			}
+
+			wantSamples := ((width + size - 1) / size) * ((height + size - 1) / size)
+			if size == 1 {
+				wantSamples = 2 * width * height
+			}
+			if out.samples != wantSamples {
+				t.Errorf("pass %d, block %d: got %d samples instead of %d",
+					pass, size, out.samples, wantSamples)
+			}
+		}
+
+		if frames := smpl.Frames(); frames != 1 {
+			t.Errorf("pass %d: expected 1 full frame but got %d", pass, frames)
+		}
+		if out.misplaced > 0 {
+			t.Errorf("pass %d: %d samples are outside of their blocks", pass, out.misplaced)
+		}
+		smpl.Restart()
+		if frames := smpl.Frames(); frames != 0 {
+			t.Errorf("pass %d: got %d full frames after restarting", pass, frames)
+		}
+	}
+}
+
+type nullOutput struct{}
+
+func (nullOutput) Set(int, int, color.Color) error { return nil }
+func (nullOutput) DoneFrame()                      {}
+func (nullOutput) StartFrame()                     {}
+
+// countingOutput is an [ErrorEstimator] which reports as converged every pixel
+// which is not noisy.
+type countingOutput struct {
+	nullOutput
+	width  int
+	counts []int
+	noisy  func(x, y int) bool
+}
+
+func (c *countingOutput) PixelError(x, y int) (float64, int) {
+	if c.noisy(x, y) {
+		return 1, c.counts[y*c.width+x]
+	}
+	return 0, c.counts[y*c.width+x]
+}
+
+// blockOutput counts how many times every pixel is set in a frame, either alone or
+// as a part of a block, and the number of samples in the frame.
+type blockOutput struct {
+	nullOutput
+	width, height int
+	covered       []int
+	samples       int
+
+	// misplaced counts the samples outside of their blocks.
+	misplaced int
+}
+
+func (b *blockOutput) reset() {
+	b.covered = make([]int, b.width*b.height)
+	b.samples = 0
+}
+
+func (b *blockOutput) Set(x, y int, _ color.Color) error {
+	b.covered[y*b.width+x]++
+	b.samples++
+	return nil
+}
+
+func (b *blockOutput) SetBlock(x, y int, block image.Rectangle, _ color.Color) error {
+	if !image.Pt(x, y).In(block) {
+		b.misplaced++
+	}
+	for py := block.Min.Y; py < block.Max.Y; py++ {
+		for px := block.Min.X; px < block.Max.X; px++ {
+			b.covered[py*b.width+px]++
+		}
+	}
+	b.samples++
+	return nil
+}
+
+// TestRadicalInverseLargeBase checks the radical inverse for the base of the
+// highest Halton dimension. The first `base` indices must have different first
+// digits, so every one of them is in its own 1/base wide stratum.
+func TestRadicalInverseLargeBase(t *testing.T) {
+	base := primes[len(primes)-1]
+
+	seen := make([]bool, base)
+	for a := range base {
+		v := owenScrambledRadicalInverse(base, uint64(a), 42)
+		if v < 0 || v >= 1 {
+			t.Fatalf("value %f for index %d is out of range", v, a)
+		}
+		stratum := int(v * float64(base))
+		if seen[stratum] {
+			t.Fatalf("index %d is in stratum %d which is already taken", a, stratum)
+		}
+		seen[stratum] = true
+	}
+}
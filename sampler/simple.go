@@ -2,8 +2,10 @@ package sampler
 
 import (
 	"errors"
-	"fmt"
+	"image"
 	"image/color"
+	"math/rand/v2"
+	"slices"
 	"sync"
 	"sync/atomic"
 )
@@ -11,17 +13,58 @@ import (
 // ErrEndOfSampling would be returned by the sampler when no further sampling is needed
 var ErrEndOfSampling = errors.New("End of sampling")
 
+// packetBlock is the side in pixels of the square blocks in which the sampler
+// shuffles pixels.
+const packetBlock = 2
+
+// State is the progress of a [SimpleSampler]. See [SimpleSampler.State].
+type State struct {
+	// Passes is the number of sampled passes.
+	Passes uint32
+
+	// SampleBase is the sample index of the first sample for every pixel in the
+	// next pass.
+	SampleBase uint32
+}
+
 // SimpleSampler implements the most simple of samplers. It generates a fixed amount of
 // sample per pixel
 type SimpleSampler struct {
-	output           Output
-	subSamplers      []*SubSampler
-	subSamplersCount uint32
-	current          uint32
+	output          Output
+	subSamplers     []*SubSampler
+	current         uint32
+	samplesPerPixel uint32
+
+	// pass is the number of the current rendering pass. Every pass takes
+	// `samplesPerPixel` new samples for every pixel. See [SimpleSampler.NextPass].
+	pass uint32
 
-	stopped    bool
+	// sampleBase is the sample index of the first sample for every pixel in the
+	// current pass.
+	sampleBase uint32
+
+	// activeSubSamplers are the sub samplers used in the current pass. Without
+	// adaptive sampling these are all of them.
+	activeSubSamplers []*SubSampler
+
+	// adaptiveThreshold is the relative pixel error below which pixels are
+	// considered converged. Zero means adaptive sampling is disabled.
+	adaptiveThreshold float64
+
+	stopped    atomic.Bool
 	continuous bool
 
+	// region is the part of the screen which is sampled.
+	region image.Rectangle
+
+	// preview is the side in pixels of the blocks sampled with a single pixel in
+	// the first continuous frame after a restart. See [SimpleSampler.SetPreview].
+	preview uint32
+
+	// restartAt is the number of sub samplers handed out before the last call to
+	// [SimpleSampler.Restart]. Continuous frames are counted from it.
+	restartAt atomic.Uint32
+
 	// pauseRequested stores 1 if there's an ongoing request for pausing the
 	// sampler. If the request is cancelled (set to 0) before the next request
 	// for the first sampler in the frame is made then no pausing will be initiated.
@@ -60,22 +103,28 @@ type SimpleSampler struct {
 // GetSubSampler returns a rectangular sampler for a smaller section of the screen.
 func (s *SimpleSampler) GetSubSampler() (*SubSampler, error) {
 
-	if s.stopped {
+	if s.stopped.Load() {
 		return nil, ErrEndOfSampling
 	}
 
-	sample := atomic.AddUint32(&s.current, 1) - 1
+	handedOut := atomic.AddUint32(&s.current, 1) - 1
+	sample := handedOut
+
+	activeCount := uint32(len(s.activeSubSamplers))
 
-	if !s.continuous && sample >= s.subSamplersCount {
+	if activeCount == 0 || (!s.continuous && sample >= activeCount) {
 		return nil, ErrEndOfSampling
 	}
 
-	if s.continuous && sample >= s.subSamplersCount {
-		sample = sample % s.subSamplersCount
+	if s.continuous && sample >= activeCount {
+		sample = sample % activeCount
 	}
 
-	ss := s.subSamplers[sample]
+	ss := s.activeSubSamplers[sample]
 	ss.Reset()
+	if s.continuous {
+		ss.startFrame(s.frame(handedOut, activeCount))
+	}
 
 	if sample == 0 {
 		s.output.DoneFrame()
@@ -103,9 +152,164 @@ func (s *SimpleSampler) UpdateScreen(x, y float64, clr color.Color) {
 	s.output.Set(int(x), int(y), clr)
 }
 
+// frame returns the number of the continuous frame since the last restart to which
+// the sub sampler handed out after `handedOut` others belongs. There are `count`
+// sub samplers in every frame.
+func (s *SimpleSampler) frame(handedOut, count uint32) uint32 {
+	start := s.restartAt.Load()
+	if handedOut < start {
+		return 0
+	}
+	return (handedOut - start) / count
+}
+
+// blockSize returns the side in pixels of the preview blocks in the continuous
+// frame with number `frame` since the last restart. It is 1 for frames at full
+// resolution.
+func (s *SimpleSampler) blockSize(frame uint32) uint32 {
+	if frame >= 32 {
+		return 1
+	}
+	return max(s.preview>>frame, 1)
+}
+
+// previewBlock returns the preview block with side `size` pixels which contains
+// the pixel at (x, y). It is clipped to the sampled region.
+func (s *SimpleSampler) previewBlock(x, y int, size uint32) image.Rectangle {
+	side := int(size)
+	origin := image.Pt(
+		s.region.Min.X+(x-s.region.Min.X)/side*side,
+		s.region.Min.Y+(y-s.region.Min.Y)/side*side,
+	)
+	return image.Rectangle{origin, origin.Add(image.Pt(side, side))}.Intersect(s.region)
+}
+
+// isBlockCentre returns true when `pixel` is the one sampled for its preview
+// block with side `size` pixels.
+func (s *SimpleSampler) isBlockCentre(pixel sampledPixel, size uint32) bool {
+	block := s.previewBlock(int(pixel.x), int(pixel.y), size)
+	half := int(size / 2)
+	return int(pixel.x) == min(block.Min.X+half, block.Max.X-1) &&
+		int(pixel.y) == min(block.Min.Y+half, block.Max.Y-1)
+}
+
+// NextPass prepares the sampler for taking another set of samples for every pixel.
+// Samples in the new pass are different from the ones in all previous passes. It
+// must not be called while sub samplers are in use.
+//
+// With adaptive sampling the pixels which have converged are not sampled anymore.
+// Their share of samples is redistributed to the rest of the pixels. Sub samplers
+// with only converged pixels are skipped altogether.
+func (s *SimpleSampler) NextPass() {
+	s.Restore(s.State())
+}
+
+// State returns the progress of the sampler after the current pass. It is enough
+// for continuing the sampling with [SimpleSampler.Restore] in another sampler,
+// possibly in another process.
+func (s *SimpleSampler) State() State {
+	var lastPerPixel uint32
+	for _, ss := range s.activeSubSamplers {
+		lastPerPixel = max(lastPerPixel, ss.passPerPixel)
+	}
+
+	return State{
+		Passes:     s.pass + 1,
+		SampleBase: s.sampleBase + lastPerPixel,
+	}
+}
+
+// Restore prepares the sampler for the pass which follows the ones in `st`. Its
+// samples are different from the ones in all of those passes. It must not be
+// called while sub samplers are in use.
+//
+// With adaptive sampling the pixels are selected using the samples already in the
+// output. So it has to be restored before the sampler.
+func (s *SimpleSampler) Restore(st State) {
+	s.sampleBase = st.SampleBase
+	s.pass = st.Passes
+	s.current = 0
+
+	if est, ok := s.output.(ErrorEstimator); ok && s.adaptiveThreshold > 0 {
+		s.adapt(est)
+	}
+}
+
+// Converged returns true when adaptive sampling has found that all pixels have
+// converged and there is nothing more to sample.
+func (s *SimpleSampler) Converged() bool {
+	return len(s.activeSubSamplers) == 0
+}
+
+// adapt selects the pixels which have to be sampled in the current pass based on
+// their error estimates.
+func (s *SimpleSampler) adapt(est ErrorEstimator) {
+	minSamples := max(2*int(s.samplesPerPixel), minAdaptiveSamples)
+
+	var totalPixels, activePixels int
+	for _, ss := range s.subSamplers {
+		ss.active = ss.active[:0]
+		for _, pixel := range ss.pixArray {
+			pixErr, n := est.PixelError(int(pixel.x), int(pixel.y))
+			if n < minSamples || pixErr > s.adaptiveThreshold {
+				ss.active = append(ss.active, pixel)
+			}
+		}
+		totalPixels += len(ss.pixArray)
+		activePixels += len(ss.active)
+	}
+
+	// Converged pixels leave samples to spare. They are given to the remaining pixels
+	// so that every pass costs roughly the same.
+	factor := uint32(maxAdaptiveFactor)
+	if activePixels > 0 {
+		factor = uint32(min(max(totalPixels/activePixels, 1), maxAdaptiveFactor))
+	}
+
+	s.activeSubSamplers = s.activeSubSamplers[:0]
+	for _, ss := range s.subSamplers {
+		ss.passPerPixel = ss.perPixel * factor
+		if len(ss.active) > 0 {
+			s.activeSubSamplers = append(s.activeSubSamplers, ss)
+		}
+	}
+}
+
+// Pass returns the number of the current rendering pass, starting from zero.
+func (s *SimpleSampler) Pass() int {
+	return int(s.pass)
+}
+
+// SubSamplers returns the number of sub samplers in which every pass is split.
+func (s *SimpleSampler) SubSamplers() int {
+	return len(s.subSamplers)
+}
+
+// SamplesPerPixel returns the number of samples taken for every pixel in a single
+// pass.
+func (s *SimpleSampler) SamplesPerPixel() int {
+	return int(s.samplesPerPixel)
+}
+
+// Frames returns the number of continuous frames at full resolution whose sub
+// samplers were all handed out since the last [SimpleSampler.Restart]. Preview
+// frames are not counted.
+func (s *SimpleSampler) Frames() int {
+	count := uint32(len(s.activeSubSamplers))
+	if count == 0 {
+		return 0
+	}
+
+	frames := int(s.frame(atomic.LoadUint32(&s.current), count))
+	for size := s.preview; size > 1; size /= 2 {
+		frames--
+	}
+	return max(frames, 0)
+}
+
 // Stop would cause all further calls to GetSample to return ErrEndOfSampling
 func (s *SimpleSampler) Stop() {
-	s.stopped = true
+	s.stopped.Store(true)
 }
 
 // Pause requests the sampler to stop handling out sub samplers. It will do that
@@ -153,57 +357,137 @@ func (s *SimpleSampler) doPause() {
 }
 
 // MakeContinuous makes sure this sampler would continue to generate samples
-// in perpetuity, eventually looping back to the start of the image
+// in perpetuity, eventually looping back to the start of the image. Every frame
+// takes new samples for the pixels so an output which accumulates them converges.
 func (s *SimpleSampler) MakeContinuous() {
 	s.continuous = true
 }
 
+// SetPreview makes the first continuous frames after every [SimpleSampler.Restart]
+// low resolution previews. The first one samples a single pixel of every block with
+// side `block` pixels and its colour is shown in the whole block. Every following
+// frame halves the block until the full resolution is reached. Values below 2
+// disable the preview. It must not be called while sub samplers are in use.
+func (s *SimpleSampler) SetPreview(block int) {
+	s.preview = uint32(max(block, 1))
+
+	for _, ss := range s.subSamplers {
+		ss.previews = make(map[uint32][]sampledPixel)
+		for size := s.preview; size > 1; size /= 2 {
+			var pixels []sampledPixel
+			for _, pixel := range ss.pixArray {
+				if s.isBlockCentre(pixel, size) {
+					pixels = append(pixels, pixel)
+				}
+			}
+			ss.previews[size] = pixels
+		}
+	}
+}
+
+// Restart makes continuous sampling start over from the next sub sampler. Frames
+// are counted from zero again so the preview is shown anew and the samples are the
+// same as in the first frame. Sub samplers which are already handed out are not
+// affected. It is used when the rendered image changes, for example after moving
+// the camera.
+func (s *SimpleSampler) Restart() {
+	s.restartAt.Store(atomic.LoadUint32(&s.current))
+}
+
 // NewSimple returns a SimpleSampler which would generate samples for a 2D output
-// with certain width and height.
-func NewSimple(width, height int, out Output) *SimpleSampler {
+// with certain width and height. Samples are generated as set in `cfg`. With a crop
+// window samples are generated only for the pixels in it.
+func NewSimple(width, height int, out Output, cfg Config) *SimpleSampler {
 	s := &SimpleSampler{
 		output:              out,
 		pauseLock:           &sync.RWMutex{},
 		minFamesBeforePause: 1,
+		preview:             1,
 	}
 
-	sampleSet := make(map[sampledPixel]struct{})
-	for x := range uint32(width) {
-		for y := range uint32(height) {
-			sampleSet[sampledPixel{x: x, y: y}] = struct{}{}
+	// Pixels are shuffled in small square blocks instead of one by one. This way
+	// neighbouring pixels stay next to each other in the list and could be traced
+	// together as ray packets. See [SubSampler.GetPacket].
+	var blocks []sampledPixel
+	region := cfg.region(width, height)
+	s.region = region
+	minX, minY := uint32(region.Min.X), uint32(region.Min.Y)
+	maxX, maxY := uint32(region.Max.X), uint32(region.Max.Y)
+
+	for x := minX; x < maxX; x += packetBlock {
+		for y := minY; y < maxY; y += packetBlock {
+			blocks = append(blocks, sampledPixel{x: x, y: y})
 		}
 	}
 
-	s.pixList = make([]sampledPixel, 0, width*height)
-
-	// Take advantage of the fact that iterating over a map returns its keys in
-	// a random order.
-	for k := range sampleSet {
-		s.pixList = append(s.pixList, k)
+	rnd := rand.New(rand.NewPCG(cfg.Seed, pixelShuffleStream))
+	rnd.Shuffle(len(blocks), func(i, j int) {
+		blocks[i], blocks[j] = blocks[j], blocks[i]
+	})
+
+	s.pixList = make([]sampledPixel, 0, region.Dx()*region.Dy())
+	for _, k := range blocks {
+		for y := k.y; y < min(k.y+packetBlock, maxY); y++ {
+			for x := k.x; x < min(k.x+packetBlock, maxX); x++ {
+				s.pixList = append(s.pixList, sampledPixel{x: x, y: y})
+			}
+		}
 	}
 
-	var splits = uint32(width / 32)
-	var count = splits * splits
-	var perSampler = uint32((width * height) / int(count))
+	tileSize := cfg.tileSize()
+	tilesX := (region.Dx() + tileSize - 1) / tileSize
+	tilesY := (region.Dy() + tileSize - 1) / tileSize
 
-	fmt.Printf("Creating %d sub samplers\n", count)
+	var count = uint32(max(tilesX*tilesY, 1))
+	var perSampler = (uint32(len(s.pixList)) + count - 1) / count
 
-	s.subSamplersCount = count
+	// Keep the pixel blocks whole in every sub sampler.
+	const blockPixels = packetBlock * packetBlock
+	perSampler = (perSampler + blockPixels - 1) / blockPixels * blockPixels
+
+	s.samplesPerPixel = uint32(cfg.samplesPerPixel())
+	s.adaptiveThreshold = cfg.AdaptiveThreshold
 	s.subSamplers = make([]*SubSampler, count)
 
 	for i := range count {
-		start := i * perSampler
-		end := start + perSampler
-		if end > uint32(len(s.pixList)) {
-			end = uint32(len(s.pixList))
-		}
+		start := min(i*perSampler, uint32(len(s.pixList)))
+		end := min(start+perSampler, uint32(len(s.pixList)))
 
 		subPixels := s.pixList[start:end]
-		s.subSamplers[i] = NewSubSampler(subPixels, 4, s)
+		s.subSamplers[i] = NewSubSampler(
+			subPixels,
+			s.samplesPerPixel,
+			cfg.NewSampler(),
+			s,
+		)
 	}
+	s.activeSubSamplers = slices.Clone(s.subSamplers)
+
 	return s
 }
 
+// ErrorEstimator is implemented by outputs which are able to estimate how far the
+// accumulated value of every pixel is from the converged one.
+type ErrorEstimator interface {
+	// PixelError returns the relative error of the pixel at (x, y) and the number
+	// of samples taken for it.
+	PixelError(x, y int) (float64, int)
+}
+
+const (
+	// minAdaptiveSamples is the minimal number of samples for a pixel before its
+	// error estimate is trusted for adaptive sampling.
+	minAdaptiveSamples = 8
+
+	// pixelShuffleStream is the PCG stream used for shuffling the pixels between
+	// sub samplers. It keeps the shuffle independent from other uses of the seed.
+	pixelShuffleStream = 0x5bd1e995
+
+	// maxAdaptiveFactor limits how many times the samples per pixel could grow in
+	// a single pass with adaptive sampling.
+	maxAdaptiveFactor = 4
+)
+
 type sampledPixel struct {
 	x, y uint32
 }
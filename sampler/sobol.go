@@ -0,0 +1,54 @@
+package sampler
+
+// SobolSampler generates samples from the first two dimensions of the Sobol
+// sequence. Higher dimensions are "padded": every pair of dimensions uses the same
+// two Sobol dimensions but with sample indices which are randomly shuffled for
+// every pixel and dimension. All values are Owen scrambled. It works best when the
+// number of samples per pixel is a power of two.
+type SobolSampler struct {
+	pixelSample
+
+	spp int
+}
+
+// NewSobol returns a new [SobolSampler] for `spp` samples per pixel.
+func NewSobol(spp int) *SobolSampler {
+	return &SobolSampler{spp: spp}
+}
+
+// shuffledIndex returns the sample index permuted for the current pixel and
+// dimension.
+func (s *SobolSampler) shuffledIndex(h uint64) uint32 {
+	index := uint32(s.index % s.spp)
+	round := uint32(s.index / s.spp)
+	return round*uint32(s.spp) + permutationElement(index, uint32(s.spp), uint32(h))
+}
+
+// Get1D implements [Sampler].
+func (s *SobolSampler) Get1D() float64 {
+	h := s.hash(0)
+	s.dimension++
+	return sobolSample(s.shuffledIndex(h), 0, uint32(h>>32))
+}
+
+// Get2D implements [Sampler].
+func (s *SobolSampler) Get2D() (float64, float64) {
+	h := s.hash(0)
+	s.dimension += 2
+
+	index := s.shuffledIndex(h)
+	return sobolSample(index, 0, uint32(h>>32)),
+		sobolSample(index, 1, uint32(mixBits(h)>>32))
+}
+
+// GetPixel2D implements [Sampler].
+func (s *SobolSampler) GetPixel2D() (float64, float64) {
+	return s.Get2D()
+}
+
+// Clone implements [Sampler].
+func (s *SobolSampler) Clone() Sampler {
+	clone := NewSobol(s.spp)
+	clone.seed = s.seed
+	return clone
+}
@@ -0,0 +1,70 @@
+package sampler
+
+import "math"
+
+// StratifiedSampler places the samples of every pixel in separate strata. In 1D
+// there are as many strata as samples per pixel. In 2D the pixel is divided in a
+// grid with as many cells as samples per pixel which is as close to a square as
+// possible. Samples are jittered within their strata. Strata are randomly shuffled
+// for every pixel and dimension so that dimensions are not correlated.
+type StratifiedSampler struct {
+	pixelSample
+
+	spp    int
+	xStrat int
+	yStrat int
+}
+
+// NewStratified returns a new [StratifiedSampler] for `spp` samples per pixel.
+func NewStratified(spp int) *StratifiedSampler {
+	xStrat := int(math.Sqrt(float64(spp)))
+	for spp%xStrat != 0 {
+		xStrat--
+	}
+
+	return &StratifiedSampler{
+		spp:    spp,
+		xStrat: xStrat,
+		yStrat: spp / xStrat,
+	}
+}
+
+// stratum returns the randomly permuted stratum of the current sample for the
+// current dimension.
+func (s *StratifiedSampler) stratum() int {
+	index := s.index % s.spp
+	return int(permutationElement(uint32(index), uint32(s.spp), uint32(s.hash(0))))
+}
+
+// Get1D implements [Sampler].
+func (s *StratifiedSampler) Get1D() float64 {
+	stratum := s.stratum()
+	jitter := randomFloat(s.hash(uint64(s.index)<<2 | 1))
+	s.dimension++
+
+	return min((float64(stratum)+jitter)/float64(s.spp), oneMinusEpsilon)
+}
+
+// Get2D implements [Sampler].
+func (s *StratifiedSampler) Get2D() (float64, float64) {
+	stratum := s.stratum()
+	jx := randomFloat(s.hash(uint64(s.index)<<2 | 1))
+	jy := randomFloat(s.hash(uint64(s.index)<<2 | 2))
+	s.dimension += 2
+
+	x, y := stratum%s.xStrat, stratum/s.xStrat
+	return min((float64(x)+jx)/float64(s.xStrat), oneMinusEpsilon),
+		min((float64(y)+jy)/float64(s.yStrat), oneMinusEpsilon)
+}
+
+// GetPixel2D implements [Sampler].
+func (s *StratifiedSampler) GetPixel2D() (float64, float64) {
+	return s.Get2D()
+}
+
+// Clone implements [Sampler].
+func (s *StratifiedSampler) Clone() Sampler {
+	clone := NewStratified(s.spp)
+	clone.seed = s.seed
+	return clone
+}
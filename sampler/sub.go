@@ -2,8 +2,9 @@ package sampler
 
 import (
 	"errors"
-	"math/rand"
-	"time"
+	"fmt"
+	"image/color"
+	"slices"
 )
 
 // ErrSubSamplerEnd represents the end this sub-sampler's cycle
@@ -12,7 +13,27 @@ var ErrSubSamplerEnd = errors.New("End of sub sampler")
 // SubSampler generates samples for a rectangular subsection of a sampler
 type SubSampler struct {
 	pixArray []sampledPixel
-	randoms  []sampleRand
+	sampler  Sampler
+
+	// active are the pixels which are sampled in the current pass. Normally they are
+	// all pixels in pixArray. With adaptive sampling converged pixels are left out.
+	// See [SimpleSampler.NextPass].
+	active []sampledPixel
+
+	// passPerPixel is the number of samples for every active pixel in the current
+	// pass. With adaptive sampling it could be more than perPixel.
+	passPerPixel uint32
+
+	// block is the side in pixels of the preview blocks in the current frame. It is
+	// 1 for frames at full resolution. See [SimpleSampler.SetPreview].
+	block uint32
+
+	// previews are the pixels sampled in the preview frames for every block size.
+	previews map[uint32][]sampledPixel
+
+	// frameBase is added to the sample indices in the current continuous frame so
+	// that every frame takes new samples.
+	frameBase uint32
 
 	current     uint32
 	perPixel    uint32
@@ -23,26 +44,133 @@ type SubSampler struct {
 
 // GetSample returns a single sample which should be raytraced.
 func (s *SubSampler) GetSample() (x, y float64, err error) {
-	if s.current >= uint32(len(s.pixArray)) {
-		if s.samplesDone+1 >= s.perPixel {
+	pixels := s.pixels()
+	if len(pixels) == 0 {
+		err = ErrSubSamplerEnd
+		return
+	}
+	if s.current >= uint32(len(pixels)) {
+		if s.samplesDone+1 >= s.framePerPixel() {
 			err = ErrSubSamplerEnd
 			return
 		}
 		s.current = 0
 		s.samplesDone++
 	}
-	if s.parent.stopped {
+	if s.parent.stopped.Load() {
 		err = ErrEndOfSampling
 		return
 	}
-	sample := s.pixArray[s.current]
-
-	x = float64(sample.x) + s.randoms[s.samplesDone].x
-	y = float64(sample.y) + s.randoms[s.samplesDone].y
+	x, y = s.pixelSample(pixels[s.current], s.sampleIndex(s.samplesDone))
 	s.current++
 	return
 }
 
+// pixelSample returns the screen position of the sample with number `index` for
+// `pixel`.
+func (s *SubSampler) pixelSample(pixel sampledPixel, index int) (x, y float64) {
+	s.sampler.StartPixelSample(int(pixel.x), int(pixel.y), index)
+	dx, dy := s.sampler.GetPixel2D()
+	return float64(pixel.x) + dx, float64(pixel.y) + dy
+}
+
+// sampleIndex returns the index of the `n`-th sample for a pixel in the current
+// pass of the parent sampler.
+func (s *SubSampler) sampleIndex(n uint32) int {
+	return int(s.parent.sampleBase + s.frameBase + n)
+}
+
+// pixels returns the pixels sampled in the current frame.
+func (s *SubSampler) pixels() []sampledPixel {
+	if s.block > 1 {
+		return s.previews[s.block]
+	}
+	return s.active
+}
+
+// framePerPixel returns the number of samples for every pixel in the current
+// frame. Preview frames take a single one.
+func (s *SubSampler) framePerPixel() uint32 {
+	if s.block > 1 {
+		return 1
+	}
+	return s.passPerPixel
+}
+
+// startFrame prepares the sub sampler for the continuous frame with number `frame`
+// since the last restart of the parent sampler.
+func (s *SubSampler) startFrame(frame uint32) {
+	s.block = s.parent.blockSize(frame)
+	s.frameBase = frame * s.passPerPixel
+}
+
+// UpdateScreen sets the colour of the sample at (x, y) in the output of the parent
+// sampler. In preview frames the colour is shown in the whole preview block.
+func (s *SubSampler) UpdateScreen(x, y float64, clr color.Color) {
+	if s.block <= 1 {
+		s.parent.UpdateScreen(x, y, clr)
+		return
+	}
+
+	px, py := int(x), int(y)
+	block := s.parent.previewBlock(px, py, s.block)
+	if out, ok := s.parent.output.(BlockOutput); ok {
+		out.SetBlock(px, py, block, clr)
+		return
+	}
+
+	for by := block.Min.Y; by < block.Max.Y; by++ {
+		for bx := block.Min.X; bx < block.Max.X; bx++ {
+			s.parent.output.Set(bx, by, clr)
+		}
+	}
+}
+
+// Sampler returns the sampler which generated the last sample. It could be used for
+// getting the rest of the sample vector for it.
+func (s *SubSampler) Sampler() Sampler {
+	return s.sampler
+}
+
+// GetPacket fills `buf` with all samples for the next few neighbouring pixels. They
+// are suitable for tracing together as a ray packet. It returns the number of
+// samples written in `buf` which should have a length of at least
+// [SubSampler.PacketLen].
+//
+// GetPacket and [SubSampler.GetSample] must not be used together for the same frame.
+func (s *SubSampler) GetPacket(buf []Sample) (int, error) {
+	if s.parent.stopped.Load() {
+		return 0, ErrEndOfSampling
+	}
+	if len(buf) < s.PacketLen() {
+		return 0, fmt.Errorf("packet buffer too small: %d samples, need %d",
+			len(buf), s.PacketLen())
+	}
+	pixels := s.pixels()
+	if s.current >= uint32(len(pixels)) {
+		return 0, ErrSubSamplerEnd
+	}
+
+	end := min(s.current+packetBlock*packetBlock, uint32(len(pixels)))
+
+	var n int
+	for index := range int(s.framePerPixel()) {
+		for _, pixel := range pixels[s.current:end] {
+			buf[n].X, buf[n].Y = s.pixelSample(pixel, s.sampleIndex(uint32(index)))
+			n++
+		}
+	}
+
+	s.current = end
+	return n, nil
+}
+
+// PacketLen returns the maximum number of samples returned by a single call to
+// [SubSampler.GetPacket].
+func (s *SubSampler) PacketLen() int {
+	return packetBlock * packetBlock * int(s.passPerPixel)
+}
+
 // Reset returns this sub sampler to its initial condition and ready for the next frame
 func (s *SubSampler) Reset() {
 	s.samplesDone = 0
@@ -50,29 +178,25 @@ func (s *SubSampler) Reset() {
 }
 
 // NewSubSampler returns a sub sampler which is responsible for a particular set of
-// pixels on the screen.
-func NewSubSampler(pixArray []sampledPixel, perPixel uint32, p *SimpleSampler) *SubSampler {
-	src := rand.NewSource(time.Now().UnixMicro())
-	rnd := rand.New(src)
-
-	rands := make([]sampleRand, 0, perPixel)
-	for range perPixel {
-		rands = append(rands, sampleRand{
-			x: rnd.Float64(),
-			y: rnd.Float64(),
-		})
-	}
-
+// pixels on the screen. Sample positions within pixels are generated by `smpl`.
+func NewSubSampler(
+	pixArray []sampledPixel,
+	perPixel uint32,
+	smpl Sampler,
+	p *SimpleSampler,
+) *SubSampler {
 	return &SubSampler{
-		pixArray: pixArray,
-		perPixel: perPixel,
-		parent:   p,
-		randoms:  rands,
+		pixArray:     pixArray,
+		active:       slices.Clone(pixArray),
+		perPixel:     perPixel,
+		passPerPixel: perPixel,
+		block:        1,
+		sampler:      smpl,
+		parent:       p,
 	}
 }
 
-// sampleRand is a random position within a sampled pixel.
-type sampleRand struct {
-	x float64
-	y float64
+// Sample is a position on the screen which should be raytraced.
+type Sample struct {
+	X, Y float64
 }
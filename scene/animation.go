@@ -0,0 +1,296 @@
+package scene
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ironsmile/raytracer/camera"
+	"github.com/ironsmile/raytracer/geometry"
+	"github.com/ironsmile/raytracer/primitive"
+	"github.com/ironsmile/raytracer/transform"
+)
+
+// Animation describes how the camera and the objects of a scene change from frame
+// to frame. Properties without keyframes keep their values from the scene.
+//
+// It is stored as JSON, for example:
+//
+//	{
+//	    "camera": {
+//	        "projection": "pinhole",
+//	        "position": [
+//	            {"frame": 0, "value": [0, 0, -5], "curve": "smooth"},
+//	            {"frame": 48, "value": [4, 2, -8]}
+//	        ],
+//	        "fov": [{"frame": 0, "value": 90}, {"frame": 48, "value": 60}]
+//	    },
+//	    "objects": {
+//	        "teapot": {
+//	            "rotate": [{"frame": 0, "value": [0, 0, 0]}, {"frame": 48, "value": [0, 360, 0]}]
+//	        }
+//	    }
+//	}
+//
+// The curves are the names of [Curves]. Linear is the default.
+type Animation struct {
+	Camera CameraAnimation `json:"camera"`
+
+	// Objects are the animations of the primitives of the scene with the given
+	// names. See [primitive.SetName].
+	Objects map[string]ObjectAnimation `json:"objects,omitempty"`
+}
+
+// CameraAnimation describes how the camera moves.
+type CameraAnimation struct {
+	// Projection is the type of the camera. It is pinhole when not set.
+	Projection camera.Projection `json:"projection,omitempty"`
+
+	Position Track[[3]float64] `json:"position"`
+	LookAt   Track[[3]float64] `json:"lookAt"`
+	Up       Track[[3]float64] `json:"up"`
+
+	// FOV is the field of view in degrees. It may be animated only for pinhole
+	// and fisheye cameras. See [camera.Options].
+	FOV Track[float64] `json:"fov"`
+
+	// Size is the size of the area seen by orthographic cameras. It may be
+	// animated only for them. See [camera.Options].
+	Size Track[float64] `json:"size"`
+}
+
+// ObjectAnimation describes how an object moves. The transformations are applied
+// in world space around the origin of the object in its initial position: first
+// the scaling, then the rotation and finally the translation.
+type ObjectAnimation struct {
+	Translate Track[[3]float64] `json:"translate"`
+
+	// Rotate has the angles in degrees around the X, Y and Z axes. The object is
+	// rotated around them in that order.
+	Rotate Track[[3]float64] `json:"rotate"`
+
+	Scale Track[[3]float64] `json:"scale"`
+}
+
+// LoadAnimation reads an animation from a JSON file. See [Animation].
+func LoadAnimation(filename string) (*Animation, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var a Animation
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, fmt.Errorf("parsing animation %s: %w", filename, err)
+	}
+	if err := a.validate(); err != nil {
+		return nil, fmt.Errorf("animation %s: %w", filename, err)
+	}
+
+	return &a, nil
+}
+
+// Save writes the animation as JSON in `filename`. It may be read back with
+// [LoadAnimation].
+func (a *Animation) Save(filename string) error {
+	data, err := json.MarshalIndent(a, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0o644)
+}
+
+// validate checks that the keyframes of all tracks are in order.
+func (a *Animation) validate() error {
+	tracks := map[string]interface{ validate() error }{
+		"camera position": a.Camera.Position,
+		"camera look at":  a.Camera.LookAt,
+		"camera up":       a.Camera.Up,
+		"camera FOV":      a.Camera.FOV,
+		"camera size":     a.Camera.Size,
+	}
+	for name, obj := range a.Objects {
+		tracks[name+" translate"] = obj.Translate
+		tracks[name+" rotate"] = obj.Rotate
+		tracks[name+" scale"] = obj.Scale
+	}
+
+	for name, track := range tracks {
+		if err := track.validate(); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return a.Camera.validate()
+}
+
+// validate checks that the projection of the camera is able to use the values of
+// all its keyframes.
+func (c CameraAnimation) validate() error {
+	if c.FOV.Animated() && defaultFOV(c.Projection) == 0 {
+		return fmt.Errorf("%s camera has no field of view", c.Projection)
+	}
+	if c.Size.Animated() && c.Projection != camera.ProjectionOrthographic {
+		return fmt.Errorf("%s camera has no size", c.Projection)
+	}
+
+	for _, k := range c.FOV {
+		if k.Value <= 0 {
+			return fmt.Errorf("camera FOV at frame %g is not positive", k.Frame)
+		}
+
+		opts := CameraOptions(1, 1)
+		opts.Projection = c.Projection
+		opts.FOV = k.Value
+		if _, err := camera.New(opts); err != nil {
+			return fmt.Errorf("camera FOV at frame %g: %w", k.Frame, err)
+		}
+	}
+	for _, k := range c.Size {
+		if k.Value <= 0 {
+			return fmt.Errorf("camera size at frame %g is not positive", k.Frame)
+		}
+	}
+	return nil
+}
+
+// defaultFOV returns the field of view of cameras with projection `p` when it is
+// not animated. It is zero for projections without a field of view.
+func defaultFOV(p camera.Projection) float64 {
+	switch p {
+	case camera.ProjectionPinhole:
+		return camera.DefaultPinholeFOV
+	case camera.ProjectionFisheyeEquidistant, camera.ProjectionFisheyeEquisolid:
+		return camera.DefaultFisheyeFOV
+	default:
+		return 0
+	}
+}
+
+// CameraAt returns the camera at `frame` for an image with the given size. It
+// fails when the projection of the camera cannot use the animated values.
+func (a *Animation) CameraAt(frame float64, width, height float64) (camera.Camera, error) {
+	if err := a.Camera.validate(); err != nil {
+		return nil, err
+	}
+	return camera.New(a.CameraStateAt(frame).Options(width, height))
+}
+
+// CameraStateAt returns the state of the camera at `frame`. Properties without
+// keyframes have the values of the camera of the demo scenes.
+func (a *Animation) CameraStateAt(frame float64) camera.State {
+	state := camera.State{
+		Projection: a.Camera.Projection,
+		Position:   defaultCameraPosition,
+		LookAt:     defaultCameraLookAt,
+		Up:         defaultCameraUp,
+		FOV:        defaultFOV(a.Camera.Projection),
+	}
+
+	if a.Camera.Position.Animated() {
+		state.Position = toVector(a.Camera.Position.At(frame))
+	}
+	if a.Camera.LookAt.Animated() {
+		state.LookAt = toVector(a.Camera.LookAt.At(frame))
+	}
+	if a.Camera.Up.Animated() {
+		state.Up = toVector(a.Camera.Up.At(frame))
+	}
+	if a.Camera.FOV.Animated() {
+		state.FOV = a.Camera.FOV.At(frame)
+	}
+	if a.Camera.Size.Animated() {
+		state.Size = a.Camera.Size.At(frame)
+	}
+
+	return state
+}
+
+// CameraEnd returns the frame of the last keyframe of the camera. The camera does
+// not move after it.
+func (a *Animation) CameraEnd() float64 {
+	var end float64
+	for _, track := range []Track[[3]float64]{
+		a.Camera.Position,
+		a.Camera.LookAt,
+		a.Camera.Up,
+	} {
+		if track.Animated() {
+			end = max(end, track[len(track)-1].Frame)
+		}
+	}
+	for _, track := range []Track[float64]{a.Camera.FOV, a.Camera.Size} {
+		if track.Animated() {
+			end = max(end, track[len(track)-1].Frame)
+		}
+	}
+	return end
+}
+
+// transformAt returns the object to world transformation at `frame` for an object
+// whose initial transformation is `initial`.
+func (o ObjectAnimation) transformAt(frame float64, initial *transform.Transform) *transform.Transform {
+	origin := initial.Point(geometry.NewVector(0, 0, 0))
+	t := transform.Translate(origin.Neg())
+
+	if o.Scale.Animated() {
+		s := o.Scale.At(frame)
+		t = transform.Scale(s[0], s[1], s[2]).Multiply(t)
+	}
+	if o.Rotate.Animated() {
+		r := o.Rotate.At(frame)
+		t = transform.RotateZ(r[2]).Multiply(transform.RotateY(r[1])).
+			Multiply(transform.RotateX(r[0])).Multiply(t)
+	}
+	if o.Translate.Animated() {
+		origin = origin.Plus(toVector(o.Translate.At(frame)))
+	}
+
+	return transform.Translate(origin).Multiply(t).Multiply(initial)
+}
+
+// Animate moves the objects of the scene to where `a` says they are at `frame`.
+// The scene accelerator is rebuilt only when some of them are animated. Otherwise
+// the scene is left untouched.
+func (s *Scene) Animate(a *Animation, frame float64) error {
+	if len(a.Objects) == 0 {
+		return nil
+	}
+
+	if s.initialTransforms == nil {
+		s.initialTransforms = make(map[uint64]*transform.Transform)
+		for _, prim := range s.Primitives {
+			o2w, _ := prim.GetTransforms()
+			s.initialTransforms[prim.GetID()] = o2w
+		}
+	}
+
+	for name, anim := range a.Objects {
+		found := false
+		for _, prim := range s.Primitives {
+			if primitive.GetName(prim.GetID()) != name {
+				continue
+			}
+			found = true
+
+			o2w := anim.transformAt(frame, s.initialTransforms[prim.GetID()])
+			prim.SetTransform(o2w)
+
+			// Lights are shaded using their light source point.
+			if base, ok := prim.(*primitive.BasePrimitive); ok && base.Light {
+				base.LightSource = o2w.Point(geometry.NewVector(0, 0, 0))
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("animated object %q is not in the scene", name)
+		}
+	}
+
+	s.buildAccel()
+	return nil
+}
+
+// toVector converts a keyframe value to a vector.
+func toVector(v [3]float64) geometry.Vector {
+	return geometry.NewVector(v[0], v[1], v[2])
+}
@@ -0,0 +1,79 @@
+package scene
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ironsmile/raytracer/geometry"
+	"github.com/ironsmile/raytracer/primitive"
+	"github.com/ironsmile/raytracer/transform"
+)
+
+// TestAnimate checks that animated objects are moved around their origin and that
+// lights move together with their light source.
+func TestAnimate(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "animation.json")
+	err := os.WriteFile(file, []byte(`{
+		"objects": {
+			"big red sphere": {
+				"translate": [{"frame": 0, "value": [0, 0, 0]}, {"frame": 10, "value": [0, 4, 0]}],
+				"scale": [{"frame": 0, "value": [1, 1, 1]}, {"frame": 10, "value": [2, 2, 2]}]
+			},
+			"Visible light source": {
+				"translate": [{"frame": 0, "value": [0, 0, 0]}, {"frame": 10, "value": [-2, 0, 0]}]
+			}
+		}
+	}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := LoadAnimation(file)
+	if err != nil {
+		t.Fatalf("loading animation: %s", err)
+	}
+
+	s := NewScene()
+
+	sphere := primitive.NewSphere(2.5)
+	sphere.SetTransform(transform.Translate(geometry.NewVector(1, -0.8, 3)))
+	primitive.SetName(sphere.GetID(), "big red sphere")
+
+	light := primitive.NewSphere(0.1)
+	light.Light = true
+	light.LightSource = geometry.NewVector(0, 5, 5)
+	light.SetTransform(transform.Translate(light.LightSource))
+	primitive.SetName(light.GetID(), "Visible light source")
+
+	s.Primitives = append(s.Primitives, sphere, light)
+	s.Lights = append(s.Lights, light)
+
+	for _, frame := range []float64{5, 10} {
+		if err := s.Animate(a, frame); err != nil {
+			t.Fatalf("animating frame %g: %s", frame, err)
+		}
+	}
+
+	bb := sphere.GetWorldBBox()
+	wantMin := geometry.NewVector(1-5, -0.8+4-5, 3-5)
+	wantMax := geometry.NewVector(1+5, -0.8+4+5, 3+5)
+	if !closeVectors(bb.Min, wantMin) || !closeVectors(bb.Max, wantMax) {
+		t.Errorf("expected the sphere in %s - %s but it is in %s - %s",
+			wantMin, wantMax, bb.Min, bb.Max)
+	}
+
+	if want := geometry.NewVector(-2, 5, 5); !closeVectors(light.LightSource, want) {
+		t.Errorf("expected the light source at %s but it is at %s", want, light.LightSource)
+	}
+
+	a.Objects["no such object"] = ObjectAnimation{}
+	if err := s.Animate(a, 0); err == nil {
+		t.Errorf("expected an error for an object which is not in the scene")
+	}
+}
+
+func closeVectors(a, b geometry.Vector) bool {
+	return math.Abs(a.X-b.X) < 1e-9 && math.Abs(a.Y-b.Y) < 1e-9 && math.Abs(a.Z-b.Z) < 1e-9
+}
@@ -5,10 +5,36 @@ import (
 	"github.com/ironsmile/raytracer/geometry"
 )
 
+// The camera of all demo scenes. An [Animation] may change all of these.
+var (
+	defaultCameraPosition = geometry.NewVector(0, 0, -5)
+	defaultCameraLookAt   = geometry.NewVector(0, 0, 1)
+	defaultCameraUp       = geometry.NewVector(0, 1, 0)
+	defaultCameraDistance = 1.0
+)
+
+// GetCamera returns the pinhole camera of the demo scenes for an image with the
+// given size.
 func GetCamera(w, h float64) camera.Camera {
-	pos := geometry.NewVector(0, 0, -5)
-	lookAtPoint := geometry.NewVector(0, 0, 1)
-	up := geometry.NewVector(0, 1, 0)
+	return camera.NewPinhole(
+		defaultCameraPosition,
+		defaultCameraLookAt,
+		defaultCameraUp,
+		defaultCameraDistance,
+		w, h,
+	)
+}
 
-	return camera.NewPinhole(pos, lookAtPoint, up, 1, w, h)
+// CameraOptions returns the options of the camera of the demo scenes for an image
+// with the given size. They may be changed before creating the camera with
+// [camera.New], for example for using another projection.
+func CameraOptions(w, h float64) camera.Options {
+	return camera.Options{
+		Projection: camera.ProjectionPinhole,
+		Position:   defaultCameraPosition,
+		LookAt:     defaultCameraLookAt,
+		Up:         defaultCameraUp,
+		Width:      w,
+		Height:     h,
+	}
 }
@@ -1,17 +1,18 @@
 package example
 
 import (
-	"fmt"
+	"log/slog"
 	"path/filepath"
 
 	"github.com/ironsmile/raytracer/geometry"
 	"github.com/ironsmile/raytracer/mat"
 	"github.com/ironsmile/raytracer/primitive"
 	"github.com/ironsmile/raytracer/transform"
+	"github.com/ironsmile/raytracer/utils"
 )
 
 // GetCarScene returns a predominantly emtpy scene with the alfa147 in the middle
-func GetCarScene() ([]primitive.Primitive, []primitive.Primitive) {
+func GetCarScene(logger *slog.Logger) ([]primitive.Primitive, []primitive.Primitive) {
 	var primitives []primitive.Primitive
 	var lights []primitive.Primitive
 
@@ -128,8 +129,8 @@ func GetCarScene() ([]primitive.Primitive, []primitive.Primitive) {
 	lights = append(lights, sphere)
 
 	alfaPath := filepath.Join("data", "objs", "alfa147.obj")
-	if obj, err := primitive.NewObject(alfaPath); err != nil {
-		fmt.Printf("Error loading obj alfa147: %s\n", err)
+	if obj, err := primitive.NewObject(alfaPath, logger); err != nil {
+		utils.Logger(logger).Error("cannot load model", "file", alfaPath, "error", err)
 	} else {
 		objTransform := transform.Translate(geometry.NewVector(-2.5, -5, 3)).Multiply(
 			transform.UniformScale(1).Multiply(
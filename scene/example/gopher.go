@@ -1,17 +1,18 @@
 package example
 
 import (
-	"fmt"
+	"log/slog"
 	"path/filepath"
 
 	"github.com/ironsmile/raytracer/geometry"
 	"github.com/ironsmile/raytracer/mat"
 	"github.com/ironsmile/raytracer/primitive"
 	"github.com/ironsmile/raytracer/transform"
+	"github.com/ironsmile/raytracer/utils"
 )
 
 // GetGopherScene returns a predominantly emtpy scene with the Gopher in focus.
-func GetGopherScene() ([]primitive.Primitive, []primitive.Primitive) {
+func GetGopherScene(logger *slog.Logger) ([]primitive.Primitive, []primitive.Primitive) {
 	var primitives []primitive.Primitive
 	var lights []primitive.Primitive
 
@@ -128,8 +129,8 @@ func GetGopherScene() ([]primitive.Primitive, []primitive.Primitive) {
 	lights = append(lights, sphere)
 
 	gopherPath := filepath.Join("data", "objs", "gopher.obj")
-	if obj, err := primitive.NewObject(gopherPath); err != nil {
-		fmt.Printf("Error loading obj gopher: %s\n", err)
+	if obj, err := primitive.NewObject(gopherPath, logger); err != nil {
+		utils.Logger(logger).Error("cannot load model", "file", gopherPath, "error", err)
 	} else {
 		objTransform := transform.UniformScale(0.25).Multiply(
 			transform.Translate(geometry.NewVector(0, -3, -15)),
@@ -1,17 +1,18 @@
 package example
 
 import (
-	"fmt"
+	"log/slog"
 	"path/filepath"
 
 	"github.com/ironsmile/raytracer/geometry"
 	"github.com/ironsmile/raytracer/mat"
 	"github.com/ironsmile/raytracer/primitive"
 	"github.com/ironsmile/raytracer/transform"
+	"github.com/ironsmile/raytracer/utils"
 )
 
 // GetTeapotScene returns the default teapot scene used throughout the development
-func GetTeapotScene() ([]primitive.Primitive, []primitive.Primitive) {
+func GetTeapotScene(logger *slog.Logger) ([]primitive.Primitive, []primitive.Primitive) {
 	var primitives []primitive.Primitive
 	var lights []primitive.Primitive
 
@@ -180,8 +181,8 @@ func GetTeapotScene() ([]primitive.Primitive, []primitive.Primitive) {
 	lights = append(lights, sphere)
 
 	teapotPath := filepath.Join("data", "objs", "teapot.obj")
-	if obj, err := primitive.NewObject(teapotPath); err != nil {
-		fmt.Printf("error loading obj teapot: %s\n", err)
+	if obj, err := primitive.NewObject(teapotPath, logger); err != nil {
+		utils.Logger(logger).Error("cannot load model", "file", teapotPath, "error", err)
 	} else {
 		objTransform := transform.Translate(geometry.NewVector(-3, 0, 5)).Multiply(
 			transform.UniformScale(0.2),
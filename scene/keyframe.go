@@ -0,0 +1,193 @@
+package scene
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Curve is the interpolation curve between two keyframes.
+type Curve int
+
+const (
+	// CurveLinear changes the value at a constant rate.
+	CurveLinear Curve = iota
+
+	// CurveStep keeps the value of a keyframe until the next one.
+	CurveStep
+
+	// CurveSmooth eases in and out of the keyframes so that the value starts and
+	// stops changing gradually.
+	CurveSmooth
+
+	// CurveCatmullRom is a spline which passes through the keyframes and also
+	// takes the keyframes around them into account. Movement through a keyframe
+	// does not stop or change its speed abruptly.
+	CurveCatmullRom
+)
+
+// Curves is a list of all interpolation curves.
+var Curves = []Curve{CurveLinear, CurveStep, CurveSmooth, CurveCatmullRom}
+
+// String implements fmt.Stringer.
+func (c Curve) String() string {
+	switch c {
+	case CurveLinear:
+		return "linear"
+	case CurveStep:
+		return "step"
+	case CurveSmooth:
+		return "smooth"
+	case CurveCatmullRom:
+		return "catmull-rom"
+	default:
+		return fmt.Sprintf("Curve(%d)", int(c))
+	}
+}
+
+// ParseCurve returns the interpolation curve with name `name`.
+func ParseCurve(name string) (Curve, error) {
+	for _, c := range Curves {
+		if c.String() == name {
+			return c, nil
+		}
+	}
+
+	names := make([]string, 0, len(Curves))
+	for _, c := range Curves {
+		names = append(names, c.String())
+	}
+
+	return CurveLinear, fmt.Errorf(
+		"unknown interpolation curve %q, possible values: %s",
+		name, strings.Join(names, ", "),
+	)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (c *Curve) UnmarshalText(text []byte) error {
+	parsed, err := ParseCurve(string(text))
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (c Curve) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// weights returns how much the keyframes before the segment, at its start, at its
+// end and after it contribute to the value at position `t` in [0, 1) of the
+// segment.
+func (c Curve) weights(t float64) [4]float64 {
+	switch c {
+	case CurveStep:
+		return [4]float64{0, 1, 0, 0}
+	case CurveSmooth:
+		s := t * t * (3 - 2*t)
+		return [4]float64{0, 1 - s, s, 0}
+	case CurveCatmullRom:
+		t2, t3 := t*t, t*t*t
+		return [4]float64{
+			(-t + 2*t2 - t3) / 2,
+			(2 - 5*t2 + 3*t3) / 2,
+			(t + 4*t2 - 3*t3) / 2,
+			(-t2 + t3) / 2,
+		}
+	default:
+		return [4]float64{0, 1 - t, t, 0}
+	}
+}
+
+// keyValue is the type of the values which can be animated.
+type keyValue interface {
+	float64 | [3]float64
+}
+
+// Keyframe is the value of an animated property at a certain frame.
+type Keyframe[T keyValue] struct {
+	Frame float64 `json:"frame"`
+	Value T       `json:"value"`
+
+	// Curve is the interpolation from this keyframe to the next one.
+	Curve Curve `json:"curve"`
+}
+
+// Track is the animation of a single property. Its keyframes are sorted by frame.
+// Before the first keyframe and after the last one the value does not change.
+type Track[T keyValue] []Keyframe[T]
+
+// Animated returns true when the track has any keyframes.
+func (tr Track[T]) Animated() bool {
+	return len(tr) > 0
+}
+
+// At returns the value of the property at `frame`. It must not be called for
+// tracks without keyframes.
+func (tr Track[T]) At(frame float64) T {
+	next, _ := slices.BinarySearchFunc(tr, frame, func(k Keyframe[T], f float64) int {
+		switch {
+		case k.Frame < f:
+			return -1
+		case k.Frame > f:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	switch {
+	case next >= len(tr):
+		return tr[len(tr)-1].Value
+	case tr[next].Frame == frame || next == 0:
+		return tr[next].Value
+	}
+
+	start, end := tr[next-1], tr[next]
+	t := (frame - start.Frame) / (end.Frame - start.Frame)
+
+	// The keyframes at the ends are repeated for the spline.
+	before, after := start, end
+	if next-2 >= 0 {
+		before = tr[next-2]
+	}
+	if next+1 < len(tr) {
+		after = tr[next+1]
+	}
+
+	return weighted([4]T{before.Value, start.Value, end.Value, after.Value}, start.Curve.weights(t))
+}
+
+// validate checks that the keyframes are sorted by frame and that no two of them
+// are for the same frame.
+func (tr Track[T]) validate() error {
+	for i := 1; i < len(tr); i++ {
+		if tr[i].Frame <= tr[i-1].Frame {
+			return fmt.Errorf("keyframe %d for frame %g is not after the one for frame %g",
+				i, tr[i].Frame, tr[i-1].Frame)
+		}
+	}
+	return nil
+}
+
+// weighted returns the weighted sum of `values`.
+func weighted[T keyValue](values [4]T, weights [4]float64) T {
+	var res T
+	switch r := any(&res).(type) {
+	case *float64:
+		for i, v := range values {
+			*r += weights[i] * any(v).(float64)
+		}
+	case *[3]float64:
+		for i, v := range values {
+			vec := any(v).([3]float64)
+			for j := range r {
+				r[j] += weights[i] * vec[j]
+			}
+		}
+	}
+	return res
+}
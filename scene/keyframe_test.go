@@ -0,0 +1,71 @@
+package scene
+
+import (
+	"math"
+	"testing"
+)
+
+// TestTrackAt checks the interpolation between keyframes with all curves.
+func TestTrackAt(t *testing.T) {
+	track := func(c Curve) Track[float64] {
+		return Track[float64]{
+			{Frame: 0, Value: 0, Curve: c},
+			{Frame: 10, Value: 10, Curve: c},
+			{Frame: 20, Value: 30, Curve: c},
+		}
+	}
+
+	tests := []struct {
+		curve Curve
+		frame float64
+		want  float64
+	}{
+		{curve: CurveLinear, frame: -5, want: 0},
+		{curve: CurveLinear, frame: 0, want: 0},
+		{curve: CurveLinear, frame: 2.5, want: 2.5},
+		{curve: CurveLinear, frame: 10, want: 10},
+		{curve: CurveLinear, frame: 15, want: 20},
+		{curve: CurveLinear, frame: 25, want: 30},
+		{curve: CurveStep, frame: 9.9, want: 0},
+		{curve: CurveStep, frame: 10, want: 10},
+		{curve: CurveStep, frame: 19, want: 10},
+		{curve: CurveSmooth, frame: 2.5, want: 10 * 0.15625},
+		{curve: CurveSmooth, frame: 5, want: 5},
+		{curve: CurveCatmullRom, frame: 10, want: 10},
+		{curve: CurveCatmullRom, frame: 5, want: 3.75},
+	}
+
+	for _, test := range tests {
+		got := track(test.curve).At(test.frame)
+		if math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%s at frame %g: expected %g but got %g",
+				test.curve, test.frame, test.want, got)
+		}
+	}
+}
+
+// TestTrackVectors checks that vectors are interpolated component-wise.
+func TestTrackVectors(t *testing.T) {
+	track := Track[[3]float64]{
+		{Frame: 1, Value: [3]float64{0, 10, -2}},
+		{Frame: 3, Value: [3]float64{4, 10, 2}},
+	}
+
+	if got, want := track.At(2), [3]float64{2, 10, 0}; got != want {
+		t.Errorf("expected %v but got %v", want, got)
+	}
+}
+
+// TestParseCurve checks that all curves are parsed by their names.
+func TestParseCurve(t *testing.T) {
+	for _, c := range Curves {
+		parsed, err := ParseCurve(c.String())
+		if err != nil || parsed != c {
+			t.Errorf("parsing %s returned %s, %v", c, parsed, err)
+		}
+	}
+
+	if _, err := ParseCurve("bouncy"); err == nil {
+		t.Errorf("expected an error for an unknown curve")
+	}
+}
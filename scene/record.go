@@ -0,0 +1,106 @@
+package scene
+
+import (
+	"time"
+
+	"github.com/ironsmile/raytracer/camera"
+	"github.com/ironsmile/raytracer/geometry"
+)
+
+// DefaultRecordFPS is the number of animation frames for every second of a camera
+// recording when no other is configured.
+const DefaultRecordFPS = 30
+
+// CameraRecorder records the movement of a camera as the camera keyframes of an
+// [Animation]. The time from the start of the recording is converted to frames at
+// a fixed rate so that rendering the frames of the animation and playing them
+// at the same rate shows the camera moving with its recorded speed.
+type CameraRecorder struct {
+	fps  float64
+	anim Animation
+
+	// last is the last recorded state and lastFrame is its frame. When the
+	// camera has not moved since then `still` is set and its keyframe is added
+	// only when the camera moves again, so that the camera stays in place
+	// between the two keyframes.
+	last      camera.State
+	lastFrame float64
+	still     bool
+}
+
+// NewCameraRecorder returns a recorder with `fps` frames for every second. When
+// it is not positive [DefaultRecordFPS] is used.
+func NewCameraRecorder(fps float64) *CameraRecorder {
+	if fps <= 0 {
+		fps = DefaultRecordFPS
+	}
+	return &CameraRecorder{fps: fps}
+}
+
+// Record adds the state of the camera at time `at` from the start of the
+// recording. States for times which are not after the previous one are ignored.
+func (r *CameraRecorder) Record(at time.Duration, state camera.State) {
+	frame := at.Seconds() * r.fps
+	if r.anim.Camera.Position.Animated() && frame <= r.lastFrame {
+		return
+	}
+
+	if r.anim.Camera.Position.Animated() && state == r.last {
+		r.lastFrame = frame
+		r.still = true
+		return
+	}
+
+	r.flush()
+	r.addKeyframe(frame, state)
+}
+
+// Animation returns the recorded animation. Recording may continue after it.
+func (r *CameraRecorder) Animation() *Animation {
+	r.flush()
+
+	// The tracks are copied so that the returned animation does not change
+	// with the following recording.
+	anim := r.anim
+	anim.Camera.Position = append(Track[[3]float64](nil), anim.Camera.Position...)
+	anim.Camera.LookAt = append(Track[[3]float64](nil), anim.Camera.LookAt...)
+	anim.Camera.Up = append(Track[[3]float64](nil), anim.Camera.Up...)
+	anim.Camera.FOV = append(Track[float64](nil), anim.Camera.FOV...)
+	anim.Camera.Size = append(Track[float64](nil), anim.Camera.Size...)
+	return &anim
+}
+
+// Frames returns the number of the last recorded frame.
+func (r *CameraRecorder) Frames() float64 {
+	return r.lastFrame
+}
+
+// flush adds the keyframe for the end of the time in which the camera was still.
+func (r *CameraRecorder) flush() {
+	if r.still {
+		r.addKeyframe(r.lastFrame, r.last)
+	}
+}
+
+func (r *CameraRecorder) addKeyframe(frame float64, state camera.State) {
+	cam := &r.anim.Camera
+	cam.Projection = state.Projection
+	cam.Position = append(cam.Position, Keyframe[[3]float64]{Frame: frame, Value: fromVector(state.Position)})
+	cam.LookAt = append(cam.LookAt, Keyframe[[3]float64]{Frame: frame, Value: fromVector(state.LookAt)})
+	cam.Up = append(cam.Up, Keyframe[[3]float64]{Frame: frame, Value: fromVector(state.Up)})
+	if state.FOV != 0 {
+		cam.FOV = append(cam.FOV, Keyframe[float64]{Frame: frame, Value: state.FOV})
+	}
+	if state.Size != 0 {
+		cam.Size = append(cam.Size, Keyframe[float64]{Frame: frame, Value: state.Size})
+	}
+
+	r.last = state
+	r.lastFrame = frame
+	r.still = false
+}
+
+// fromVector converts a vector to a keyframe value.
+func fromVector(v geometry.Vector) [3]float64 {
+	return [3]float64{v.X, v.Y, v.Z}
+}
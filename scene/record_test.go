@@ -0,0 +1,176 @@
+package scene
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ironsmile/raytracer/camera"
+	"github.com/ironsmile/raytracer/geometry"
+)
+
+// TestCameraRecorder checks that a recorded camera path plays back the recorded
+// states and that the camera stays still while it was not moved.
+func TestCameraRecorder(t *testing.T) {
+	stateAt := func(x float64) camera.State {
+		return camera.State{
+			Projection: camera.ProjectionPinhole,
+			Position:   geometry.NewVector(x, 0, -5),
+			LookAt:     geometry.NewVector(x, 0, 1),
+			Up:         geometry.NewVector(0, 1, 0),
+			FOV:        60,
+		}
+	}
+
+	rec := NewCameraRecorder(10)
+	rec.Record(0, stateAt(0))
+	rec.Record(time.Second, stateAt(1))
+	rec.Record(2*time.Second, stateAt(1))
+	rec.Record(3*time.Second, stateAt(1))
+	rec.Record(3*time.Second, stateAt(5))
+	rec.Record(4*time.Second, stateAt(2))
+
+	filename := filepath.Join(t.TempDir(), "path.json")
+	if err := rec.Animation().Save(filename); err != nil {
+		t.Fatalf("saving the path: %s", err)
+	}
+	anim, err := LoadAnimation(filename)
+	if err != nil {
+		t.Fatalf("loading the path: %s", err)
+	}
+
+	if end := anim.CameraEnd(); end != 40 {
+		t.Errorf("expected the path to end at frame 40 but it ends at %g", end)
+	}
+	if n := len(anim.Camera.Position); n != 4 {
+		t.Errorf("expected 4 keyframes but got %d", n)
+	}
+
+	tests := []struct {
+		frame float64
+		x     float64
+	}{
+		{0, 0},
+		{5, 0.5},
+		{10, 1},
+		{20, 1},
+		{30, 1},
+		{35, 1.5},
+		{40, 2},
+		{100, 2},
+	}
+	for _, test := range tests {
+		got := anim.CameraStateAt(test.frame)
+		if want := stateAt(test.x); !got.Position.Equals(want.Position) ||
+			!got.LookAt.Equals(want.LookAt) || got.FOV != want.FOV {
+			t.Errorf("frame %g: expected %s but got %s", test.frame, want, got)
+		}
+	}
+}
+
+// TestCameraStateAt checks that the properties without keyframes keep the values
+// of the demo scene camera.
+func TestCameraStateAt(t *testing.T) {
+	var anim Animation
+	anim.Camera.Position = Track[[3]float64]{{Frame: 0, Value: [3]float64{1, 2, 3}}}
+
+	got := anim.CameraStateAt(7)
+	want := camera.State{
+		Projection: camera.ProjectionPinhole,
+		Position:   geometry.NewVector(1, 2, 3),
+		LookAt:     defaultCameraLookAt,
+		Up:         defaultCameraUp,
+		FOV:        camera.DefaultPinholeFOV,
+	}
+	if got != want {
+		t.Errorf("expected %s but got %s", want, got)
+	}
+}
+
+// TestCameraRecorderProjections checks that recorded paths keep the projection and
+// the size of the camera and that they are played back with the same camera.
+func TestCameraRecorderProjections(t *testing.T) {
+	tests := []camera.State{
+		{
+			Projection: camera.ProjectionFisheyeEquidistant,
+			Position:   geometry.NewVector(0, 0, -5),
+			LookAt:     geometry.NewVector(0, 0, 1),
+			Up:         geometry.NewVector(0, 1, 0),
+			FOV:        180,
+		},
+		{
+			Projection: camera.ProjectionOrthographic,
+			Position:   geometry.NewVector(0, 0, -5),
+			LookAt:     geometry.NewVector(0, 0, 1),
+			Up:         geometry.NewVector(0, 1, 0),
+			Size:       7,
+		},
+	}
+
+	for _, state := range tests {
+		t.Run(state.Projection.String(), func(t *testing.T) {
+			moved := state
+			moved.Position = geometry.NewVector(1, 0, -5)
+
+			rec := NewCameraRecorder(10)
+			rec.Record(0, state)
+			rec.Record(time.Second, moved)
+
+			filename := filepath.Join(t.TempDir(), "path.json")
+			if err := rec.Animation().Save(filename); err != nil {
+				t.Fatalf("saving the path: %s", err)
+			}
+			anim, err := LoadAnimation(filename)
+			if err != nil {
+				t.Fatalf("loading the path: %s", err)
+			}
+
+			cam, err := anim.CameraAt(10, 64, 48)
+			if err != nil {
+				t.Fatalf("creating the camera: %s", err)
+			}
+			if got := cam.State(); got != moved {
+				t.Errorf("expected %s but got %s", moved, got)
+			}
+		})
+	}
+}
+
+// TestCameraAnimationValidate checks that fields of view and sizes which the
+// projection of the camera cannot use are rejected.
+func TestCameraAnimationValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		cam  CameraAnimation
+	}{
+		{
+			name: "pinhole FOV 180",
+			cam:  CameraAnimation{FOV: Track[float64]{{Value: 180}}},
+		},
+		{
+			name: "fisheye FOV 400",
+			cam: CameraAnimation{
+				Projection: camera.ProjectionFisheyeEquisolid,
+				FOV:        Track[float64]{{Value: 400}},
+			},
+		},
+		{
+			name: "orthographic FOV",
+			cam: CameraAnimation{
+				Projection: camera.ProjectionOrthographic,
+				FOV:        Track[float64]{{Value: 60}},
+			},
+		},
+		{
+			name: "pinhole size",
+			cam:  CameraAnimation{Size: Track[float64]{{Value: 2}}},
+		},
+	}
+
+	for _, test := range tests {
+		anim := &Animation{Camera: test.cam}
+		if _, err := anim.CameraAt(0, 64, 48); err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
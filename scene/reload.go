@@ -0,0 +1,147 @@
+package scene
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/ironsmile/raytracer/primitive"
+	"github.com/ironsmile/raytracer/utils"
+)
+
+// fileShape is a shape loaded from files, such as [shape.Object].
+type fileShape interface {
+	Files() []string
+}
+
+// Files returns the asset files from which the primitives of the scene were
+// loaded, such as .obj models and their .mtl materials. See [Scene.Reload]. The
+// primitives defined in code and models which failed to load have no files.
+func (s *Scene) Files() []string {
+	var files []string
+	for _, prim := range s.Primitives {
+		if fs, ok := prim.Shape().(fileShape); ok {
+			files = append(files, fs.Files()...)
+		}
+	}
+
+	slices.Sort(files)
+	return slices.Compact(files)
+}
+
+// Reload returns a copy of the scene in which the primitives loaded from any of
+// the `changed` files are loaded again. Only models are reloaded, the primitives
+// defined in code stay the same. They keep their names and placement. The
+// accelerator of the copy is built anew while the scene itself is not changed,
+// so it may be rendered in the meantime.
+func (s *Scene) Reload(changed []string) (*Scene, error) {
+	reloaded := make(map[uint64]primitive.Primitive)
+
+	for _, prim := range s.Primitives {
+		fs, ok := prim.Shape().(fileShape)
+		if !ok {
+			continue
+		}
+
+		files := fs.Files()
+		if !slices.ContainsFunc(files, func(f string) bool {
+			return slices.Contains(changed, f)
+		}) {
+			continue
+		}
+
+		obj, err := primitive.NewObject(files[0], s.Logger)
+		if err != nil {
+			return nil, fmt.Errorf("reloading %s: %w", files[0], err)
+		}
+
+		o2w, _ := prim.GetTransforms()
+		obj.SetTransform(o2w)
+		primitive.SetName(obj.GetID(), primitive.GetName(prim.GetID()))
+
+		reloaded[prim.GetID()] = obj
+	}
+
+	replace := func(prims []primitive.Primitive) []primitive.Primitive {
+		res := make([]primitive.Primitive, len(prims))
+		for i, prim := range prims {
+			if obj, ok := reloaded[prim.GetID()]; ok {
+				prim = obj
+			}
+			res[i] = prim
+		}
+		return res
+	}
+
+	scn := &Scene{
+		Primitives: replace(s.Primitives),
+		Lights:     replace(s.Lights),
+		Logger:     s.Logger,
+	}
+	scn.buildAccel()
+
+	utils.Logger(s.Logger).Info("scene reloaded",
+		"files", changed,
+		"primitives", len(reloaded),
+	)
+	return scn, nil
+}
+
+// ReleaseReplaced forgets the global names of the primitives of the scene which
+// were replaced in `reloaded`, the scene returned by [Scene.Reload]. See
+// [Scene.Release]. The names of the primitives shared by both scenes are kept.
+func (s *Scene) ReleaseReplaced(reloaded *Scene) {
+	kept := make(map[uint64]bool, len(reloaded.Primitives)+len(reloaded.Lights))
+	for _, prim := range slices.Concat(reloaded.Primitives, reloaded.Lights) {
+		kept[prim.GetID()] = true
+	}
+
+	for _, prim := range slices.Concat(s.Primitives, s.Lights) {
+		if !kept[prim.GetID()] {
+			primitive.DeleteName(prim.GetID())
+		}
+	}
+}
+
+// FileWatcher finds out when files change by polling their modification times.
+// It is used for reloading the scene when its assets change.
+type FileWatcher struct {
+	modTimes map[string]time.Time
+}
+
+// NewFileWatcher returns a watcher of `files` which are considered unchanged at
+// the moment.
+func NewFileWatcher(files []string) *FileWatcher {
+	w := &FileWatcher{modTimes: make(map[string]time.Time, len(files))}
+	for _, file := range files {
+		w.modTimes[file] = modTime(file)
+	}
+	return w
+}
+
+// Changed returns the sorted files whose modification time changed since the
+// previous call or since the watcher was created. Files which are created or
+// removed are also changed.
+func (w *FileWatcher) Changed() []string {
+	var changed []string
+	for file, old := range w.modTimes {
+		if mt := modTime(file); !mt.Equal(old) {
+			w.modTimes[file] = mt
+			changed = append(changed, file)
+		}
+	}
+
+	slices.Sort(changed)
+	return changed
+}
+
+// modTime returns the modification time of `file` or the zero time when it does
+// not exist.
+func modTime(file string) time.Time {
+	st, err := os.Stat(file)
+	if err != nil {
+		return time.Time{}
+	}
+	return st.ModTime()
+}
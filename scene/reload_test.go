@@ -0,0 +1,122 @@
+package scene
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ironsmile/raytracer/geometry"
+	"github.com/ironsmile/raytracer/primitive"
+	"github.com/ironsmile/raytracer/transform"
+)
+
+const testObj = `mtllib triangle.mtl
+o triangle
+v 0 0 0
+v 1 0 0
+v 0 1 0
+usemtl paint
+f 1 2 3
+`
+
+// TestReload checks that changing the material file of a model is noticed and
+// that the reloaded scene has the new material at the same place.
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	objFile := filepath.Join(dir, "triangle.obj")
+	mtlFile := filepath.Join(dir, "triangle.mtl")
+	writeFile(t, objFile, testObj)
+	writeFile(t, mtlFile, "newmtl paint\nKd 1 0 0\nd 1\n")
+
+	obj, err := primitive.NewObject(objFile, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj.SetTransform(transform.Translate(geometry.NewVector(0, 0, 5)))
+	primitive.SetName(obj.GetID(), "painted triangle")
+
+	sphere := primitive.NewSphere(0.1)
+	sphere.SetTransform(transform.Translate(geometry.NewVector(5, 5, 5)))
+	primitive.SetName(sphere.GetID(), "unchanged sphere")
+
+	scn := &Scene{Primitives: []primitive.Primitive{obj, sphere}}
+	scn.buildAccel()
+
+	if files := scn.Files(); !slices.Equal(files, []string{mtlFile, objFile}) {
+		t.Fatalf("unexpected scene files %v", files)
+	}
+
+	watcher := NewFileWatcher(scn.Files())
+	if changed := watcher.Changed(); len(changed) != 0 {
+		t.Errorf("unchanged files %v are reported as changed", changed)
+	}
+
+	writeFile(t, mtlFile, "newmtl paint\nKd 0 1 0\nd 1\n")
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(mtlFile, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	changed := watcher.Changed()
+	if !slices.Equal(changed, []string{mtlFile}) {
+		t.Fatalf("expected only %s to change but got %v", mtlFile, changed)
+	}
+	if again := watcher.Changed(); len(again) != 0 {
+		t.Errorf("files %v are reported as changed twice", again)
+	}
+
+	reloaded, err := scn.Reload(changed)
+	if err != nil {
+		t.Fatalf("reloading: %s", err)
+	}
+
+	ray := geometry.NewRay(geometry.NewVector(0.2, 0.2, 0), geometry.NewVector(0, 0, 1))
+	for _, test := range []struct {
+		scn  *Scene
+		want *geometry.Color
+	}{
+		{scn, geometry.NewColor(1, 0, 0)},
+		{reloaded, geometry.NewColor(0, 1, 0)},
+	} {
+		var in primitive.Intersection
+		if !test.scn.Intersect(ray, &in) {
+			t.Fatalf("the triangle is not hit")
+		}
+		if in.DfGeometry.Distance != 5 {
+			t.Errorf("the triangle is at distance %g instead of 5", in.DfGeometry.Distance)
+		}
+
+		color := in.DfGeometry.Shape.MaterialAt(in.DfGeometry.Point).Color
+		if *color != *test.want {
+			t.Errorf("expected colour %v but got %v", test.want, color)
+		}
+	}
+
+	if reloaded.Primitives[1] != sphere {
+		t.Errorf("the sphere was replaced although it was not changed")
+	}
+	if name := primitive.GetName(reloaded.Primitives[0].GetID()); name != "painted triangle" {
+		t.Errorf("the reloaded triangle is named %q", name)
+	}
+
+	scn.ReleaseReplaced(reloaded)
+	if name := primitive.GetName(obj.GetID()); name == "painted triangle" {
+		t.Errorf("the name of the replaced triangle was kept")
+	}
+	if name := primitive.GetName(reloaded.Primitives[0].GetID()); name != "painted triangle" {
+		t.Errorf("the reloaded triangle is named %q after releasing the old scene", name)
+	}
+	if name := primitive.GetName(sphere.GetID()); name != "unchanged sphere" {
+		t.Errorf("the unchanged sphere is named %q after releasing the old scene", name)
+	}
+}
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,12 +1,15 @@
 package scene
 
 import (
-	"fmt"
+	"log/slog"
+	"time"
 
 	"github.com/ironsmile/raytracer/accel"
 	"github.com/ironsmile/raytracer/geometry"
 	"github.com/ironsmile/raytracer/primitive"
 	"github.com/ironsmile/raytracer/scene/example"
+	"github.com/ironsmile/raytracer/transform"
+	"github.com/ironsmile/raytracer/utils"
 )
 
 // Scene is a type which is responsible for loading and managing a scene for rendering.
@@ -14,6 +17,17 @@ type Scene struct {
 	Primitives []primitive.Primitive
 	Lights     []primitive.Primitive
 	accel      primitive.Primitive
+
+	// Logger receives the diagnostics while loading the scene. Nothing is logged
+	// when it is nil.
+	Logger *slog.Logger
+
+	// buildTime is how long building the accelerator took the last time.
+	buildTime time.Duration
+
+	// initialTransforms holds the object to world transformations of all
+	// primitives before they were moved by an animation. See [Scene.Animate].
+	initialTransforms map[uint64]*transform.Transform
 }
 
 // GetNrLights returns the number of lights in this scene
@@ -48,6 +62,52 @@ func (s *Scene) IntersectP(ray geometry.Ray) bool {
 	return s.accel.IntersectP(ray)
 }
 
+// IntersectPacket finds the closest intersection for every ray in the packet. See
+// [accel.RayPacket] for where the results are stored.
+func (s *Scene) IntersectPacket(p *accel.RayPacket) {
+	accel.IntersectPacket(s.accel, p)
+}
+
+// IntersectPPacket tells whether every ray in the packet intersects any of the
+// primitives in the scene. See [accel.RayPacket] for where the results are stored.
+func (s *Scene) IntersectPPacket(p *accel.RayPacket) {
+	accel.IntersectPPacket(s.accel, p)
+}
+
+// IntersectStats is the same as [Scene.Intersect] but records the work done by the
+// scene accelerator in `st`.
+func (s *Scene) IntersectStats(
+	ray geometry.Ray,
+	in *primitive.Intersection,
+	st *accel.TraversalStats,
+) bool {
+	if sa, ok := s.accel.(accel.StatsIntersecter); ok {
+		return sa.IntersectStats(ray, in, st)
+	}
+	st.Rays++
+	return s.accel.Intersect(ray, in)
+}
+
+// IntersectPStats is the same as [Scene.IntersectP] but records the work done by
+// the scene accelerator in `st`.
+func (s *Scene) IntersectPStats(ray geometry.Ray, st *accel.TraversalStats) bool {
+	if sa, ok := s.accel.(accel.StatsIntersecter); ok {
+		return sa.IntersectPStats(ray, st)
+	}
+	st.Rays++
+	return s.accel.IntersectP(ray)
+}
+
+// LeafOccupancy returns the histogram of primitives per leaf node of the scene
+// accelerator. See [accel.StatsIntersecter]. It returns nil when the accelerator
+// does not support statistics.
+func (s *Scene) LeafOccupancy() []uint64 {
+	if sa, ok := s.accel.(accel.StatsIntersecter); ok {
+		return sa.LeafOccupancy()
+	}
+	return nil
+}
+
 // IntersectBBoxEdge tells whether a ray intersects a bounding box edge of any of the
 // primitives in the scene.
 func (s *Scene) IntersectBBoxEdge(ray geometry.Ray) bool {
@@ -68,24 +128,58 @@ func (s *Scene) InitScene(name string) {
 
 	switch name {
 	case "car":
-		prims, lights = example.GetCarScene()
+		prims, lights = example.GetCarScene(s.Logger)
 	case "empty":
 		prims, lights = example.GetEmptyScene()
 	case "gopher":
-		prims, lights = example.GetGopherScene()
+		prims, lights = example.GetGopherScene(s.Logger)
 	default:
-		prims, lights = example.GetTeapotScene()
+		prims, lights = example.GetTeapotScene(s.Logger)
 	}
 
 	s.Lights = lights
 	s.Primitives = prims
 
 	if err := s.initDebugRays(); err != nil {
-		fmt.Printf("error adding debug rays to the scene: %s\n", err)
+		utils.Logger(s.Logger).Error("cannot add debug rays to the scene", "error", err)
+	}
+
+	s.buildAccel()
+}
+
+// Release forgets the global names of the primitives in the scene. See
+// [primitive.SetName]. Call it when a scene is not needed anymore by programs
+// which load many scenes. The scene may still be rendered but picking its
+// primitives will not find their names.
+func (s *Scene) Release() {
+	for _, prim := range s.Primitives {
+		primitive.DeleteName(prim.GetID())
+	}
+	for _, light := range s.Lights {
+		primitive.DeleteName(light.GetID())
 	}
+}
 
-	s.accel = accel.NewBVH(s.Primitives, 1)
+// buildAccel builds the accelerator for the current primitives of the scene.
+func (s *Scene) buildAccel() {
+	start := time.Now()
+	bvh := accel.NewBVH(s.Primitives, 1)
+	s.accel = bvh
 	// s.accel = accel.NewGrid(s.Primitives)
+	s.buildTime = time.Since(start)
+
+	prims, nodes := bvh.Size()
+	utils.Logger(s.Logger).Debug("BVH built",
+		"primitives", prims,
+		"nodes", nodes,
+		"duration", s.buildTime,
+	)
+}
+
+// BuildTime returns how long building the accelerator of the scene took the last
+// time it was built.
+func (s *Scene) BuildTime() time.Duration {
+	return s.buildTime
 }
 
 // NewScene returns a new demo scene
@@ -0,0 +1,356 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ironsmile/raytracer/engine"
+	"github.com/ironsmile/raytracer/imagefilm"
+	"github.com/ironsmile/raytracer/sampler"
+	"github.com/ironsmile/raytracer/scene"
+)
+
+// Limits of the job requests.
+const (
+	maxImageSide = 8192
+	maxTargetSPP = 1 << 16
+)
+
+// Job states.
+const (
+	StateQueued    = "queued"
+	StateRendering = "rendering"
+	StateDone      = "done"
+	StateFailed    = "failed"
+	StateCanceled  = "canceled"
+)
+
+// JobRequest describes an image to be rendered.
+type JobRequest struct {
+	// Scene is the name of one of the demo scenes. See [scene.PossibleScenes].
+	Scene string `json:"scene"`
+
+	// Animation is the name of an animation file in the animations directory of
+	// the server. It places the objects and the camera of the scene. See
+	// [scene.Animation]. Frame is the rendered frame of the animation.
+	Animation string  `json:"animation,omitempty"`
+	Frame     float64 `json:"frame,omitempty"`
+
+	// Camera overrides the default camera and the camera of the animation.
+	Camera Camera `json:"camera"`
+
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// SPP is the number of samples per pixel after which rendering stops.
+	SPP int `json:"spp"`
+
+	// Sampler is the name of the sampler. See [sampler.Kinds]. It is
+	// [sampler.DefaultKind] when empty.
+	Sampler string `json:"sampler,omitempty"`
+
+	// Seed selects the random samples. The same request with the same seed always
+	// renders the same image.
+	Seed uint64 `json:"seed,omitempty"`
+
+	// Format is the format of the finished image, "png" or "jpeg". It is "png"
+	// when empty.
+	Format string `json:"format,omitempty"`
+}
+
+// Camera positions the camera. Fields which are not set keep their defaults.
+type Camera struct {
+	Position *[3]float64 `json:"position,omitempty"`
+	LookAt   *[3]float64 `json:"lookAt,omitempty"`
+	Up       *[3]float64 `json:"up,omitempty"`
+
+	// FOV is the field of view in degrees across the shorter side of the image.
+	FOV float64 `json:"fov,omitempty"`
+}
+
+// Progress describes how far the rendering of a job is.
+type Progress struct {
+	Passes          int     `json:"passes"`
+	SamplesPerPixel int     `json:"samplesPerPixel"`
+	TargetSPP       int     `json:"targetSPP"`
+	Noise           float64 `json:"noise"`
+	ElapsedSeconds  float64 `json:"elapsedSeconds"`
+}
+
+// JobStatus is the state of a job as returned by the API.
+type JobStatus struct {
+	ID       string     `json:"id"`
+	State    string     `json:"state"`
+	Request  JobRequest `json:"request"`
+	Progress Progress   `json:"progress"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// job is a single render job. The fields after `lock` are guarded by it.
+type job struct {
+	id  string
+	req JobRequest
+
+	// animationFile is the path of the animation of the request on the server.
+	animationFile string
+
+	// logger receives the diagnostics of rendering the job.
+	logger *slog.Logger
+
+	// cancel is closed when the job is canceled.
+	cancel     chan struct{}
+	cancelOnce sync.Once
+
+	lock     sync.Mutex
+	state    string
+	progress Progress
+	err      error
+
+	// finished is when the job was done, failed or was canceled.
+	finished time.Time
+
+	// preview is the image after the last finished pass.
+	preview *image.NRGBA
+
+	// result is the encoded finished image.
+	result []byte
+}
+
+func newJob(id string, req JobRequest, logger *slog.Logger) *job {
+	return &job{
+		id:     id,
+		req:    req,
+		logger: logger.With("job", id),
+		state:  StateQueued,
+		cancel: make(chan struct{}),
+		progress: Progress{
+			TargetSPP: req.SPP,
+		},
+	}
+}
+
+// validate checks the request and fills in the defaults.
+func (r *JobRequest) validate() error {
+	if r.Scene == "" {
+		r.Scene = "teapot"
+	}
+	if !slices.Contains(scene.PossibleScenes, r.Scene) {
+		return fmt.Errorf("unknown scene %q", r.Scene)
+	}
+
+	if r.Width <= 0 || r.Height <= 0 || r.Width > maxImageSide || r.Height > maxImageSide {
+		return fmt.Errorf("width and height must be between 1 and %d", maxImageSide)
+	}
+	if r.SPP <= 0 || r.SPP > maxTargetSPP {
+		return fmt.Errorf("spp must be between 1 and %d", maxTargetSPP)
+	}
+	if r.Camera.FOV < 0 || r.Camera.FOV >= 180 {
+		return fmt.Errorf("fov must be between 0 and 180 degrees")
+	}
+
+	if r.Sampler != "" {
+		if _, err := sampler.ParseKind(r.Sampler); err != nil {
+			return err
+		}
+	}
+
+	switch r.Format {
+	case "":
+		r.Format = "png"
+	case "png", "jpeg":
+	default:
+		return fmt.Errorf("unknown format %q, possible values: png, jpeg", r.Format)
+	}
+
+	return nil
+}
+
+// status returns the current status of the job.
+func (j *job) status() JobStatus {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	st := JobStatus{
+		ID:       j.id,
+		State:    j.state,
+		Request:  j.req,
+		Progress: j.progress,
+	}
+	if j.err != nil {
+		st.Error = j.err.Error()
+	}
+	return st
+}
+
+// finishedAt returns when the job was finished. It returns false while the job
+// may still change.
+func (j *job) finishedAt() (time.Time, bool) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	done := j.state == StateDone || j.state == StateFailed || j.state == StateCanceled
+	return j.finished, done
+}
+
+// stop cancels the job. A queued job is never rendered and a job which is being
+// rendered stops after the sub samplers in flight.
+func (j *job) stop() {
+	j.cancelOnce.Do(func() {
+		close(j.cancel)
+	})
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if j.state == StateQueued {
+		j.state = StateCanceled
+		j.finished = time.Now()
+	}
+}
+
+// run renders the job unless it was canceled while in the queue.
+func (j *job) run() {
+	j.lock.Lock()
+	if j.state != StateQueued {
+		j.lock.Unlock()
+		return
+	}
+	j.state = StateRendering
+	j.lock.Unlock()
+
+	result, err := j.render()
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	j.finished = time.Now()
+
+	select {
+	case <-j.cancel:
+		j.state = StateCanceled
+		j.logger.Info("job canceled")
+		return
+	default:
+	}
+
+	if err != nil {
+		j.state = StateFailed
+		j.err = err
+		j.logger.Error("job failed", "error", err)
+		return
+	}
+
+	j.state = StateDone
+	j.result = result
+	j.logger.Info("job done", "samplesPerPixel", j.progress.SamplesPerPixel)
+}
+
+// render renders the requested image and returns it encoded in the requested
+// format.
+func (j *job) render() ([]byte, error) {
+	req := j.req
+
+	anim := &scene.Animation{}
+	if j.animationFile != "" {
+		var err error
+		anim, err = scene.LoadAnimation(j.animationFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Camera.Position != nil {
+		anim.Camera.Position = scene.Track[[3]float64]{{Value: *req.Camera.Position}}
+	}
+	if req.Camera.LookAt != nil {
+		anim.Camera.LookAt = scene.Track[[3]float64]{{Value: *req.Camera.LookAt}}
+	}
+	if req.Camera.Up != nil {
+		anim.Camera.Up = scene.Track[[3]float64]{{Value: *req.Camera.Up}}
+	}
+	if req.Camera.FOV > 0 {
+		anim.Camera.FOV = scene.Track[float64]{{Value: req.Camera.FOV}}
+	}
+
+	cfg := sampler.Config{Seed: req.Seed}
+	if req.Sampler != "" {
+		cfg.Kind, _ = sampler.ParseKind(req.Sampler)
+	}
+
+	output := imagefilm.NewImage("")
+	if err := output.Init(req.Width, req.Height); err != nil {
+		return nil, err
+	}
+
+	smpl := sampler.NewSimple(req.Width, req.Height, output, cfg)
+	defer smpl.Stop()
+
+	tracer := engine.New(smpl)
+	tracer.SetLogger(j.logger)
+	tracer.Scene.InitScene(req.Scene)
+	defer tracer.Scene.Release()
+	if err := tracer.Scene.Animate(anim, req.Frame); err != nil {
+		return nil, err
+	}
+	cam, err := anim.CameraAt(req.Frame, float64(req.Width), float64(req.Height))
+	if err != nil {
+		return nil, err
+	}
+	tracer.SetTarget(output, cam)
+
+	_, err = tracer.RenderProgressive(engine.Progressive{
+		TargetSPP: req.SPP,
+		Interrupt: j.cancel,
+		OnPass: func(res engine.ProgressiveResult) {
+			preview := image.NewNRGBA(output.Image().Rect)
+			copy(preview.Pix, output.Image().Pix)
+
+			j.lock.Lock()
+			defer j.lock.Unlock()
+
+			j.preview = preview
+			j.progress.Passes = res.Passes
+			j.progress.SamplesPerPixel = res.SamplesPerPixel
+			j.progress.Noise = res.Noise
+			j.progress.ElapsedSeconds = res.Elapsed.Seconds()
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return encodeImage(output.Image(), req.Format)
+}
+
+// encodeImage returns `img` encoded in `format`.
+func encodeImage(img image.Image, format string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	var err error
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95})
+	default:
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// contentType returns the MIME type of images in `format`.
+func contentType(format string) string {
+	if format == "jpeg" {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
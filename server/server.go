@@ -0,0 +1,350 @@
+// Package server implements a render server. It accepts render jobs over a JSON
+// HTTP API, renders them in the background and serves the finished images.
+//
+// The API is:
+//
+//	POST   /jobs              submits a JobRequest and returns its JobStatus
+//	GET    /jobs              returns the JobStatus of all jobs
+//	GET    /jobs/{id}         returns the JobStatus of a job
+//	GET    /jobs/{id}/preview returns the image after the last rendered pass as PNG
+//	GET    /jobs/{id}/image   returns the finished image in the requested format
+//	DELETE /jobs/{id}         cancels a job or forgets a finished one
+//
+// Finished jobs keep their images in memory until they are deleted. The server
+// forgets them on its own after [Config.FinishedTTL] or when there are more than
+// [Config.MaxFinished] of them.
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ironsmile/raytracer/utils"
+)
+
+// Config configures a [Server].
+type Config struct {
+	// Workers is the number of jobs rendered at the same time. Every one of them
+	// uses all CPUs. It is one when zero.
+	Workers int
+
+	// QueueSize is the number of jobs which may wait for rendering. Submitting
+	// more jobs fails until some of the waiting ones are started. It is 64 when
+	// zero.
+	QueueSize int
+
+	// AnimationsDir is the directory with the animation files which jobs may use.
+	// Jobs cannot use animations when it is empty.
+	AnimationsDir string
+
+	// FinishedTTL is how long finished jobs are kept after they are done, failed
+	// or canceled. It is one hour when zero.
+	FinishedTTL time.Duration
+
+	// MaxFinished is the number of finished jobs which are kept. The ones which
+	// finished first are forgotten when there are more. It is 100 when zero.
+	MaxFinished int
+
+	// Logger receives the diagnostics of the server and its renderings. Nothing is
+	// logged when it is nil.
+	Logger *slog.Logger
+}
+
+// Server is an http.Handler which serves the render job API. See the package
+// documentation for the API.
+type Server struct {
+	cfg Config
+	mux *http.ServeMux
+
+	queue chan *job
+	wg    sync.WaitGroup
+
+	// lock guards `jobs`, `order`, `lastID` and `closed`.
+	lock   sync.Mutex
+	jobs   map[string]*job
+	order  []string
+	lastID uint64
+	closed bool
+}
+
+// New returns a server which starts rendering the submitted jobs right away.
+// [Server.Close] stops it.
+func New(cfg Config) *Server {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 64
+	}
+	if cfg.FinishedTTL <= 0 {
+		cfg.FinishedTTL = time.Hour
+	}
+	if cfg.MaxFinished <= 0 {
+		cfg.MaxFinished = 100
+	}
+
+	s := &Server{
+		cfg:   cfg,
+		mux:   http.NewServeMux(),
+		queue: make(chan *job, cfg.QueueSize),
+		jobs:  make(map[string]*job),
+	}
+
+	s.mux.HandleFunc("POST /jobs", s.submit)
+	s.mux.HandleFunc("GET /jobs", s.list)
+	s.mux.HandleFunc("GET /jobs/{id}", s.get)
+	s.mux.HandleFunc("GET /jobs/{id}/preview", s.preview)
+	s.mux.HandleFunc("GET /jobs/{id}/image", s.image)
+	s.mux.HandleFunc("DELETE /jobs/{id}", s.delete)
+
+	for range cfg.Workers {
+		s.wg.Add(1)
+		go s.work()
+	}
+
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.evict()
+	s.mux.ServeHTTP(w, r)
+}
+
+// evict forgets the finished jobs which are older than [Config.FinishedTTL] and
+// the oldest ones above [Config.MaxFinished].
+func (s *Server) evict() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	type finishedJob struct {
+		id string
+		at time.Time
+	}
+
+	var finished []finishedJob
+	for _, id := range s.order {
+		if at, ok := s.jobs[id].finishedAt(); ok {
+			finished = append(finished, finishedJob{id, at})
+		}
+	}
+	slices.SortStableFunc(finished, func(a, b finishedJob) int {
+		return a.at.Compare(b.at)
+	})
+
+	expired := time.Now().Add(-s.cfg.FinishedTTL)
+	forget := make(map[string]bool)
+	for i, f := range finished {
+		if f.at.Before(expired) || len(finished)-i > s.cfg.MaxFinished {
+			forget[f.id] = true
+			delete(s.jobs, f.id)
+		}
+	}
+	if len(forget) == 0 {
+		return
+	}
+
+	s.order = slices.DeleteFunc(s.order, func(id string) bool {
+		return forget[id]
+	})
+	s.log().Debug("forgot finished jobs", "jobs", len(forget))
+}
+
+// Close cancels all jobs and waits for the ones being rendered to stop. No jobs
+// are accepted after it.
+func (s *Server) Close() {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return
+	}
+	s.closed = true
+	for _, j := range s.jobs {
+		j.stop()
+	}
+	close(s.queue)
+	s.lock.Unlock()
+
+	s.wg.Wait()
+}
+
+// work renders jobs from the queue until it is closed.
+func (s *Server) work() {
+	defer s.wg.Done()
+
+	for j := range s.queue {
+		j.run()
+	}
+}
+
+func (s *Server) submit(w http.ResponseWriter, r *http.Request) {
+	var req JobRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("decoding job: %w", err))
+		return
+	}
+
+	if err := req.validate(); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var animation string
+	if req.Animation != "" {
+		if s.cfg.AnimationsDir == "" || !filepath.IsLocal(req.Animation) {
+			s.writeError(w, http.StatusBadRequest,
+				fmt.Errorf("animation %q is not available", req.Animation))
+			return
+		}
+		animation = filepath.Join(s.cfg.AnimationsDir, req.Animation)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.closed {
+		s.writeError(w, http.StatusServiceUnavailable, fmt.Errorf("server is shutting down"))
+		return
+	}
+
+	j := newJob(strconv.FormatUint(s.lastID+1, 10), req, s.log())
+	j.animationFile = animation
+
+	select {
+	case s.queue <- j:
+	default:
+		s.writeError(w, http.StatusServiceUnavailable, fmt.Errorf("job queue is full"))
+		return
+	}
+
+	s.lastID++
+	s.jobs[j.id] = j
+	s.order = append(s.order, j.id)
+
+	w.Header().Set("Location", "/jobs/"+j.id)
+	s.writeJSON(w, http.StatusAccepted, j.status())
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	statuses := make([]JobStatus, 0, len(s.order))
+	for _, id := range s.order {
+		statuses = append(statuses, s.jobs[id].status())
+	}
+	s.lock.Unlock()
+
+	s.writeJSON(w, http.StatusOK, statuses)
+}
+
+func (s *Server) get(w http.ResponseWriter, r *http.Request) {
+	j, ok := s.job(w, r)
+	if !ok {
+		return
+	}
+	s.writeJSON(w, http.StatusOK, j.status())
+}
+
+func (s *Server) preview(w http.ResponseWriter, r *http.Request) {
+	j, ok := s.job(w, r)
+	if !ok {
+		return
+	}
+
+	j.lock.Lock()
+	preview := j.preview
+	j.lock.Unlock()
+
+	if preview == nil {
+		s.writeError(w, http.StatusConflict, fmt.Errorf("job %s has no finished passes yet", j.id))
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	if err := png.Encode(w, preview); err != nil {
+		s.log().Error("cannot write preview", "job", j.id, "error", err)
+	}
+}
+
+func (s *Server) image(w http.ResponseWriter, r *http.Request) {
+	j, ok := s.job(w, r)
+	if !ok {
+		return
+	}
+
+	j.lock.Lock()
+	result, state := j.result, j.state
+	j.lock.Unlock()
+
+	if state != StateDone {
+		s.writeError(w, http.StatusConflict, fmt.Errorf("job %s is %s", j.id, state))
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType(j.req.Format))
+	w.Header().Set("Content-Length", strconv.Itoa(len(result)))
+	w.Write(result)
+}
+
+func (s *Server) delete(w http.ResponseWriter, r *http.Request) {
+	j, ok := s.job(w, r)
+	if !ok {
+		return
+	}
+
+	if _, done := j.finishedAt(); !done {
+		j.stop()
+		s.writeJSON(w, http.StatusOK, j.status())
+		return
+	}
+
+	s.lock.Lock()
+	delete(s.jobs, j.id)
+	s.order = slices.DeleteFunc(s.order, func(id string) bool {
+		return id == j.id
+	})
+	s.lock.Unlock()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// job returns the job from the request path. It writes a not found response when
+// there is no such job.
+func (s *Server) job(w http.ResponseWriter, r *http.Request) (*job, bool) {
+	id := r.PathValue("id")
+
+	s.lock.Lock()
+	j, ok := s.jobs[id]
+	s.lock.Unlock()
+
+	if !ok {
+		s.writeError(w, http.StatusNotFound, fmt.Errorf("job %q not found", id))
+	}
+	return j, ok
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.log().Error("cannot write response", "error", err)
+	}
+}
+
+func (s *Server) writeError(w http.ResponseWriter, status int, err error) {
+	s.writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// log returns the logger of the server. It is never nil.
+func (s *Server) log() *slog.Logger {
+	return utils.Logger(s.cfg.Logger)
+}
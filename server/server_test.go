@@ -0,0 +1,213 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestServerRendersJob checks the whole life of a job: submitting it, following
+// its progress, getting the image and forgetting it.
+func TestServerRendersJob(t *testing.T) {
+	srv := New(Config{})
+	defer srv.Close()
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	st := submit(t, ts, `{"scene": "teapot", "width": 32, "height": 24, "spp": 2,
+		"camera": {"position": [0, 1, -6], "fov": 70}}`, http.StatusAccepted)
+	if st.State != StateQueued && st.State != StateRendering {
+		t.Errorf("new job is %s", st.State)
+	}
+
+	st = waitFor(t, ts, st.ID, StateDone)
+	if st.Progress.SamplesPerPixel < 2 || st.Progress.Passes == 0 {
+		t.Errorf("unexpected progress of a finished job: %+v", st.Progress)
+	}
+
+	for _, path := range []string{"image", "preview"} {
+		resp := request(t, ts, http.MethodGet, "/jobs/"+st.ID+"/"+path, "")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("getting %s: status %d", path, resp.StatusCode)
+		}
+		img, err := png.Decode(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("decoding %s: %s", path, err)
+		}
+		if b := img.Bounds(); b.Dx() != 32 || b.Dy() != 24 {
+			t.Errorf("%s is %dx%d instead of 32x24", path, b.Dx(), b.Dy())
+		}
+	}
+
+	var list []JobStatus
+	resp := request(t, ts, http.MethodGet, "/jobs", "")
+	decode(t, resp, &list)
+	if len(list) != 1 || list[0].ID != st.ID {
+		t.Errorf("expected a list with job %s but got %+v", st.ID, list)
+	}
+
+	resp = request(t, ts, http.MethodDelete, "/jobs/"+st.ID, "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("deleting a finished job: status %d", resp.StatusCode)
+	}
+
+	resp = request(t, ts, http.MethodGet, "/jobs/"+st.ID, "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("getting a deleted job: status %d", resp.StatusCode)
+	}
+}
+
+// TestServerCancel checks canceling of queued and running jobs and that the queue
+// is bounded.
+func TestServerCancel(t *testing.T) {
+	srv := New(Config{Workers: 1, QueueSize: 1})
+	defer srv.Close()
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	long := `{"width": 256, "height": 256, "spp": 65536}`
+
+	running := submit(t, ts, long, http.StatusAccepted)
+	waitFor(t, ts, running.ID, StateRendering)
+
+	queued := submit(t, ts, long, http.StatusAccepted)
+	submit(t, ts, long, http.StatusServiceUnavailable)
+
+	resp := request(t, ts, http.MethodGet, "/jobs/"+running.ID+"/image", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("getting the image of an unfinished job: status %d", resp.StatusCode)
+	}
+
+	var st JobStatus
+	decode(t, request(t, ts, http.MethodDelete, "/jobs/"+queued.ID, ""), &st)
+	if st.State != StateCanceled {
+		t.Errorf("queued job is %s after canceling it", st.State)
+	}
+
+	resp = request(t, ts, http.MethodDelete, "/jobs/"+running.ID, "")
+	resp.Body.Close()
+	waitFor(t, ts, running.ID, StateCanceled)
+}
+
+// TestServerForgetsFinishedJobs checks that only the last [Config.MaxFinished]
+// finished jobs are kept.
+func TestServerForgetsFinishedJobs(t *testing.T) {
+	srv := New(Config{Workers: 1, MaxFinished: 1})
+	defer srv.Close()
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	job := `{"scene": "empty", "width": 8, "height": 8, "spp": 1}`
+
+	first := submit(t, ts, job, http.StatusAccepted)
+	waitFor(t, ts, first.ID, StateDone)
+
+	second := submit(t, ts, job, http.StatusAccepted)
+	waitFor(t, ts, second.ID, StateDone)
+
+	resp := request(t, ts, http.MethodGet, "/jobs/"+first.ID, "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("getting the first of two finished jobs: status %d", resp.StatusCode)
+	}
+
+	var list []JobStatus
+	decode(t, request(t, ts, http.MethodGet, "/jobs", ""), &list)
+	if len(list) != 1 || list[0].ID != second.ID {
+		t.Errorf("expected a list with job %s but got %+v", second.ID, list)
+	}
+}
+
+// TestServerRejectsBadJobs checks the validation of job requests.
+func TestServerRejectsBadJobs(t *testing.T) {
+	srv := New(Config{})
+	defer srv.Close()
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	for _, body := range []string{
+		`{"width": 0, "height": 10, "spp": 1}`,
+		`{"width": 10, "height": 10, "spp": 0}`,
+		`{"scene": "nope", "width": 10, "height": 10, "spp": 1}`,
+		`{"width": 10, "height": 10, "spp": 1, "format": "gif"}`,
+		`{"width": 10, "height": 10, "spp": 1, "sampler": "nope"}`,
+		`{"width": 10, "height": 10, "spp": 1, "animation": "../secret.json"}`,
+		`{"width": 10, "height": 10, "spp": 1, "unknown": true}`,
+		`not json`,
+	} {
+		submit(t, ts, body, http.StatusBadRequest)
+	}
+
+	resp := request(t, ts, http.MethodGet, "/jobs/42", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("getting an unknown job: status %d", resp.StatusCode)
+	}
+}
+
+func submit(t *testing.T, ts *httptest.Server, body string, status int) JobStatus {
+	t.Helper()
+
+	resp := request(t, ts, http.MethodPost, "/jobs", body)
+	if resp.StatusCode != status {
+		resp.Body.Close()
+		t.Fatalf("submitting %s: expected status %d but got %d", body, status, resp.StatusCode)
+	}
+
+	var st JobStatus
+	decode(t, resp, &st)
+	return st
+}
+
+// waitFor polls the job with `id` until it is in `state`.
+func waitFor(t *testing.T, ts *httptest.Server, id, state string) JobStatus {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Minute)
+	for time.Now().Before(deadline) {
+		var st JobStatus
+		decode(t, request(t, ts, http.MethodGet, "/jobs/"+id, ""), &st)
+		if st.State == state {
+			return st
+		}
+		if st.State == StateFailed {
+			t.Fatalf("job %s failed: %s", id, st.Error)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("job %s did not become %s in time", id, state)
+	return JobStatus{}
+}
+
+func request(t *testing.T, ts *httptest.Server, method, path, body string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(method, ts.URL+path, bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func decode(t *testing.T, resp *http.Response, v any) {
+	t.Helper()
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatal(fmt.Errorf("decoding response: %w", err))
+	}
+}
@@ -1,11 +1,11 @@
 package shape
 
 import (
-	"fmt"
 	"math"
 
 	"github.com/ironsmile/raytracer/bbox"
 	"github.com/ironsmile/raytracer/geometry"
+	"github.com/ironsmile/raytracer/utils"
 )
 
 // Cylinder represents a finite cylinder with a particular radius.
@@ -129,6 +129,30 @@ func (c *Cylinder) Intersect(ray geometry.Ray, dg *DifferentialGeometry) bool {
 	dg.Shape = c
 	dg.Distance = tDist
 
+	// The hit distance from the quadratic could be quite imprecise. So the hit point
+	// is reprojected on the cylinder surface which bounds its error much better.
+	hit := ray.At(tDist)
+	axisAt := c.endcapBottom.Plus(Ca.MultiplyScalar(hit.Minus(c.endcapBottom).Dot(Ca)))
+	radial := hit.Minus(axisAt)
+	dg.Point = axisAt.Plus(radial.MultiplyScalar(c.radius / radial.Length()))
+	dg.PointError = dg.Point.Abs().Plus(c.endcapBottom.Abs()).Plus(c.endcapTop.Abs()).
+		MultiplyScalar(geometry.Gamma(12))
+	dg.Normal = radial.Normalize()
+
+	// U goes around the axis starting from an arbitrary direction perpendicular to
+	// it. V goes along the axis from the bottom to the top.
+	side := Ca.Cross(geometry.NewVector(1, 0, 0))
+	if side.Length() < 0.1 {
+		side = Ca.Cross(geometry.NewVector(0, 1, 0))
+	}
+	side = side.Normalize()
+	phi := math.Atan2(radial.Dot(Ca.Cross(side)), radial.Dot(side))
+	if phi < 0 {
+		phi += 2 * math.Pi
+	}
+	dg.U = phi / (2 * math.Pi)
+	dg.V = utils.Clamp(hit.Minus(c.endcapBottom).Dot(Ca)/Ch, 0, 1)
+
 	return true
 }
 
@@ -138,7 +162,8 @@ func (c *Cylinder) NormalAt(at geometry.Vector) geometry.Vector {
 	vat := at.Minus(c.endcapBottom)
 	th := math.Acos(dir.Dot(vat) / (dir.Length() * vat.Length()))
 	if math.IsNaN(th) {
-		fmt.Printf("NaN acos in normal calculations for cylinder at %#v\n", at)
+		// The point is at the centre of the bottom endcap or rounding put the
+		// cosine out of range. Shading must go on, so there is nothing to report.
 		return at.Minus(c.endcapBottom)
 	}
 
@@ -1,5 +1,7 @@
 package shape
 
+import "github.com/ironsmile/raytracer/geometry"
+
 // DifferentialGeometry is a self-contained representation for the geometry
 // of a particular point on a surface (typically the point of a ray intersection).
 // This abstraction needs to hide the particular type of geometric shape the point lies
@@ -12,4 +14,21 @@ type DifferentialGeometry struct {
 
 	// WHich shape was hit with this intersection
 	Shape Shape
+
+	// Point is the intersection point in the object space of the shape.
+	Point geometry.Vector
+
+	// PointError is a conservative bound on the absolute rounding error of each
+	// coordinate of Point. It is used for offsetting the origins of rays which leave
+	// the surface so that they do not intersect it again.
+	PointError geometry.Vector
+
+	// Normal is the geometric normal of the surface at Point in object space. Unlike
+	// the normal returned by [Shape.NormalAt] it is never interpolated so it is the
+	// one to use for offsetting rays which leave the surface.
+	Normal geometry.Vector
+
+	// U and V are the coordinates of Point in the parameterization of the surface.
+	// For meshes they are the texture coordinates of their vertices when present.
+	U, V float64
 }
@@ -68,3 +68,20 @@ func (m *Mesh) Refine() []Shape {
 	}
 	return meshFaces
 }
+
+// texCoords returns the texture coordinates of the vertices of `face`. When any of
+// them does not have ones [defaultUVs] are returned instead.
+func (m *Mesh) texCoords(face *obj.Face) [4][2]float64 {
+	for _, ref := range face.References {
+		if !ref.HasTexCoord() {
+			return defaultUVs
+		}
+	}
+
+	var uv [4][2]float64
+	for i, ref := range face.References[:min(len(face.References), 4)] {
+		tc := m.model.GetTexCoordFromReference(ref)
+		uv[i] = [2]float64{tc.U, tc.V}
+	}
+	return uv
+}
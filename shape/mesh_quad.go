@@ -35,62 +35,7 @@ func NewMeshQuad(m *Mesh, face *obj.Face) *MeshQuad {
 // Intersect implements the [Shape] interface.
 func (m *MeshQuad) Intersect(ray geometry.Ray, dg *DifferentialGeometry) bool {
 	p0, p1, p2, p3 := m.getPoints()
-
-	e01 := p1.Minus(p0)
-	e03 := p3.Minus(p0)
-	p := ray.Direction.Cross(e03)
-	det := e01.Dot(p)
-	if det == 0 {
-		return false
-	}
-	invDet := 1 / det
-	t := ray.Origin.Minus(p0)
-	alfa := t.Dot(p) * invDet
-	if alfa < 0 || alfa > 1 {
-		return false
-	}
-	w := t.Cross(e01)
-	beta := ray.Direction.Dot(w) * invDet
-	if beta < 0 || beta > 1 {
-		return false
-	}
-
-	if alfa+beta > 1 {
-		e21 := p1.Minus(p2)
-		e23 := p3.Minus(p2)
-
-		pp := ray.Direction.Cross(e21)
-		detp := e23.Dot(pp)
-		if detp == 0 {
-			return false
-		}
-		invDetp := 1 / detp
-		tp := ray.Origin.Minus(p2)
-		alfap := tp.Dot(pp) * invDetp
-		if alfap < 0 {
-			return false
-		}
-		qp := tp.Cross(e23)
-		betap := ray.Direction.Dot(qp) * invDetp
-		if betap < 0 {
-			return false
-		}
-	}
-
-	tDist := e03.Dot(w) * invDet
-
-	if tDist < ray.Mint || tDist > ray.Maxt {
-		return false
-	}
-
-	if dg == nil {
-		return true
-	}
-
-	dg.Shape = m
-	dg.Distance = tDist
-
-	return true
+	return intersectQuad(ray, dg, m, p0, p1, p2, p3, m.mesh.texCoords(m.face))
 }
 
 // IntersectP implements the [Shape] interface.
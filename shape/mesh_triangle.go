@@ -20,35 +20,9 @@ type MeshTriangle struct {
 func (m *MeshTriangle) Intersect(ray geometry.Ray, dg *DifferentialGeometry) bool {
 
 	p1, p2, p3 := m.getPoints()
-	e1 := p2.Minus(p1)
-	e2 := p3.Minus(p1)
-
-	s1 := ray.Direction.Cross(e2)
-	divisor := s1.Product(e1)
-
-	if divisor == 0 {
-		return false
-	}
-
-	invDivisor := 1 / divisor
-
-	d := ray.Origin.Minus(p1)
-	b1 := d.Product(s1) * invDivisor
-
-	if b1 < 0 || b1 > 1 {
-		return false
-	}
-
-	s2 := d.Cross(e1)
-	b2 := ray.Direction.Product(s2) * invDivisor
-
-	if b2 < 0 || b1+b2 > 1 {
-		return false
-	}
-
-	t := e2.Product(s2) * invDivisor
 
-	if t < ray.Mint || t > ray.Maxt {
+	t, b, ok := intersectTriangle(ray, p1, p2, p3)
+	if !ok {
 		return false
 	}
 
@@ -58,6 +32,8 @@ func (m *MeshTriangle) Intersect(ray geometry.Ray, dg *DifferentialGeometry) boo
 
 	dg.Shape = m
 	dg.Distance = t
+	uv := m.mesh.texCoords(m.face)
+	setTriangleHit(dg, p1, p2, p3, b, [3][2]float64{uv[0], uv[1], uv[2]})
 
 	return true
 }
@@ -2,6 +2,7 @@ package shape
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/ironsmile/raytracer/bbox"
 	"github.com/ironsmile/raytracer/geometry"
+	"github.com/ironsmile/raytracer/utils"
 
 	"github.com/mokiat/go-data-front/decoder/mtl"
 	"github.com/mokiat/go-data-front/decoder/obj"
@@ -24,6 +26,9 @@ type Object struct {
 
 	// All the meshes which compose this object
 	meshes []Shape
+
+	// files are the .obj file and the .mtl file from which the object was loaded.
+	files []string
 }
 
 // Intersect implements the Shape interface
@@ -37,8 +42,10 @@ func (o *Object) IntersectP(geometry.Ray) bool {
 }
 
 // NewObject parses an .obj file (`filePath`) and returns an Object, which represents
-// it. It places the object at the position, given by its second argument - `center`.
-func NewObject(filePath string) (*Object, error) {
+// it. Details about the loaded model are written to `logger`, which may be nil.
+func NewObject(filePath string, logger *slog.Logger) (*Object, error) {
+	logger = utils.Logger(logger)
+
 	filesToTry := []string{
 		filePath,
 	}
@@ -78,13 +85,13 @@ func NewObject(filePath string) (*Object, error) {
 		return nil, err
 	}
 
-	fmt.Printf("model %s has %d objects\n", filePath, len(model.Objects))
-
 	var matLib *mtl.Library
+	files := []string{filePath}
 
 	if strings.HasSuffix(filePath, objFileSuffix) {
 		materialPath := strings.TrimSuffix(filePath, objFileSuffix)
 		materialPath += mtlFileSuffix
+		files = append(files, materialPath)
 
 		if matFile, err := os.Open(materialPath); err == nil {
 			defer matFile.Close()
@@ -94,24 +101,25 @@ func NewObject(filePath string) (*Object, error) {
 				return nil, fmt.Errorf("error decoding material file: %s", err)
 			}
 		} else {
-			fmt.Printf("Error opening material file %s: %s\n", materialPath, err)
+			logger.Warn("cannot open material file", "file", materialPath, "error", err)
 		}
 	}
 
-	fmt.Printf("model %s has has a material: %p\n", filePath, matLib)
-
-	o := &Object{}
+	o := &Object{files: files}
 	var facesCount int
 
 	for _, modelObj := range model.Objects {
-		fmt.Printf("object %s has %d meshes\n", modelObj.Name, len(modelObj.Meshes))
 		for meshIndex, mesh := range modelObj.Meshes {
 			meshName := mesh.MaterialName
 			if len(meshName) < 1 {
 				meshName = "Unknown"
 			}
-			fmt.Printf("mesh %d is from `%s` and has %d faces\n", meshIndex, meshName,
-				len(mesh.Faces))
+			logger.Debug("mesh loaded",
+				"object", modelObj.Name,
+				"mesh", meshIndex,
+				"material", meshName,
+				"faces", len(mesh.Faces),
+			)
 			facesCount += len(mesh.Faces)
 			faceMesh := NewMesh(model, mesh)
 
@@ -142,11 +150,22 @@ func NewObject(filePath string) (*Object, error) {
 		}
 	}
 
-	fmt.Printf("%s has %d faces\n", filePath, facesCount)
+	logger.Info("model loaded",
+		"file", filePath,
+		"objects", len(model.Objects),
+		"materials", matLib != nil,
+		"faces", facesCount,
+	)
 
 	return o, nil
 }
 
+// Files returns the .obj file from which the object was loaded followed by its
+// .mtl material file. The material file is returned even when it does not exist.
+func (o *Object) Files() []string {
+	return o.files
+}
+
 // CanIntersect implements the Shape interface
 func (o *Object) CanIntersect() bool {
 	return false
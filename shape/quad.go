@@ -27,64 +27,10 @@ func NewQuad(v1, v2, v3, v4 geometry.Vector) *Quad {
 	return q
 }
 
-// Intersect implements the Shape interface for quad face in 3D space. It is based on the
-// Ares Lagae and Philip Dutre (2005) algorithm.
+// Intersect implements the Shape interface for quad face in 3D space.
 func (q *Quad) Intersect(ray geometry.Ray, dg *DifferentialGeometry) bool {
-	e01 := q.vertices[1].Minus(q.vertices[0])
-	e03 := q.vertices[3].Minus(q.vertices[0])
-	p := ray.Direction.Cross(e03)
-	det := e01.Dot(p)
-	if det == 0 {
-		return false
-	}
-	invDet := 1 / det
-	t := ray.Origin.Minus(q.vertices[0])
-	alfa := t.Dot(p) * invDet
-	if alfa < 0 || alfa > 1 {
-		return false
-	}
-	w := t.Cross(e01)
-	beta := ray.Direction.Dot(w) * invDet
-	if beta < 0 || beta > 1 {
-		return false
-	}
-
-	if alfa+beta > 1 {
-		e21 := q.vertices[1].Minus(q.vertices[2])
-		e23 := q.vertices[3].Minus(q.vertices[2])
-
-		pp := ray.Direction.Cross(e21)
-		detp := e23.Dot(pp)
-		if detp == 0 {
-			return false
-		}
-		invDetp := 1 / detp
-		tp := ray.Origin.Minus(q.vertices[2])
-		alfap := tp.Dot(pp) * invDetp
-		if alfap < 0 {
-			return false
-		}
-		qp := tp.Cross(e23)
-		betap := ray.Direction.Dot(qp) * invDetp
-		if betap < 0 {
-			return false
-		}
-	}
-
-	tDist := e03.Dot(w) * invDet
-
-	if tDist < ray.Mint || tDist > ray.Maxt {
-		return false
-	}
-
-	if dg == nil {
-		return true
-	}
-
-	dg.Shape = q
-	dg.Distance = tDist
-
-	return true
+	v := &q.vertices
+	return intersectQuad(ray, dg, q, v[0], v[1], v[2], v[3], defaultUVs)
 }
 
 // NormalAt implements the Shape interface
@@ -1,6 +1,8 @@
 package shape
 
 import (
+	"math"
+
 	"github.com/ironsmile/raytracer/bbox"
 	"github.com/ironsmile/raytracer/geometry"
 	"github.com/ironsmile/raytracer/utils"
@@ -47,6 +49,20 @@ func (s *Sphere) Intersect(ray geometry.Ray, dg *DifferentialGeometry) bool {
 	dg.Shape = s
 	dg.Distance = retdist
 
+	// Reproject the hit point on the sphere surface which makes its error much
+	// smaller than the one of evaluating the ray.
+	dg.Point = ray.At(retdist)
+	dg.Point = dg.Point.MultiplyScalar(s.radius / dg.Point.Length())
+	dg.PointError = dg.Point.Abs().MultiplyScalar(geometry.Gamma(5))
+	dg.Normal = s.NormalAt(dg.Point)
+
+	phi := math.Atan2(dg.Point.Y, dg.Point.X)
+	if phi < 0 {
+		phi += 2 * math.Pi
+	}
+	dg.U = phi / (2 * math.Pi)
+	dg.V = math.Acos(utils.Clamp(dg.Point.Z/s.radius, -1, 1)) / math.Pi
+
 	return true
 }
 
@@ -30,36 +30,8 @@ func NewTriangle(vertices [3]geometry.Vector) *Triangle {
 }
 
 func (t *Triangle) Intersect(ray geometry.Ray, dg *DifferentialGeometry) bool {
-	// Implements Möller–Trumbore ray-triangle intersection algorithm:
-	// https://en.wikipedia.org/wiki/M%C3%B6ller%E2%80%93Trumbore_intersection_algorithm
-
-	s1 := ray.Direction.Cross(t.edge2)
-	divisor := t.edge1.Product(s1)
-
-	// Not culling:
-	if divisor > -geometry.EPSILON && divisor < geometry.EPSILON {
-		return false
-	}
-
-	invDivisor := 1.0 / divisor
-
-	s := ray.Origin.Minus(t.Vertices[0])
-	b1 := s.Product(s1) * invDivisor
-
-	if b1 < 0.0 || b1 > 1.0 {
-		return false
-	}
-
-	s2 := s.Cross(t.edge1)
-	b2 := ray.Direction.Product(s2) * invDivisor
-
-	if b2 < 0.0 || b1+b2 > 1.0 {
-		return false
-	}
-
-	tt := t.edge2.Product(s2) * invDivisor
-
-	if tt > ray.Maxt || tt < ray.Mint {
+	tt, b, ok := intersectTriangle(ray, t.Vertices[0], t.Vertices[1], t.Vertices[2])
+	if !ok {
 		return false
 	}
 
@@ -69,6 +41,8 @@ func (t *Triangle) Intersect(ray geometry.Ray, dg *DifferentialGeometry) bool {
 
 	dg.Shape = t
 	dg.Distance = tt
+	setTriangleHit(dg, t.Vertices[0], t.Vertices[1], t.Vertices[2], b,
+		[3][2]float64{defaultUVs[0], defaultUVs[1], defaultUVs[2]})
 
 	return true
 }
@@ -0,0 +1,166 @@
+package shape
+
+import (
+	"math"
+
+	"github.com/ironsmile/raytracer/geometry"
+)
+
+// intersectTriangle intersects `ray` with the triangle (p0, p1, p2). It returns the
+// distance to the hit and its barycentric coordinates.
+//
+// It implements the watertight ray-triangle intersection algorithm from Woop, Benthin
+// and Wald (2013), "Watertight Ray/Triangle Intersection". Rays which hit an edge or
+// a vertex shared between triangles are never missed by all of them. The hit
+// distance is also checked against a conservative bound of its rounding error so
+// that hits which could be behind the ray origin are discarded.
+func intersectTriangle(
+	ray geometry.Ray,
+	p0, p1, p2 geometry.Vector,
+) (t float64, b [3]float64, ok bool) {
+	// Translate the vertices so that the ray origin is at (0, 0, 0).
+	p0t := p0.Minus(ray.Origin)
+	p1t := p1.Minus(ray.Origin)
+	p2t := p2.Minus(ray.Origin)
+
+	// Permute the axes so that the ray direction's biggest component is on z.
+	kz := ray.Direction.Abs().MaxDimension()
+	kx := (kz + 1) % 3
+	ky := (kx + 1) % 3
+
+	d := geometry.NewVector(
+		ray.Direction.ByAxis(kx),
+		ray.Direction.ByAxis(ky),
+		ray.Direction.ByAxis(kz),
+	)
+	p0t = geometry.NewVector(p0t.ByAxis(kx), p0t.ByAxis(ky), p0t.ByAxis(kz))
+	p1t = geometry.NewVector(p1t.ByAxis(kx), p1t.ByAxis(ky), p1t.ByAxis(kz))
+	p2t = geometry.NewVector(p2t.ByAxis(kx), p2t.ByAxis(ky), p2t.ByAxis(kz))
+
+	// Shear the vertices so that the ray direction becomes (0, 0, 1). Only x and y
+	// are sheared now. The z shear is applied only if the ray hits the triangle.
+	sx := -d.X / d.Z
+	sy := -d.Y / d.Z
+	sz := 1 / d.Z
+
+	p0t.X += sx * p0t.Z
+	p0t.Y += sy * p0t.Z
+	p1t.X += sx * p1t.Z
+	p1t.Y += sy * p1t.Z
+	p2t.X += sx * p2t.Z
+	p2t.Y += sy * p2t.Z
+
+	// Edge functions. The ray passes through the triangle when they all have the
+	// same sign.
+	e0 := p1t.X*p2t.Y - p1t.Y*p2t.X
+	e1 := p2t.X*p0t.Y - p2t.Y*p0t.X
+	e2 := p0t.X*p1t.Y - p0t.Y*p1t.X
+
+	if (e0 < 0 || e1 < 0 || e2 < 0) && (e0 > 0 || e1 > 0 || e2 > 0) {
+		return 0, b, false
+	}
+
+	det := e0 + e1 + e2
+	if det == 0 {
+		return 0, b, false
+	}
+
+	p0t.Z *= sz
+	p1t.Z *= sz
+	p2t.Z *= sz
+
+	// The distance is still scaled by det. Compare it against the ray range without
+	// dividing first.
+	tScaled := e0*p0t.Z + e1*p1t.Z + e2*p2t.Z
+	if det < 0 && (tScaled > ray.Mint*det || tScaled < ray.Maxt*det) {
+		return 0, b, false
+	}
+	if det > 0 && (tScaled < ray.Mint*det || tScaled > ray.Maxt*det) {
+		return 0, b, false
+	}
+
+	invDet := 1 / det
+	b = [3]float64{e0 * invDet, e1 * invDet, e2 * invDet}
+	t = tScaled * invDet
+
+	// Make sure that the distance is greater than zero even with the rounding
+	// errors of all of the calculations above.
+	maxZt := math.Max(math.Abs(p0t.Z), math.Max(math.Abs(p1t.Z), math.Abs(p2t.Z)))
+	deltaZ := geometry.Gamma(3) * maxZt
+
+	maxXt := math.Max(math.Abs(p0t.X), math.Max(math.Abs(p1t.X), math.Abs(p2t.X)))
+	maxYt := math.Max(math.Abs(p0t.Y), math.Max(math.Abs(p1t.Y), math.Abs(p2t.Y)))
+	deltaX := geometry.Gamma(5) * (maxXt + maxZt)
+	deltaY := geometry.Gamma(5) * (maxYt + maxZt)
+
+	deltaE := 2 * (geometry.Gamma(2)*maxXt*maxYt + deltaY*maxXt + deltaX*maxYt)
+	maxE := math.Max(math.Abs(e0), math.Max(math.Abs(e1), math.Abs(e2)))
+
+	deltaT := 3 * (geometry.Gamma(3)*maxE*maxZt + deltaE*maxZt + deltaZ*maxE) *
+		math.Abs(invDet)
+	if t <= deltaT {
+		return 0, b, false
+	}
+
+	return t, b, true
+}
+
+// intersectQuad intersects `ray` with the convex quad (p0, p1, p2, p3) of `shape`
+// and fills `dg` on hit when it is not nil.
+//
+// The quad is split in two triangles along its p0-p2 diagonal. Both of them are
+// intersected with [intersectTriangle] so rays through the diagonal never leak
+// between them.
+func intersectQuad(
+	ray geometry.Ray,
+	dg *DifferentialGeometry,
+	shape Shape,
+	p0, p1, p2, p3 geometry.Vector,
+	uv [4][2]float64,
+) bool {
+	t, b, ok := intersectTriangle(ray, p0, p1, p2)
+	if !ok {
+		p1, p2 = p2, p3
+		uv[1], uv[2] = uv[2], uv[3]
+		t, b, ok = intersectTriangle(ray, p0, p1, p2)
+	}
+	if !ok {
+		return false
+	}
+
+	if dg == nil {
+		return true
+	}
+
+	dg.Shape = shape
+	dg.Distance = t
+	setTriangleHit(dg, p0, p1, p2, b, [3][2]float64{uv[0], uv[1], uv[2]})
+
+	return true
+}
+
+// defaultUVs are the surface coordinates of the vertices of quads and triangles
+// which do not have their own. Triangles use the first three of them.
+var defaultUVs = [4][2]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}}
+
+// setTriangleHit fills the hit point, normal and surface coordinates in `dg` from
+// the barycentric coordinates `b` of a hit in the triangle (p0, p1, p2) with vertex
+// surface coordinates `uv`. Calculating the point from the vertices instead of the
+// ray gives a much tighter error bound.
+func setTriangleHit(
+	dg *DifferentialGeometry,
+	p0, p1, p2 geometry.Vector,
+	b [3]float64,
+	uv [3][2]float64,
+) {
+	bp0 := p0.MultiplyScalar(b[0])
+	bp1 := p1.MultiplyScalar(b[1])
+	bp2 := p2.MultiplyScalar(b[2])
+
+	dg.Point = bp0.Plus(bp1).Plus(bp2)
+	dg.PointError = bp0.Abs().Plus(bp1.Abs()).Plus(bp2.Abs()).
+		MultiplyScalar(geometry.Gamma(7))
+	dg.Normal = p1.Minus(p0).Cross(p2.Minus(p0)).Normalize()
+	dg.U = b[0]*uv[0][0] + b[1]*uv[1][0] + b[2]*uv[2][0]
+	dg.V = b[0]*uv[0][1] + b[1]*uv[1][1] + b[2]*uv[2][1]
+}
@@ -0,0 +1,148 @@
+package shape_test
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/ironsmile/raytracer/geometry"
+	"github.com/ironsmile/raytracer/shape"
+)
+
+// TestTrianglesWatertight shoots rays exactly through the edges and the vertex shared
+// by a fan of triangles. Every one of them has to hit at least one triangle.
+func TestTrianglesWatertight(t *testing.T) {
+	rnd := rand.New(rand.NewSource(7))
+
+	center := geometry.NewVector(0.1, 0.3, 0.7)
+	ring := []geometry.Vector{
+		geometry.NewVector(1.3, 0.1, 0.2),
+		geometry.NewVector(0.2, 1.7, 0.9),
+		geometry.NewVector(-1.1, 0.6, 0.4),
+		geometry.NewVector(-0.3, -1.4, 1.1),
+	}
+
+	var fan []*shape.Triangle
+	for i := range ring {
+		fan = append(fan, shape.NewTriangle([3]geometry.Vector{
+			center, ring[i], ring[(i+1)%len(ring)],
+		}))
+	}
+
+	for i := 0; i < 10000; i++ {
+		// A point on one of the shared edges or the shared vertex itself.
+		target := center
+		if i%10 != 0 {
+			target = geometry.Lerp(center, ring[rnd.Intn(len(ring))], rnd.Float64())
+		}
+
+		origin := geometry.NewVector(
+			rnd.Float64()*20-10,
+			rnd.Float64()*20-10,
+			rnd.Float64()*20+5,
+		)
+		ray := geometry.NewRay(origin, target.Minus(origin).Normalize())
+
+		var hit bool
+		for _, tr := range fan {
+			if tr.IntersectP(ray) {
+				hit = true
+				break
+			}
+		}
+
+		if !hit {
+			t.Fatalf("ray %d from %s toward %s leaked through the triangle fan",
+				i, origin, target)
+		}
+	}
+}
+
+// TestSpawnedRaysDoNotSelfIntersect checks that rays leaving a triangle from its
+// intersection points never hit the same triangle again. Neither on the side of the
+// normal nor on the opposite side.
+func TestSpawnedRaysDoNotSelfIntersect(t *testing.T) {
+	rnd := rand.New(rand.NewSource(11))
+
+	tr := shape.NewTriangle([3]geometry.Vector{
+		geometry.NewVector(-1000.3, 2.1, -999.7),
+		geometry.NewVector(1000.9, 5.3, -1000.1),
+		geometry.NewVector(0.4, -3.7, 1000.2),
+	})
+
+	for i := 0; i < 10000; i++ {
+		origin := geometry.NewVector(
+			rnd.Float64()*200-100,
+			rnd.Float64()*50+100,
+			rnd.Float64()*200-100,
+		)
+		target := geometry.NewVector(rnd.Float64()*200-100, 0, rnd.Float64()*200-100)
+		ray := geometry.NewRay(origin, target.Minus(origin).Normalize())
+
+		var dg shape.DifferentialGeometry
+		if !tr.Intersect(ray, &dg) {
+			continue
+		}
+
+		dir := geometry.NewVector(
+			rnd.Float64()*2-1,
+			rnd.Float64()*2-1,
+			rnd.Float64()*2-1,
+		).Normalize()
+
+		spawned := geometry.SpawnRay(dg.Point, dg.PointError, dg.Normal, dir)
+		if side(tr, spawned.Origin) != (dir.Dot(tr.Normal) > 0) {
+			t.Fatalf("ray %d spawned from %s with error %s in direction %s "+
+				"starts on the wrong side of the triangle", i, dg.Point, dg.PointError, dir)
+		}
+		if tr.IntersectP(spawned) {
+			t.Fatalf("ray %d spawned from %s with error %s in direction %s "+
+				"intersected its own triangle", i, dg.Point, dg.PointError, dir)
+		}
+
+		toLight := geometry.SpawnRayTo(dg.Point, dg.PointError, dg.Normal, origin)
+		if tr.IntersectP(toLight) {
+			t.Fatalf("shadow ray %d from %s intersected its own triangle", i, dg.Point)
+		}
+	}
+}
+
+// side returns whether `p` is on the side of the triangle plane toward which
+// its Normal field points. The calculation is done in exact arithmetic.
+func side(tr *shape.Triangle, p geometry.Vector) bool {
+	const prec = 1024
+
+	num := func(x float64) *big.Float {
+		return new(big.Float).SetPrec(prec).SetFloat64(x)
+	}
+	exact := func(v geometry.Vector) [3]*big.Float {
+		return [3]*big.Float{num(v.X), num(v.Y), num(v.Z)}
+	}
+	sub := func(a, b [3]*big.Float) [3]*big.Float {
+		var r [3]*big.Float
+		for i := range r {
+			r[i] = new(big.Float).SetPrec(prec).Sub(a[i], b[i])
+		}
+		return r
+	}
+	mul := func(a, b *big.Float) *big.Float {
+		return new(big.Float).SetPrec(prec).Mul(a, b)
+	}
+
+	v0 := exact(tr.Vertices[0])
+	e1 := sub(exact(tr.Vertices[1]), v0)
+	e2 := sub(exact(tr.Vertices[2]), v0)
+	d := sub(exact(p), v0)
+
+	// The triple product d . (e1 x e2) is positive on the side of e1 x e2.
+	cross := [3]*big.Float{
+		num(0).Sub(mul(e1[1], e2[2]), mul(e1[2], e2[1])),
+		num(0).Sub(mul(e1[2], e2[0]), mul(e1[0], e2[2])),
+		num(0).Sub(mul(e1[0], e2[1]), mul(e1[1], e2[0])),
+	}
+	triple := num(0).Add(mul(d[0], cross[0]), mul(d[1], cross[1]))
+	triple.Add(triple, mul(d[2], cross[2]))
+
+	// The triangle normal is the negated cross product.
+	return triple.Sign() < 0
+}
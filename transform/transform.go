@@ -2,6 +2,7 @@ package transform
 
 import (
 	"fmt"
+	"math"
 
 	"github.com/ironsmile/raytracer/bbox"
 	"github.com/ironsmile/raytracer/geometry"
@@ -46,6 +47,26 @@ func (t *Transform) Point(point geometry.Vector) geometry.Vector {
 	return p
 }
 
+// PointWithError transforms `point` which has an absolute error of `pError` for
+// each of its coordinates. It returns the transformed point and a conservative
+// bound on its absolute error which includes the rounding errors of the
+// transformation itself. Only affine transformations are supported.
+func (t *Transform) PointWithError(
+	point, pError geometry.Vector,
+) (geometry.Vector, geometry.Vector) {
+	m := &t.mat.els
+	g3 := geometry.Gamma(3)
+
+	errAxis := func(row int) float64 {
+		return (g3+1)*(math.Abs(m[row][0])*pError.X+math.Abs(m[row][1])*pError.Y+
+			math.Abs(m[row][2])*pError.Z) +
+			g3*(math.Abs(m[row][0]*point.X)+math.Abs(m[row][1]*point.Y)+
+				math.Abs(m[row][2]*point.Z)+math.Abs(m[row][3]))
+	}
+
+	return t.Point(point), geometry.NewVector(errAxis(0), errAxis(1), errAxis(2))
+}
+
 func (t *Transform) Vector(vec geometry.Vector) geometry.Vector {
 	xp := (t.mat.els[0][0])*vec.X + (t.mat.els[0][1])*vec.Y +
 		(t.mat.els[0][2])*vec.Z
@@ -0,0 +1,14 @@
+package utils
+
+import "log/slog"
+
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// Logger returns `l` when it is not nil. Otherwise it returns a logger which
+// discards everything so that nil loggers mean no logging.
+func Logger(l *slog.Logger) *slog.Logger {
+	if l == nil {
+		return discardLogger
+	}
+	return l
+}